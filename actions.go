@@ -0,0 +1,309 @@
+package jsonmask
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// AddFuncFactory adds a factory of parametrized masking functions associated with a name.
+// Actions like "name(arg)" are resolved by calling the factory with the argument
+// found in parentheses. Resolved functions are cached per action.
+func (jm *JsonMaskerImpl) AddFuncFactory(name string, f func(arg string) (func(string) []byte, error)) {
+	if jm.checkReplace(name) {
+		jm.addFuncFactory(name, f)
+	}
+}
+
+// addFuncFactory adds the factory without checking its algorithm.
+func (jm *JsonMaskerImpl) addFuncFactory(name string, f func(arg string) (func(string) []byte, error)) {
+	jm.factories[name] = f
+	jm.resetResolved()
+}
+
+// resetResolved drops all actions resolved by factories and chains, as they
+// may refer to a replaced function or factory, also in arguments of factories,
+// e.g. "quoted(zero)".
+func (jm *JsonMaskerImpl) resetResolved() {
+	jm.resolved.Range(func(key, _ any) bool {
+		jm.resolved.Delete(key)
+		return true
+	})
+}
+
+// lookupFunc returns a masking function for the action. Registered functions take
+// precedence over parametrized actions resolved by factories and chains.
+func (jm *JsonMaskerImpl) lookupFunc(action string) (func(string) []byte, bool) {
+	f, err := jm.resolveFunc(action)
+	return f, err == nil
+}
+
+// resolveFunc is like lookupFunc but returns ErrUnknownAction or the error
+// of the factory, e.g. a key provider being unreachable.
+func (jm *JsonMaskerImpl) resolveFunc(action string) (func(string) []byte, error) {
+	for p := jm; p != nil; p = p.parent {
+		if f, ok := p.funcs[action]; ok {
+			return f, nil
+		}
+	}
+
+	if f, ok := jm.resolved.Load(action); ok {
+		return f.(func(string) []byte), nil
+	}
+
+	if chain := splitActions(action); len(chain) > 1 {
+		funcs := make([]func(string) []byte, len(chain))
+		for i, a := range chain {
+			f, err := jm.resolveFunc(a)
+			if err != nil {
+				return nil, err
+			}
+			funcs[i] = f
+		}
+		f := chainFunc(funcs)
+		jm.resolved.Store(action, f)
+		return f, nil
+	}
+
+	name, arg, ok := parseAction(action)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAction, action)
+	}
+
+	var factory func(string) (func(string) []byte, error)
+	for p := jm; p != nil && factory == nil; p = p.parent {
+		factory = p.factories[name]
+	}
+	if factory == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAction, action)
+	}
+
+	f, err := factory(arg)
+	if err != nil {
+		return nil, fmt.Errorf("action %s: %w", action, err)
+	}
+
+	jm.resolved.Store(action, f)
+	return f, nil
+}
+
+// splitActions splits the chain of actions separated by '|', e.g.
+// "lower|initialChar". Separators inside parentheses belong to arguments.
+func splitActions(action string) []string {
+	var res []string
+	depth, start := 0, 0
+	for i := 0; i < len(action); i++ {
+		switch action[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '|':
+			if depth == 0 {
+				res = append(res, action[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(res, action[start:])
+}
+
+// chainFunc returns a masking function passing the value through funcs in order,
+// the output of every function feeds the next one.
+func chainFunc(funcs []func(string) []byte) func(string) []byte {
+	return func(s string) []byte {
+		var res []byte
+		for _, f := range funcs {
+			res = f(s)
+			s = string(res)
+		}
+		return res
+	}
+}
+
+// parseAction splits an action like "name(arg)" to the name and the argument.
+func parseAction(action string) (name, arg string, ok bool) {
+	idx := strings.IndexByte(action, '(')
+	if idx <= 0 || !strings.HasSuffix(action, ")") {
+		return "", "", false
+	}
+	return action[:idx], action[idx+1 : len(action)-1], true
+}
+
+// base64Factory returns a masking function that decodes base64 encoded JSON,
+// masks it with the named rule set and encodes it back using the same encoding.
+func (jm *JsonMaskerImpl) base64Factory(rulesName string) (func(string) []byte, error) {
+	if _, ok := jm.Rules(rulesName); !ok {
+		return nil, ErrRulesNotFound
+	}
+
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.URLEncoding,
+		base64.RawStdEncoding,
+		base64.RawURLEncoding,
+	}
+
+	return func(s string) []byte {
+		str, ok := unquote(s)
+		if !ok {
+			return []byte(s)
+		}
+
+		for _, enc := range encodings {
+			decoded, err := enc.DecodeString(str)
+			if err != nil {
+				continue
+			}
+			if !gjson.ValidBytes(decoded) {
+				break
+			}
+
+			smr, _ := jm.Rules(rulesName)
+			masked, err := jm.maskDocument(decoded, smr, smr.Rules)
+			if err != nil {
+				break
+			}
+			return quote(enc.EncodeToString(masked))
+		}
+
+		return []byte(`"invalid_base64_format"`)
+	}, nil
+}
+
+// jsonFactory returns a masking function that parses JSON embedded into a string,
+// masks it with the named rule set and serializes it back into the string.
+func (jm *JsonMaskerImpl) jsonFactory(rulesName string) (func(string) []byte, error) {
+	if _, ok := jm.Rules(rulesName); !ok {
+		return nil, ErrRulesNotFound
+	}
+
+	return func(s string) []byte {
+		str, ok := unquote(s)
+		if !ok {
+			return []byte(s)
+		}
+
+		if !gjson.Valid(str) {
+			return []byte(`"invalid_json_format"`)
+		}
+
+		smr, _ := jm.Rules(rulesName)
+		masked, err := jm.maskDocument([]byte(str), smr, smr.Rules)
+		if err != nil {
+			return []byte(`"invalid_json_format"`)
+		}
+		return quote(string(masked))
+	}, nil
+}
+
+// stripHTMLFactory returns a masking function removing HTML tags and truncating
+// the text to the number of characters given by arg.
+func stripHTMLFactory(arg string) (func(string) []byte, error) {
+	maxLen, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, err
+	}
+	return StripHTMLFn(maxLen), nil
+}
+
+// limitFactory returns a masking function keeping the first N array elements.
+// The argument is "N" or "N,more", the latter appends a marker of dropped elements.
+func limitFactory(arg string) (func(string) []byte, error) {
+	n, more, _ := strings.Cut(arg, ",")
+	limit, err := strconv.Atoi(strings.TrimSpace(n))
+	if err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, errors.New("negative array limit")
+	}
+	return ArrayLimitFn(limit, strings.TrimSpace(more) == "more"), nil
+}
+
+// ageBucketFactory returns a masking function converting birth dates to age
+// bands of the width given by arg.
+func ageBucketFactory(arg string) (func(string) []byte, error) {
+	width, err := ageBucketWidth(arg)
+	if err != nil {
+		return nil, err
+	}
+	return AgeBucketFn(width), nil
+}
+
+// ageBucketWidth parses the age band width given by arg.
+func ageBucketWidth(arg string) (int, error) {
+	width, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, err
+	}
+	if width <= 0 {
+		return 0, errors.New("non-positive age band width")
+	}
+	return width, nil
+}
+
+// roundFactory returns a masking function rounding numbers to the nearest
+// multiple of the precision given by arg.
+func roundFactory(arg string) (func(string) []byte, error) {
+	precision, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return nil, err
+	}
+	if !(precision > 0) || math.IsInf(precision, 1) {
+		return nil, errors.New("non-positive precision")
+	}
+	return AmountFn(precision), nil
+}
+
+// laplaceFactory returns a masking function adding Laplace noise with epsilon
+// and sensitivity given by arg, e.g. "0.5,1".
+func laplaceFactory(arg string) (func(string) []byte, error) {
+	epsilon, sensitivity, err := laplaceArgs(arg)
+	if err != nil {
+		return nil, err
+	}
+	return LaplaceFn(epsilon, sensitivity), nil
+}
+
+// laplaceArgs parses epsilon and sensitivity given by arg, e.g. "0.5,1".
+func laplaceArgs(arg string) (epsilon, sensitivity float64, err error) {
+	e, sens, ok := strings.Cut(arg, ",")
+	if !ok {
+		return 0, 0, errors.New("epsilon and sensitivity expected")
+	}
+	if epsilon, err = strconv.ParseFloat(strings.TrimSpace(e), 64); err != nil {
+		return 0, 0, err
+	}
+	if sensitivity, err = strconv.ParseFloat(strings.TrimSpace(sens), 64); err != nil {
+		return 0, 0, err
+	}
+	if !(epsilon > 0) || math.IsInf(epsilon, 1) || !(sensitivity > 0) || math.IsInf(sensitivity, 1) {
+		return 0, 0, errors.New("non-positive epsilon or sensitivity")
+	}
+	if !validLaplaceArgs(epsilon, sensitivity) {
+		return 0, 0, errors.New("noise scale out of range")
+	}
+	return epsilon, sensitivity, nil
+}
+
+// tombstoneFactory returns a masking function replacing values with tombstones
+// of the retention policy given by arg.
+func tombstoneFactory(policy string) (func(string) []byte, error) {
+	return TombstoneFn(policy), nil
+}
+
+// quotedFactory returns a masking function applying the action given by arg
+// to numbers encoded as strings, e.g. "quoted(zero)".
+func (jm *JsonMaskerImpl) quotedFactory(action string) (func(string) []byte, error) {
+	f, ok := jm.lookupFunc(action)
+	if !ok {
+		return nil, ErrUnknownAction
+	}
+	return QuotedFn(f), nil
+}
@@ -0,0 +1,199 @@
+package jsonmask_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestJsonMaskerImpl_Base64(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddRules("customer", jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "name", Action: "initialChar"},
+			{Path: "password", Action: "-"},
+		},
+	})
+
+	body := base64.StdEncoding.EncodeToString([]byte(`{"name":"john","password":"secret"}`))
+	data := []byte(`{"event":"created","body":"` + body + `","raw":"bm90IGpzb24=","bad":"%%%"}`)
+
+	result, err := jm.Mask(data, jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "body", Action: "base64(customer)"},
+			{Path: "raw", Action: "base64(customer)"},
+			{Path: "bad", Action: "base64(customer)"},
+			{Path: "event", Action: "base64(unknown)"},
+		},
+	})
+	assert.NoError(t, err)
+
+	expected := base64.StdEncoding.EncodeToString([]byte(`{"name":"J"}`))
+	assert.JSONEq(t, `{"event":"created","body":"`+expected+`","raw":"invalid_base64_format","bad":"invalid_base64_format"}`, string(result))
+}
+
+func TestJsonMaskerImpl_AddFuncFactory(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddFuncFactory("const", func(arg string) (func(string) []byte, error) {
+		return func(string) []byte { return []byte(`"` + arg + `"`) }, nil
+	})
+
+	rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "const(x)"}}}
+
+	result, err := jm.Mask([]byte(`{"name":"john"}`), rules)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"x"}`, string(result))
+
+	// re-registered factory replaces previously resolved functions.
+	jm.AddFuncFactory("const", func(arg string) (func(string) []byte, error) {
+		return func(string) []byte { return []byte(`"` + arg + arg + `"`) }, nil
+	})
+
+	result, err = jm.Mask([]byte(`{"name":"john"}`), rules)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"xx"}`, string(result))
+}
+
+func TestJsonMaskerImpl_JSON(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddRules("customer", jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "name", Action: "initialChar"},
+			{Path: "note", Action: "upper"},
+			{Path: "password", Action: "-"},
+		},
+	})
+
+	data := []byte(`{"payload":"{\"name\":\"john\",\"note\":\"say \\\"hi\\\" <b>\",\"password\":\"secret\"}","text":"plain"}`)
+
+	result, err := jm.Mask(data, jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "payload", Action: "json(customer)"},
+			{Path: "text", Action: "json(customer)"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"payload":"{\"name\":\"J\",\"note\":\"SAY \\\"HI\\\" <B>\"}","text":"invalid_json_format"}`, string(result))
+}
+
+func TestJsonMaskerImpl_StripHTML(t *testing.T) {
+	jm := jsonmask.New()
+
+	result, err := jm.Mask([]byte(`{"body":"<p>Hello <b>world</b></p>","comment":"<p>Hello world</p>"}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "body", Action: "stripHTML"},
+			{Path: "comment", Action: "stripHTML(5)"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"body":"Hello world","comment":"Hello"}`, string(result))
+}
+
+func TestJsonMaskerImpl_Limit(t *testing.T) {
+	type Transaction struct {
+		ID     int    `json:"id"`
+		Number string `json:"number" mask:"first4"`
+	}
+	type Account struct {
+		Transactions []Transaction `json:"transactions" mask:"limit(1,more)"`
+		Tags         []string      `json:"tags" mask:"limit(2)"`
+	}
+
+	jm := jsonmask.New()
+	rules := jm.ParseStruct(Account{})
+
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "transactions", Action: "limit(1,more)"},
+		{Path: "transactions.#.number", Action: "first4"},
+		{Path: "tags", Action: "limit(2)"},
+	}, rules.Rules)
+
+	result, err := jm.Mask([]byte(`{"transactions":[{"id":1,"number":"4111111111111111"},{"id":2},{"id":3}],"tags":["a","b","c"]}`), rules)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"transactions":[{"id":1,"number":"4111"},"…and 2 more"],"tags":["a","b"]}`, string(result))
+
+	type Order struct {
+		Items []Transaction `json:"items" mask:"wrap"`
+	}
+	jm.AddFunc("wrap", func(s string) []byte { return []byte(s) })
+	order := Order{Items: []Transaction{{ID: 1, Number: "4111111111111111"}}}
+	assert.NoError(t, jm.MaskStruct(&order))
+	assert.Equal(t, "4111", order.Items[0].Number)
+}
+
+func TestJsonMaskerImpl_Laplace(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+
+	result, err := jm.Mask([]byte(`{"count":1000,"name":"x"}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{{Path: "count", Action: "laplace(1, 1)"}, {Path: "name", Action: "laplace(1,1)"}},
+	})
+	assert.NoError(t, err)
+	assert.InDelta(t, 1000, gjson.GetBytes(result, "count").Int(), 100)
+	assert.Equal(t, "x", gjson.GetBytes(result, "name").Str)
+
+	for _, action := range []string{"laplace(1)", "laplace(0,1)", "laplace(x,1)", "laplace(1,-1)", "laplace(NaN,1)", "laplace(1,Inf)", "laplace(1e-320,1)"} {
+		_, err = jm.Mask([]byte(`{"count":1}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "count", Action: action}}})
+		assert.ErrorIs(t, err, jsonmask.ErrUnknownAction, action)
+	}
+}
+
+func TestJsonMaskerImpl_Round(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+
+	result, err := jm.Mask([]byte(`{"amount":1234.56}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{{Path: "amount", Action: "round(100)"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":1200}`, string(result))
+
+	for _, action := range []string{"round(0)", "round(-1)", "round(NaN)", "round(Inf)", "round(x)"} {
+		_, err = jm.Mask([]byte(`{"amount":1}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "amount", Action: action}}})
+		assert.ErrorIs(t, err, jsonmask.ErrUnknownAction, action)
+	}
+}
+
+func TestJsonMaskerImpl_AgeBucket(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+
+	result, err := jm.Mask([]byte(`{"born":"2000-01-01"}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{{Path: "born", Action: "ageBucket(100)"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"born":"0-99"}`, string(result))
+
+	for _, action := range []string{"ageBucket(0)", "ageBucket(-5)", "ageBucket(x)"} {
+		_, err = jm.Mask([]byte(`{"born":"2000-01-01"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "born", Action: action}}})
+		assert.ErrorIs(t, err, jsonmask.ErrUnknownAction, action)
+	}
+	assert.Panics(t, func() { jsonmask.AgeBucketFn(0) })
+}
+
+func TestJsonMaskerImpl_ChainedActions(t *testing.T) {
+	type Customer struct {
+		Name  string `json:"name" mask:"initialChar|lower"`
+		Phone string `json:"phone" mask:"limit(4)|upper,keys"`
+	}
+
+	jm := jsonmask.New(jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+	rules := jm.ParseStruct(Customer{})
+	assert.Equal(t, []string{"initialChar", "lower"}, rules.Rules[0].Actions())
+	assert.Equal(t, []string{"limit(4)", "upper"}, rules.Rules[1].Actions())
+
+	result, err := jm.Mask([]byte(`{"name":"JOHN","phone":"x"}`), jsonmask.StructMaskRules{Rules: rules.Rules[:1]})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"j","phone":"x"}`, string(result))
+
+	jm.AddFunc("initialChar", jsonmask.Upper)
+	result, err = jm.Mask([]byte(`{"name":"JOHN"}`), rules)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"john"}`, string(result))
+
+	_, err = jm.Mask([]byte(`{"name":"JOHN"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "lower|nope"}}})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+
+	assert.Empty(t, jsonmask.Lint(rules.Rules, []string{"lower", "initialChar", "upper", "limit"}))
+	assert.Len(t, jsonmask.Lint(rules.Rules, []string{"lower", "upper", "limit"}), 1)
+}
@@ -0,0 +1,254 @@
+package jsonmask
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MaskBSON applies rules to the BSON document, e.g. of MongoDB change streams
+// or oplog entries, traversing bson.Raw directly instead of converting the
+// document to JSON and back, so key order and types of values not masked are
+// kept. Selected values are passed to masking functions in relaxed Extended
+// JSON, e.g. strings and numbers as usual, ObjectID as {"$oid":"..."}, and
+// masked values are converted back the same way. Masking functions returning
+// invalid JSON set values to null. Like MaskMap, it doesn't support exclusion
+// rules and gjson modifiers, and applies rules regardless of When and Sample.
+func (jm *JsonMaskerImpl) MaskBSON(doc []byte, smr StructMaskRules, opts ...MaskOption) ([]byte, error) {
+	if jm.disabled {
+		return doc, nil
+	}
+	if err := bson.Raw(doc).Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBSON, err)
+	}
+
+	root := bson.RawValue{Type: bson.TypeEmbeddedDocument, Value: doc}
+	rules := applyMaskOptions(smr.Rules, opts)
+	if jm.unknownFields != "" && len(smr.Fields) > 0 {
+		raw, err := bsonToJSON(root)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBSON, err)
+		}
+		rules = jm.unknownFieldRules([]byte(raw), smr.Fields, rules)
+	}
+
+	for _, rule := range rules {
+		if isExclusion(rule) {
+			jm.log("jsonmask: exclusion not supported, rule skipped", "path", rule.Path)
+			continue
+		}
+
+		maskFunc, ok, err := jm.ruleFunc(rule)
+		if !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, found := cutModifierPath(rule.Path); found {
+			if jm.strict {
+				return nil, fmt.Errorf("%w: %s", ErrModifier, rule.Path)
+			}
+			jm.log("jsonmask: modifier not allowed, rule skipped", "path", rule.Path, "action", rule.Action)
+			continue
+		}
+
+		rule := rule
+		op := func(v bson.RawValue) (bson.RawValue, bool) {
+			return maskBSONValue(v, rule, maskFunc)
+		}
+
+		var found bool
+		if root, found = maskBSONTree(root, splitPath(rule.Path), op); !found {
+			jm.log("jsonmask: path not found", "path", rule.Path, "action", rule.Action)
+		}
+	}
+
+	return root.Value, nil
+}
+
+// maskBSONValue returns the BSON value masked by the rule, see maskTreeValue.
+func maskBSONValue(v bson.RawValue, rule Rule, maskFunc func(string) []byte) (res bson.RawValue, keep bool) {
+	isDocument := v.Type == bson.TypeEmbeddedDocument
+	if rule.Keys && !isDocument {
+		return v, true
+	}
+
+	if maskFunc == nil {
+		if rule.Keys {
+			return bson.RawValue{Type: bson.TypeEmbeddedDocument, Value: buildBSON(nil, nil)}, true
+		}
+		return bson.RawValue{}, false
+	}
+
+	raw, err := bsonToJSON(v)
+	if err != nil {
+		return bson.RawValue{Type: bson.TypeNull}, true
+	}
+
+	var masked []byte
+	if rule.Keys {
+		masked = maskKeys(gjson.Parse(raw), maskFunc)
+	} else {
+		masked = maskFunc(raw)
+	}
+	if string(masked) == raw {
+		return v, true // keep the original type, e.g. int64 of a small number
+	}
+
+	if res, err = jsonToBSON(masked); err != nil {
+		return bson.RawValue{Type: bson.TypeNull}, true
+	}
+	return res, true
+}
+
+// maskBSONTree applies op to values of the BSON value v selected by path
+// segments, like maskTree. It returns the new value of v and whether any value
+// was selected.
+func maskBSONTree(v bson.RawValue, segs []pathSegment, op func(bson.RawValue) (bson.RawValue, bool)) (bson.RawValue, bool) {
+	if len(segs) == 0 || (v.Type != bson.TypeEmbeddedDocument && v.Type != bson.TypeArray) {
+		return v, false
+	}
+
+	seg, rest := segs[0], segs[1:]
+	deep := seg.selector && seg.key == "**"
+	found := false
+
+	if deep && len(rest) > 0 {
+		// "**" matches zero or more levels
+		v, found = maskBSONTree(v, rest, op)
+	}
+
+	apply := func(child bson.RawValue) (bson.RawValue, bool) {
+		var f bool
+		switch {
+		case deep:
+			child, f = maskBSONTree(child, segs, op)
+			found = found || f
+			if len(rest) > 0 {
+				return child, true
+			}
+		case len(rest) > 0:
+			child, f = maskBSONTree(child, rest, op)
+			found = found || f
+			return child, true
+		}
+		found = true
+		return op(child)
+	}
+
+	elems, err := bson.Raw(v.Value).Elements()
+	if err != nil {
+		return v, false
+	}
+	keys := make([]string, len(elems))
+	values := make([]bson.RawValue, len(elems))
+	for i, e := range elems {
+		keys[i], values[i] = e.Key(), e.Value()
+	}
+
+	selected := make([]bool, len(values))
+	if v.Type == bson.TypeEmbeddedDocument {
+		for i, k := range keys {
+			switch {
+			case !seg.selector || seg.key[0] == '-':
+				// negative indexes are valid object keys
+				selected[i] = k == seg.key
+			case seg.key == "*" || deep:
+				selected[i] = true
+			}
+		}
+	} else {
+		var indexes []int
+		switch {
+		case !seg.selector:
+			if i, err := strconv.Atoi(seg.key); err == nil && i >= 0 && i < len(values) {
+				indexes = append(indexes, i)
+			}
+		case seg.key == "*" || deep:
+			indexes = selectElements(len(values), "#", nil)
+		default:
+			indexes = selectElements(len(values), seg.key, func(i int) string {
+				raw, _ := bsonToJSON(values[i])
+				return raw
+			})
+		}
+		for _, i := range indexes {
+			selected[i] = true
+		}
+	}
+	matched := false
+	for _, s := range selected {
+		matched = matched || s
+	}
+	if !matched {
+		return v, found
+	}
+
+	var resKeys []string
+	var resValues []bson.RawValue
+	for i := range values {
+		child, keep := values[i], true
+		if selected[i] {
+			child, keep = apply(child)
+		}
+		if !keep {
+			continue
+		}
+		key := keys[i]
+		if v.Type == bson.TypeArray {
+			key = strconv.Itoa(len(resValues)) // arrays are renumbered after deletions
+		}
+		resKeys = append(resKeys, key)
+		resValues = append(resValues, child)
+	}
+
+	return bson.RawValue{Type: v.Type, Value: buildBSON(resKeys, resValues)}, found
+}
+
+// bsonToJSON returns the BSON value in relaxed Extended JSON.
+func bsonToJSON(v bson.RawValue) (string, error) {
+	data, err := bson.MarshalExtJSON(bson.Raw(buildBSON([]string{"v"}, []bson.RawValue{v})), false, false)
+	if err != nil {
+		return "", err
+	}
+	return gjson.GetBytes(data, "v").Raw, nil
+}
+
+// jsonToBSON returns the BSON value of the relaxed Extended JSON value.
+func jsonToBSON(raw []byte) (bson.RawValue, error) {
+	if !gjson.ValidBytes(raw) {
+		return bson.RawValue{}, ErrInvalidJSON
+	}
+
+	var doc bson.Raw
+	if err := bson.UnmarshalExtJSON(append(append([]byte(`{"v":`), raw...), '}'), false, &doc); err != nil {
+		return bson.RawValue{}, err
+	}
+	return doc.LookupErr("v")
+}
+
+// buildBSON returns the BSON document, or array if keys are indexes,
+// holding values by keys.
+func buildBSON(keys []string, values []bson.RawValue) []byte {
+	res := make([]byte, 4, 5+len(keys)*16)
+	for i, v := range values {
+		res = append(res, byte(v.Type))
+		res = append(res, keys[i]...)
+		res = append(res, 0)
+		res = append(res, v.Value...)
+	}
+	res = append(res, 0)
+	binary.LittleEndian.PutUint32(res, uint32(len(res)))
+	return res
+}
+
+// Error definitions
+var (
+	ErrInvalidBSON = errors.New("invalid bson")
+)
@@ -0,0 +1,55 @@
+package jsonmask_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMaskBSON(t *testing.T) {
+	id := bson.NewObjectID()
+	created := bson.NewDateTimeFromTime(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	doc, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "email", Value: "john@example.com"},
+		{Key: "balance", Value: int64(1200)},
+		{Key: "created", Value: created},
+		{Key: "cards", Value: bson.A{
+			bson.D{{Key: "pan", Value: "4111111111111111"}, {Key: "primary", Value: true}},
+			bson.D{{Key: "pan", Value: "5500000000000004"}, {Key: "primary", Value: false}},
+		}},
+		{Key: "notes", Value: bson.A{"a", "b", "c"}},
+	})
+	require.NoError(t, err)
+
+	jm := jsonmask.New()
+	masked, err := jm.MaskBSON(doc, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "email", Action: "email"},
+		{Path: "balance", Action: "zero"},
+		{Path: "cards.#(primary==true)#.pan", Action: "truncate"},
+		{Path: "notes.1", Action: "-"},
+		{Path: "missing.path", Action: "null"},
+	}})
+	require.NoError(t, err)
+
+	var res bson.D
+	require.NoError(t, bson.Unmarshal(masked, &res))
+	assert.Equal(t, bson.D{
+		{Key: "_id", Value: id},
+		{Key: "email", Value: "j**n@e******.com"},
+		{Key: "balance", Value: int32(0)},
+		{Key: "created", Value: created},
+		{Key: "cards", Value: bson.A{
+			bson.D{{Key: "pan", Value: ""}, {Key: "primary", Value: true}},
+			bson.D{{Key: "pan", Value: "5500000000000004"}, {Key: "primary", Value: false}},
+		}},
+		{Key: "notes", Value: bson.A{"a", "c"}},
+	}, res)
+
+	_, err = jm.MaskBSON([]byte{1, 2, 3}, jsonmask.StructMaskRules{})
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidBSON)
+}
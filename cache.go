@@ -0,0 +1,54 @@
+package jsonmask
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is a key-value store of JSON payloads, e.g. a thin wrapper of a Redis client.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// MaskingCache masks payloads before they are written to the cache, so cached
+// API responses never store raw PII. Values masked with reversible actions,
+// like encryption or tokenization, can be restored on read by GetUnmasked.
+type MaskingCache struct {
+	jm    *JsonMaskerImpl
+	cache Cache
+	smr   StructMaskRules
+}
+
+// NewMaskingCache creates a new instance of MaskingCache storing payloads
+// to the cache masked by the rules.
+func (jm *JsonMaskerImpl) NewMaskingCache(cache Cache, smr StructMaskRules) *MaskingCache {
+	return &MaskingCache{jm: jm, cache: cache, smr: smr}
+}
+
+// Set masks the payload and writes it to the cache. Nothing is written if
+// masking fails. Payloads are masked even if masking is bypassed for ctx by Skip.
+func (mc *MaskingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	masked, err := mc.jm.Mask(value, mc.smr)
+	if err != nil {
+		return err
+	}
+	return mc.cache.Set(ctx, key, masked, ttl)
+}
+
+// Get reads the masked payload from the cache.
+func (mc *MaskingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return mc.cache.Get(ctx, key)
+}
+
+// GetUnmasked reads the payload from the cache and restores values masked
+// with reversible actions. Values masked irreversibly stay masked.
+// It fails with ErrUnmaskDenied if unmasking is guarded by WithUnmaskAuthorizer.
+func (mc *MaskingCache) GetUnmasked(ctx context.Context, key string) ([]byte, error) {
+	value, err := mc.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err = mc.jm.Unmask(value, mc.smr)
+	return value, err
+}
@@ -0,0 +1,60 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+var errCacheMiss = errors.New("cache miss")
+
+type mapCache map[string][]byte
+
+func (c mapCache) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := c[key]
+	if !ok {
+		return nil, errCacheMiss
+	}
+	return v, nil
+}
+
+func (c mapCache) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	c[key] = value
+	return nil
+}
+
+func TestMaskingCache(t *testing.T) {
+	kp := jsonmask.NewStaticKeyProvider("k1", bytes.Repeat([]byte{1}, 32))
+	jm := jsonmask.New()
+	jm.AddReversibleFunc("encrypt", jsonmask.EncryptFn(kp), jsonmask.DecryptFn(kp))
+
+	cache := mapCache{}
+	mc := jm.NewMaskingCache(cache, jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "encrypt"},
+			{Path: "password", Action: "-"},
+		},
+	})
+
+	ctx := jsonmask.Skip(context.Background())
+	err := mc.Set(ctx, "user:1", []byte(`{"id":1,"email":"john@example.com","password":"x"}`), time.Minute)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(cache["user:1"]), "john@example.com")
+	assert.NotContains(t, string(cache["user:1"]), "password")
+
+	v, err := mc.Get(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, cache["user:1"], v)
+
+	v, err = mc.GetUnmasked(ctx, "user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"email":"john@example.com"}`, string(v))
+
+	_, err = mc.GetUnmasked(ctx, "user:2")
+	assert.ErrorIs(t, err, errCacheMiss)
+}
@@ -0,0 +1,116 @@
+package jsonmask
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tidwall/gjson"
+)
+
+// WithCanonicalOutput makes masking functions of documents return them in
+// a canonical form, so masked documents can be byte-compared, hashed and
+// deduplicated reliably, see Canonicalize. It's applied after post-mask hooks.
+func WithCanonicalOutput() Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.canonical = true
+	}
+}
+
+// Canonicalize returns the JSON document in the canonical form close to
+// RFC 8785: no insignificant whitespace, object attributes sorted by names
+// compared as UTF-16 code units, numbers formatted like in JavaScript and
+// strings escaped consistently. Numbers beyond float64 precision are rounded.
+// Invalid JSON is returned as is.
+func Canonicalize(data []byte) []byte {
+	if !gjson.ValidBytes(data) {
+		return data
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(data))
+	writeCanonical(&sb, gjson.ParseBytes(data))
+	return []byte(sb.String())
+}
+
+// writeCanonical writes the value in the canonical form.
+func writeCanonical(sb *strings.Builder, v gjson.Result) {
+	switch {
+	case v.IsObject():
+		type attr struct {
+			name  string
+			value gjson.Result
+		}
+		var attrs []attr
+		v.ForEach(func(key, value gjson.Result) bool {
+			attrs = append(attrs, attr{key.Str, value})
+			return true
+		})
+		sort.SliceStable(attrs, func(i, j int) bool {
+			return lessUTF16(attrs[i].name, attrs[j].name)
+		})
+
+		sb.WriteByte('{')
+		for i, a := range attrs {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.Write(quote(a.name))
+			sb.WriteByte(':')
+			writeCanonical(sb, a.value)
+		}
+		sb.WriteByte('}')
+	case v.IsArray():
+		sb.WriteByte('[')
+		i := 0
+		v.ForEach(func(_, value gjson.Result) bool {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeCanonical(sb, value)
+			i++
+			return true
+		})
+		sb.WriteByte(']')
+	case v.Type == gjson.String:
+		sb.Write(quote(v.Str))
+	case v.Type == gjson.Number:
+		sb.WriteString(formatNumber(v.Float()))
+	default:
+		sb.WriteString(v.Raw) // true, false, null
+	}
+}
+
+// lessUTF16 compares strings as sequences of UTF-16 code units.
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// formatNumber formats the number like JavaScript's Number.prototype.toString,
+// e.g. 1e+21, 0.000001, 1e-7.
+func formatNumber(f float64) string {
+	if f == 0 {
+		return "0" // negative zero included
+	}
+
+	abs := f
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	// Go writes at least two exponent digits, e.g. 1e-07
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(s, "e")
+	sign, digits := exp[:1], strings.TrimLeft(exp[1:], "0")
+	return mantissa + "e" + sign + digits
+}
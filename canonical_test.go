@@ -0,0 +1,37 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{ "b": 1, "a": [ 1.50, 2E3, -0, 1e21, 0.0000001, 100 ] }`, `{"a":[1.5,2000,0,1e+21,1e-7,100],"b":1}`},
+		{`{"\ufb33":2,"\u20ac":"A<>","\ud83d\ude00":1}`, "{\"\u20ac\":\"A<>\",\"\U0001F600\":1,\"\uFB33\":2}"},
+		{`[true,null,{"z":{"y":1,"x":2}}]`, `[true,null,{"z":{"x":2,"y":1}}]`},
+		{`"a\/b"`, `"a/b"`},
+		{`{`, `{`},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, string(jsonmask.Canonicalize([]byte(tt.input))), tt.input)
+	}
+}
+
+func TestWithCanonicalOutput(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithCanonicalOutput())
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "null"}}}
+
+	a, err := jm.Mask([]byte(`{"email":"a@example.com", "amount": 1.0, "id": 7}`), smr)
+	assert.NoError(t, err)
+	b, err := jm.Mask([]byte(`{"id":7,"amount":1,"email":"b@example.com"}`), smr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":1,"email":null,"id":7}`, string(a))
+	assert.Equal(t, a, b)
+}
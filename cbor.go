@@ -0,0 +1,172 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR is a Codec of CBOR documents having maps at the root, e.g. IoT telemetry
+// or COSE payloads like CWT claims. Documents are encoded back in the core
+// deterministic encoding, so signatures can be recomputed over masked payloads.
+// Byte strings are passed to masking functions base64 encoded, masked values
+// are decoded like by MaskMap, so numbers become floats.
+//
+// Integer keys, e.g. of COSE headers and CWT claims, are addressed by rules in
+// their decimal form, e.g. "4" or "-260", and encoded back as integers. The
+// codec remembers them between Decode and Encode of a document, so the shared
+// CBOR value rejects maps with integer keys; MaskCBOR and codecs returned by
+// NewCBORCodec, one per document, accept them. Keys of other types are rejected.
+var CBOR Codec = cborCodec{}
+
+// NewCBORCodec returns a CBOR codec accepting integer keys, for a single document.
+func NewCBORCodec() Codec {
+	return cborCodec{intKeys: make(map[uintptr]cborIntKeys)}
+}
+
+// cborModes holds modes of CBOR encoding shared by cborCodec.
+var cborModes = struct {
+	dec cbor.DecMode
+	enc cbor.EncMode
+}{
+	dec: mustMode(cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[any]any(nil))}.DecMode()),
+	enc: mustMode(cbor.CoreDetEncOptions().EncMode()),
+}
+
+// mustMode returns the mode, panicking on invalid options.
+func mustMode[T any](mode T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
+
+// cborCodec implements Codec of CBOR documents. intKeys holds original integer
+// keys of decoded maps, keyed by identity of the maps; nil rejects integer keys.
+type cborCodec struct {
+	intKeys map[uintptr]cborIntKeys
+}
+
+// cborIntKeys holds original integer keys of the decoded map by their decimal
+// form. It keeps the map referenced, so its identity isn't reused.
+type cborIntKeys struct {
+	m    map[string]any
+	keys map[string]any
+}
+
+// Decode implements Codec.
+func (c cborCodec) Decode(data []byte) (map[string]any, error) {
+	var raw any
+	if err := cborModes.dec.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCBOR, err)
+	}
+	if _, ok := raw.(map[any]any); !ok {
+		return nil, fmt.Errorf("%w: root is not a map", ErrInvalidCBOR)
+	}
+
+	doc, err := c.decodeValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	return doc.(map[string]any), nil
+}
+
+// decodeValue converts maps of the decoded value to maps with string keys.
+func (c cborCodec) decodeValue(v any) (any, error) {
+	switch v := v.(type) {
+	case map[any]any:
+		res := make(map[string]any, len(v))
+		var ints map[string]any
+		for k, val := range v {
+			var key string
+			switch k := k.(type) {
+			case string:
+				key = k
+			case uint64:
+				key = strconv.FormatUint(k, 10)
+			case int64:
+				key = strconv.FormatInt(k, 10)
+			default:
+				return nil, fmt.Errorf("%w: unsupported map key of type %T", ErrInvalidCBOR, k)
+			}
+			if _, ok := k.(string); !ok {
+				if c.intKeys == nil {
+					return nil, fmt.Errorf("%w: integer map key %s, use MaskCBOR or NewCBORCodec", ErrInvalidCBOR, key)
+				}
+				if ints == nil {
+					ints = make(map[string]any)
+				}
+				ints[key] = k
+			}
+			if _, ok := res[key]; ok {
+				return nil, fmt.Errorf("%w: ambiguous map key %q", ErrInvalidCBOR, key)
+			}
+
+			dv, err := c.decodeValue(val)
+			if err != nil {
+				return nil, err
+			}
+			res[key] = dv
+		}
+		if ints != nil {
+			c.intKeys[reflect.ValueOf(res).Pointer()] = cborIntKeys{m: res, keys: ints}
+		}
+		return res, nil
+	case []any:
+		for i := range v {
+			dv, err := c.decodeValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = dv
+		}
+		return v, nil
+	}
+	return v, nil
+}
+
+// Encode implements Codec.
+func (c cborCodec) Encode(doc map[string]any) ([]byte, error) {
+	return cborModes.enc.Marshal(c.encodeValue(doc))
+}
+
+// encodeValue restores integer keys of maps decoded by the codec.
+func (c cborCodec) encodeValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		ints := c.intKeys[reflect.ValueOf(v).Pointer()].keys
+		if ints == nil {
+			for k, val := range v {
+				v[k] = c.encodeValue(val)
+			}
+			return v
+		}
+		res := make(map[any]any, len(v))
+		for k, val := range v {
+			if ik, ok := ints[k]; ok {
+				res[ik] = c.encodeValue(val)
+			} else {
+				res[k] = c.encodeValue(val)
+			}
+		}
+		return res
+	case []any:
+		for i := range v {
+			v[i] = c.encodeValue(v[i])
+		}
+	}
+	return v
+}
+
+// MaskCBOR applies rules to the CBOR document, see CBOR and MaskCodec.
+func (jm *JsonMaskerImpl) MaskCBOR(data []byte, smr StructMaskRules) ([]byte, error) {
+	return jm.MaskCodec(data, NewCBORCodec(), smr)
+}
+
+// Error definitions
+var (
+	ErrInvalidCBOR = errors.New("invalid cbor")
+)
@@ -0,0 +1,80 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskCBOR(t *testing.T) {
+	data, err := cbor.Marshal(map[string]any{
+		"device": "sensor-7",
+		"owner":  map[string]any{"email": "john@example.com", "phone": "+15551234567"},
+		"readings": []any{
+			map[string]any{"t": 20, "lat": 52.52},
+			map[string]any{"t": 21, "lat": 52.53},
+		},
+	})
+	require.NoError(t, err)
+
+	jm := jsonmask.New()
+	masked, err := jm.MaskCBOR(data, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "owner.email", Action: "email"},
+		{Path: "owner.phone", Action: "-"},
+		{Path: "readings.#.lat", Action: "round(1)"},
+	}})
+	require.NoError(t, err)
+
+	var res map[string]any
+	require.NoError(t, cbor.Unmarshal(masked, &res))
+	assert.Equal(t, map[any]any{"email": "j**n@e******.com"}, res["owner"])
+	assert.Equal(t, "sensor-7", res["device"])
+	assert.Equal(t, []any{
+		map[any]any{"t": uint64(20), "lat": 53.0},
+		map[any]any{"t": uint64(21), "lat": 53.0},
+	}, res["readings"])
+
+	_, err = jm.MaskCBOR([]byte{0xff}, jsonmask.StructMaskRules{})
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidCBOR)
+}
+
+func TestMaskCBORIntegerKeys(t *testing.T) {
+	// CWT claims: 1 iss, 2 sub, 4 exp, with a private text claim.
+	data, err := cbor.Marshal(map[any]any{
+		1:       "issuer.example.com",
+		2:       "john@example.com",
+		4:       1700000000,
+		"email": "john@example.com",
+		"cnf":   map[any]any{-1: "key", 3: "alg"},
+	})
+	require.NoError(t, err)
+
+	jm := jsonmask.New()
+	masked, err := jm.MaskCBOR(data, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "2", Action: "email"},
+		{Path: "email", Action: "email"},
+		{Path: "cnf.-1", Action: "-"},
+	}})
+	require.NoError(t, err)
+
+	var res map[any]any
+	require.NoError(t, cbor.Unmarshal(masked, &res))
+	assert.Equal(t, map[any]any{
+		uint64(1): "issuer.example.com",
+		uint64(2): "j**n@e******.com",
+		uint64(4): uint64(1700000000),
+		"email":   "j**n@e******.com",
+		"cnf":     map[any]any{uint64(3): "alg"},
+	}, res)
+
+	_, err = jsonmask.CBOR.Decode(data)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidCBOR)
+
+	ambiguous, err := cbor.Marshal(map[any]any{1: "a", "1": "b"})
+	require.NoError(t, err)
+	_, err = jm.MaskCBOR(ambiguous, jsonmask.StructMaskRules{})
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidCBOR)
+}
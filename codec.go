@@ -0,0 +1,27 @@
+package jsonmask
+
+// Codec converts documents of a binary format, e.g. CBOR or MessagePack, to
+// the tree masked by MaskMap and back, so the format can be masked by the same
+// rules and functions as JSON. Decode must return objects as map[string]any
+// and arrays as []any.
+type Codec interface {
+	Decode(data []byte) (map[string]any, error)
+	Encode(doc map[string]any) ([]byte, error)
+}
+
+// MaskCodec decodes the document by the codec, masks it like MaskMap
+// and encodes it back.
+func (jm *JsonMaskerImpl) MaskCodec(data []byte, c Codec, smr StructMaskRules) ([]byte, error) {
+	if jm.disabled {
+		return data, nil
+	}
+
+	doc, err := c.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := jm.MaskMap(doc, smr); err != nil {
+		return nil, err
+	}
+	return c.Encode(doc)
+}
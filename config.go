@@ -0,0 +1,109 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvDisabled  = "JSONMASK_DISABLED"
+	EnvProfile   = "JSONMASK_PROFILE"
+	EnvStrict    = "JSONMASK_STRICT"
+	EnvRulesFile = "JSONMASK_RULES_FILE"
+)
+
+// DefaultProfile is a profile used if JSONMASK_PROFILE is not set.
+const DefaultProfile = "default"
+
+// Config holds masking settings adjustable by operators without code changes.
+type Config struct {
+	// Disabled turns masking off, Mask returns data as is.
+	Disabled bool
+
+	// Profile selects the group of named rule sets loaded from the rules file.
+	Profile string
+
+	// Strict makes Mask fail on rules with unknown actions instead of skipping them.
+	Strict bool
+
+	// RulesFile is a path to JSON file holding named rule sets per profile:
+	//
+	//	{"default": {"customer": {"rules": [{"path": "email", "action": "email"}]}}}
+	RulesFile string
+
+	// Rules holds named rule sets of the selected profile loaded from RulesFile.
+	Rules map[string]StructMaskRules
+}
+
+// ConfigFromEnv reads Config from environment variables JSONMASK_DISABLED,
+// JSONMASK_PROFILE, JSONMASK_STRICT and JSONMASK_RULES_FILE, loading named rule
+// sets of the selected profile if the rules file is set.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Profile:   os.Getenv(EnvProfile),
+		RulesFile: os.Getenv(EnvRulesFile),
+	}
+
+	if cfg.Profile == "" {
+		cfg.Profile = DefaultProfile
+	}
+
+	var err error
+	if cfg.Disabled, err = envBool(EnvDisabled); err != nil {
+		return cfg, err
+	}
+	if cfg.Strict, err = envBool(EnvStrict); err != nil {
+		return cfg, err
+	}
+
+	if cfg.RulesFile == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(cfg.RulesFile)
+	if err != nil {
+		return cfg, err
+	}
+
+	var profiles map[string]map[string]StructMaskRules
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", cfg.RulesFile, err)
+	}
+
+	rules, ok := profiles[cfg.Profile]
+	if !ok {
+		return cfg, fmt.Errorf("profile %q not found in %s", cfg.Profile, cfg.RulesFile)
+	}
+	cfg.Rules = rules
+
+	return cfg, nil
+}
+
+// envBool parses the boolean environment variable. Unset variable is false.
+func envBool(name string) (bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q: %w", name, v, err)
+	}
+	return b, nil
+}
+
+// WithConfig applies the configuration, registering its named rule sets.
+// Rule sets rejected by AddRules are reported by SelfCheck.
+func WithConfig(cfg Config) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.disabled = cfg.Disabled
+		jm.strict = cfg.Strict
+		for name, smr := range cfg.Rules {
+			jm.registerErr(jm.AddRules(name, smr))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package jsonmask_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	err := os.WriteFile(file, []byte(`{
+		"default": {"customer": {"rules": [{"path": "name", "action": "initialChar"}]}},
+		"strict": {"customer": {"rules": [{"path": "name", "action": "-"}]}}
+	}`), 0o600)
+	assert.NoError(t, err)
+
+	t.Run("Profile", func(t *testing.T) {
+		t.Setenv(jsonmask.EnvRulesFile, file)
+		t.Setenv(jsonmask.EnvProfile, "strict")
+		t.Setenv(jsonmask.EnvStrict, "true")
+
+		cfg, err := jsonmask.ConfigFromEnv()
+		assert.NoError(t, err)
+		assert.True(t, cfg.Strict)
+		assert.False(t, cfg.Disabled)
+
+		jm := jsonmask.New(jsonmask.WithConfig(cfg))
+		rules, ok := jm.Rules("customer")
+		assert.True(t, ok)
+
+		result, err := jm.Mask([]byte(`{"name":"john","id":1}`), rules)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"id":1}`, string(result))
+
+		_, err = jm.Mask([]byte(`{"name":"john"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "unknown"}}})
+		assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+	})
+
+	t.Run("DefaultProfile", func(t *testing.T) {
+		t.Setenv(jsonmask.EnvRulesFile, file)
+
+		cfg, err := jsonmask.ConfigFromEnv()
+		assert.NoError(t, err)
+		assert.Equal(t, jsonmask.DefaultProfile, cfg.Profile)
+		assert.Equal(t, "initialChar", cfg.Rules["customer"].Rules[0].Action)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Setenv(jsonmask.EnvDisabled, "1")
+
+		cfg, err := jsonmask.ConfigFromEnv()
+		assert.NoError(t, err)
+
+		jm := jsonmask.New(jsonmask.WithConfig(cfg))
+		result, err := jm.Mask([]byte(`{"name":"john"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "-"}}})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name":"john"}`, string(result))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Setenv(jsonmask.EnvStrict, "maybe")
+		_, err := jsonmask.ConfigFromEnv()
+		assert.Error(t, err)
+
+		t.Setenv(jsonmask.EnvStrict, "")
+		t.Setenv(jsonmask.EnvRulesFile, file)
+		t.Setenv(jsonmask.EnvProfile, "unknown")
+		_, err = jsonmask.ConfigFromEnv()
+		assert.Error(t, err)
+	})
+}
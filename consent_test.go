@@ -0,0 +1,64 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_When(t *testing.T) {
+	type Subject struct {
+		Email    string `json:"email" mask:"email,when=!consents.marketing==true"`
+		Phone    string `json:"phone" mask:"-,when=!consents.marketing==true"`
+		Name     string `json:"name"`
+		Consents struct {
+			Marketing bool `json:"marketing"`
+		} `json:"consents"`
+	}
+
+	jm := jsonmask.New()
+	smr := jm.ParseStruct(Subject{})
+	assert.Equal(t, "!consents.marketing==true", smr.Rules[0].When)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"consent given",
+			`{"email":"john@example.com","phone":"123","consents":{"marketing":true}}`,
+			`{"email":"john@example.com","phone":"123","consents":{"marketing":true}}`,
+		},
+		{
+			"consent refused",
+			`{"email":"john@example.com","phone":"123","consents":{"marketing":false}}`,
+			`{"email":"j**n@e******.com","consents":{"marketing":false}}`,
+		},
+		{
+			"consent missing",
+			`{"email":"john@example.com","phone":"123"}`,
+			`{"email":"j**n@e******.com"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := jm.Mask([]byte(tt.input), smr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(result))
+		})
+	}
+
+	// conditional exclusion keeps values of subjects who opted in
+	rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "contact", Action: "null"},
+		{Path: "!contact", When: `optIn=="yes"`},
+	}}
+	result, err := jm.Mask([]byte(`{"contact":"x","optIn":"yes"}`), rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"contact":"x","optIn":"yes"}`, string(result))
+	result, err = jm.Mask([]byte(`{"contact":"x","optIn":"no"}`), rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"contact":null,"optIn":"no"}`, string(result))
+}
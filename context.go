@@ -0,0 +1,27 @@
+package jsonmask
+
+import "context"
+
+type skipKey struct{}
+
+// Skip returns a copy of ctx marking the request as privileged, so MaskContext
+// returns data unmasked. It's meant for authorized internal tooling receiving
+// unmasked data through the same code path; every bypass is logged.
+func Skip(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// IsSkipped reports whether masking is bypassed for ctx by Skip.
+func IsSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipKey{}).(bool)
+	return skip
+}
+
+// MaskContext is like Mask but returns data as is if masking is bypassed for ctx by Skip.
+func (jm *JsonMaskerImpl) MaskContext(ctx context.Context, data []byte, smr StructMaskRules, opts ...MaskOption) ([]byte, error) {
+	if IsSkipped(ctx) {
+		jm.log("jsonmask: masking skipped by context", "rules", len(smr.Rules))
+		return data, nil
+	}
+	return jm.Mask(data, smr, opts...)
+}
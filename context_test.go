@@ -0,0 +1,32 @@
+package jsonmask_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskContext(t *testing.T) {
+	l := &testLogger{}
+	jm := jsonmask.New(jsonmask.WithLogger(l, jsonmask.LevelInfo))
+	rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "upper"}}}
+	data := []byte(`{"name":"john"}`)
+
+	ctx := context.Background()
+	assert.False(t, jsonmask.IsSkipped(ctx))
+
+	result, err := jm.MaskContext(ctx, data, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"JOHN"}`, string(result))
+	assert.Empty(t, l.messages)
+
+	ctx = jsonmask.Skip(ctx)
+	assert.True(t, jsonmask.IsSkipped(ctx))
+
+	result, err = jm.MaskContext(ctx, data, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"john"}`, string(result))
+	assert.Equal(t, []string{"INFO jsonmask: masking skipped by context [rules 1]"}, l.messages)
+}
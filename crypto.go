@@ -0,0 +1,245 @@
+package jsonmask
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Prefixes of values produced by crypto maskers.
+const (
+	HMACPrefix    = "hmac:"
+	EncryptPrefix = "enc:"
+)
+
+// KeyProvider provides keys to crypto maskers. Masked values embed the identifier
+// of the key used, so values masked before a key rotation can still be verified
+// or decrypted with the old key.
+type KeyProvider interface {
+	// CurrentKey returns the key used to mask new values and its identifier.
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key by its identifier.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider holding keys in memory.
+type StaticKeyProvider struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeyProvider creates a new instance of StaticKeyProvider with a single key.
+func NewStaticKeyProvider(id string, key []byte) *StaticKeyProvider {
+	return &StaticKeyProvider{
+		current: id,
+		keys:    map[string][]byte{id: key},
+	}
+}
+
+// Rotate adds the key and makes it current. Previous keys are kept for decryption.
+func (kp *StaticKeyProvider) Rotate(id string, key []byte) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.keys[id] = key
+	kp.current = id
+}
+
+// CurrentKey implements KeyProvider.
+func (kp *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	kp.mu.RLock()
+	defer kp.mu.RUnlock()
+	return kp.current, kp.keys[kp.current], nil
+}
+
+// Key implements KeyProvider.
+func (kp *StaticKeyProvider) Key(id string) ([]byte, error) {
+	kp.mu.RLock()
+	defer kp.mu.RUnlock()
+	key, ok := kp.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// HMACFn returns a function that replaces the input value with its HMAC-SHA256
+// computed with the current key of the provider, e.g. "hmac:k1:Xb3...".
+// Equal values give equal results while the key is not rotated, so masked values
+// can still be joined on. The last bytes of the HMAC are replaced by a tag
+// authenticating the value as masked with the key, so NULL and values already
+// masked, e.g. by re-processing of archived documents, are returned as is,
+// while values merely looking masked are masked.
+func HMACFn(kp KeyProvider) func(string) []byte {
+	return func(s string) []byte {
+		if s == "null" || hmacMasked(kp, s) {
+			return []byte(s)
+		}
+
+		id, key, err := kp.CurrentKey()
+		if err != nil {
+			return []byte(`null`)
+		}
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(s))
+		sum := mac.Sum(nil)[:sha256.Size-hmacTagSize]
+		sum = append(sum, hmacTag(key, sum)...)
+		return quote(HMACPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(sum))
+	}
+}
+
+// hmacTagSize is the number of trailing bytes of values masked by HMACFn
+// authenticating them.
+const hmacTagSize = 8
+
+// hmacTag returns the tag authenticating the truncated HMAC as masked with the key.
+func hmacTag(key, sum []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("jsonmask:hmac-tag\x00"))
+	mac.Write(sum)
+	return mac.Sum(nil)[:hmacTagSize]
+}
+
+// hmacMasked reports whether the raw JSON value was masked by HMACFn with a key
+// of the provider, verifying its tag.
+func hmacMasked(kp KeyProvider, s string) bool {
+	str, ok := unquote(s)
+	if !ok || !strings.HasPrefix(str, HMACPrefix) {
+		return false
+	}
+
+	id, encoded, ok := cutKeyID(strings.TrimPrefix(str, HMACPrefix))
+	if !ok || id == "" {
+		return false
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sum) != sha256.Size {
+		return false
+	}
+
+	key, err := kp.Key(id)
+	if err != nil {
+		return false
+	}
+	n := sha256.Size - hmacTagSize
+	return hmac.Equal(sum[n:], hmacTag(key, sum[:n]))
+}
+
+// EncryptFn returns a function that replaces the input value with its AES-GCM
+// ciphertext encrypted with the current key of the provider, e.g. "enc:k1:Zm9v...".
+// The original JSON value, including its type, is restored by DecryptFn.
+// Keys must be 16, 24 or 32 bytes long. NULL and values already encrypted with
+// keys of the provider, being authenticated by decryption, are returned as is.
+func EncryptFn(kp KeyProvider) func(string) []byte {
+	decrypt := DecryptFn(kp)
+	return func(s string) []byte {
+		if s == "null" {
+			return []byte(s)
+		}
+		if _, err := decrypt(s); err == nil {
+			return []byte(s) // already encrypted
+		}
+
+		res, err := encrypt(kp, []byte(s))
+		if err != nil {
+			return []byte(`null`)
+		}
+		return quote(res)
+	}
+}
+
+// encrypt returns the plaintext encrypted with the current key of the provider,
+// e.g. "enc:k1:Zm9v...".
+func encrypt(kp KeyProvider, plaintext []byte) (string, error) {
+	id, key, err := kp.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(id))
+	return EncryptPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptFn returns a function that restores the original JSON value encrypted
+// by EncryptFn, using the key identified in the value.
+func DecryptFn(kp KeyProvider) func(string) ([]byte, error) {
+	return func(s string) ([]byte, error) {
+		if s == "null" {
+			return []byte(s), nil
+		}
+
+		str, ok := unquote(s)
+		if !ok || !strings.HasPrefix(str, EncryptPrefix) {
+			return nil, ErrInvalidCiphertext
+		}
+
+		id, encoded, ok := cutKeyID(strings.TrimPrefix(str, EncryptPrefix))
+		if !ok {
+			return nil, ErrInvalidCiphertext
+		}
+
+		sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, ErrInvalidCiphertext
+		}
+
+		key, err := kp.Key(id)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(sealed) < aead.NonceSize() {
+			return nil, ErrInvalidCiphertext
+		}
+
+		return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	}
+}
+
+// cutKeyID splits the masked value without the prefix into the key identifier
+// and base64url encoded bytes around the last ':', which base64url never
+// contains, so key identifiers may contain ':'.
+func cutKeyID(s string) (id, encoded string, ok bool) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Error definitions
+var (
+	ErrKeyNotFound       = errors.New("key not found")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
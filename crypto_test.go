@@ -0,0 +1,91 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACFn(t *testing.T) {
+	kp := jsonmask.NewStaticKeyProvider("k1", []byte("secret-1"))
+	f := jsonmask.HMACFn(kp)
+
+	first := string(f(`"john@example.com"`))
+	assert.True(t, strings.HasPrefix(first, `"hmac:k1:`), first)
+	assert.Equal(t, first, string(f(`"john@example.com"`)))
+	assert.NotEqual(t, first, string(f(`"jane@example.com"`)))
+	assert.Equal(t, `null`, string(f(`null`)))
+
+	// already masked values are kept, so re-processing doesn't break joins
+	assert.Equal(t, first, string(f(first)))
+	assert.NotEqual(t, `"hmac:k1:short"`, string(f(`"hmac:k1:short"`)))
+	forged := `"hmac:k1:` + strings.Repeat("A", 43) + `"`
+	assert.NotEqual(t, forged, string(f(forged)), "values looking masked are verified")
+	other := string(jsonmask.HMACFn(jsonmask.NewStaticKeyProvider("k1", []byte("other")))(`"john@example.com"`))
+	assert.NotEqual(t, other, string(f(other)), "values masked with another key are masked")
+
+	kp.Rotate("k2", []byte("secret-2"))
+	assert.True(t, strings.HasPrefix(string(f(`"john@example.com"`)), `"hmac:k2:`))
+	assert.Equal(t, first, string(f(first)))
+}
+
+func TestEncryptFn(t *testing.T) {
+	kp := jsonmask.NewStaticKeyProvider("k1", bytes.Repeat([]byte{1}, 32))
+	encrypt := jsonmask.EncryptFn(kp)
+	decrypt := jsonmask.DecryptFn(kp)
+
+	for _, value := range []string{`"john@example.com"`, `123.45`, `{"a":[1,2]}`} {
+		masked := encrypt(value)
+		assert.True(t, bytes.HasPrefix(masked, []byte(`"enc:k1:`)), string(masked))
+		assert.NotContains(t, string(masked), value)
+
+		restored, err := decrypt(string(masked))
+		assert.NoError(t, err)
+		assert.Equal(t, value, string(restored))
+	}
+
+	t.Run("Rotation", func(t *testing.T) {
+		old := encrypt(`"john"`)
+		kp.Rotate("k2", bytes.Repeat([]byte{2}, 32))
+
+		masked := encrypt(`"john"`)
+		assert.True(t, bytes.HasPrefix(masked, []byte(`"enc:k2:`)))
+
+		restored, err := decrypt(string(old))
+		assert.NoError(t, err)
+		assert.Equal(t, `"john"`, string(restored))
+	})
+
+	t.Run("AlreadyEncrypted", func(t *testing.T) {
+		masked := encrypt(`"john"`)
+		assert.Equal(t, string(masked), string(encrypt(string(masked))))
+		assert.NotEqual(t, `"enc:k1:AAAA"`, string(encrypt(`"enc:k1:AAAA"`)))
+	})
+
+	t.Run("KeyIDWithColon", func(t *testing.T) {
+		kp := jsonmask.NewStaticKeyProvider("kms:eu:1", bytes.Repeat([]byte{3}, 32))
+		masked := jsonmask.EncryptFn(kp)(`"john"`)
+		assert.True(t, bytes.HasPrefix(masked, []byte(`"enc:kms:eu:1:`)), string(masked))
+
+		restored, err := jsonmask.DecryptFn(kp)(string(masked))
+		assert.NoError(t, err)
+		assert.Equal(t, `"john"`, string(restored))
+
+		hashed := jsonmask.HMACFn(kp)(`"john"`)
+		assert.Equal(t, string(hashed), string(jsonmask.HMACFn(kp)(string(hashed))))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := decrypt(`"plain"`)
+		assert.ErrorIs(t, err, jsonmask.ErrInvalidCiphertext)
+
+		_, err = decrypt(`"enc:unknown:AAAA"`)
+		assert.ErrorIs(t, err, jsonmask.ErrKeyNotFound)
+
+		_, err = decrypt(`"enc:k1:AAAA"`)
+		assert.ErrorIs(t, err, jsonmask.ErrInvalidCiphertext)
+	})
+}
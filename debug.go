@@ -0,0 +1,208 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds counters of masking calls since the masker was created.
+type Stats struct {
+	Documents  uint64            `json:"documents"`            // documents masked by Mask
+	Errors     uint64            `json:"errors"`               // Mask calls failed
+	Bytes      uint64            `json:"bytes"`                // size of documents masked
+	Values     map[string]uint64 `json:"values"`               // values masked by action
+	LastMasked time.Time         `json:"lastMasked,omitempty"` // time of the last document masked
+	LastError  string            `json:"lastError,omitempty"`
+}
+
+// maskStats collects Stats concurrently.
+type maskStats struct {
+	documents atomic.Uint64
+	errors    atomic.Uint64
+	bytes     atomic.Uint64
+	values    sync.Map // action -> *atomic.Uint64
+
+	mu         sync.Mutex
+	lastMasked time.Time
+	lastError  string
+}
+
+// masked counts the document masked or failed.
+func (s *maskStats) masked(size int, err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.errors.Add(1)
+	} else {
+		s.documents.Add(1)
+		s.bytes.Add(uint64(size))
+	}
+
+	s.mu.Lock()
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		s.lastMasked = timeNow()
+	}
+	s.mu.Unlock()
+}
+
+// applied counts values masked by the action.
+func (s *maskStats) applied(action string, n int) {
+	if s == nil || n == 0 {
+		return
+	}
+	c, ok := s.values.Load(action)
+	if !ok {
+		c, _ = s.values.LoadOrStore(action, new(atomic.Uint64))
+	}
+	c.(*atomic.Uint64).Add(uint64(n))
+}
+
+// Stats returns counters of masking calls of the masker. Tenant maskers of
+// a Manager count their own calls.
+func (jm *JsonMaskerImpl) Stats() Stats {
+	res := Stats{Values: make(map[string]uint64)}
+	s := jm.stats
+	if s == nil {
+		return res
+	}
+
+	res.Documents = s.documents.Load()
+	res.Errors = s.errors.Load()
+	res.Bytes = s.bytes.Load()
+	s.values.Range(func(key, value any) bool {
+		res.Values[key.(string)] = value.(*atomic.Uint64).Load()
+		return true
+	})
+
+	s.mu.Lock()
+	res.LastMasked, res.LastError = s.lastMasked, s.lastError
+	s.mu.Unlock()
+	return res
+}
+
+// debugRules is a version of a rule set listed by DebugHandler.
+type debugRules struct {
+	Version string `json:"version,omitempty"`
+	Rules   []Rule `json:"rules"`
+}
+
+// debugCache lists cached entries of the masker.
+type debugCache struct {
+	Types   map[string][]Rule `json:"types"`   // rules extracted by ParseStruct by type
+	Actions []string          `json:"actions"` // parametrized actions resolved by factories
+}
+
+// DebugHandler returns an http.Handler exposing the live configuration of the
+// masker as JSON, so operators can verify it, e.g. mounted by
+//
+//	mux.Handle("/debug/jsonmask/", jm.DebugHandler())
+//
+// Paths ending with "funcs", "factories", "rules", "cache", "stats" and
+// "overrides" return the section only, other paths return all sections.
+// Functions, factories and rule sets of parents are included. Rules may reveal
+// what is considered sensitive, so the handler should not be exposed publicly.
+func (jm *JsonMaskerImpl) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sections := map[string]func() any{
+			"funcs":     func() any { return jm.funcNames() },
+			"factories": func() any { return jm.factoryNames() },
+			"rules":     func() any { return jm.debugRules() },
+			"cache":     func() any { return jm.debugCache() },
+			"stats":     func() any { return jm.Stats() },
+			"overrides": func() any { return jm.RuleOverrides() },
+		}
+
+		var res any
+		if section, ok := sections[path.Base(r.URL.Path)]; ok {
+			res = section()
+		} else {
+			all := make(map[string]any, len(sections))
+			for name, section := range sections {
+				all[name] = section()
+			}
+			res = all
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+	})
+}
+
+// funcNames returns sorted names of functions registered in the masker and its parents.
+func (jm *JsonMaskerImpl) funcNames() []string {
+	seen := make(map[string]bool)
+	for p := jm; p != nil; p = p.parent {
+		for name := range p.funcs {
+			seen[name] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// factoryNames returns sorted names of factories registered in the masker and its parents.
+func (jm *JsonMaskerImpl) factoryNames() []string {
+	seen := make(map[string]bool)
+	for p := jm; p != nil; p = p.parent {
+		for name := range p.factories {
+			seen[name] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// debugRules returns versions of rule sets registered in the masker by name.
+// Rule sets of the masker hide rule sets of parents with the same name.
+func (jm *JsonMaskerImpl) debugRules() map[string][]debugRules {
+	res := make(map[string][]debugRules)
+	for p := jm; p != nil; p = p.parent {
+		for name, versions := range p.rules {
+			if _, ok := res[name]; ok {
+				continue
+			}
+			list := make([]debugRules, len(versions))
+			for i, smr := range versions {
+				list[i] = debugRules{Version: smr.Version, Rules: smr.Rules}
+			}
+			res[name] = list
+		}
+	}
+	return res
+}
+
+// debugCache returns types and actions cached by the masker.
+func (jm *JsonMaskerImpl) debugCache() debugCache {
+	res := debugCache{Types: make(map[string][]Rule), Actions: []string{}}
+	jm.cache.Range(func(key, value any) bool {
+		if t, ok := key.(reflect.Type); ok {
+			res.Types[t.String()] = value.([]Rule)
+		}
+		return true
+	})
+	jm.resolved.Range(func(key, _ any) bool {
+		res.Actions = append(res.Actions, key.(string))
+		return true
+	})
+	sort.Strings(res.Actions)
+	return res
+}
+
+// sortedKeys returns keys of the set in ascending order.
+func sortedKeys(set map[string]bool) []string {
+	res := make([]string, 0, len(set))
+	for key := range set {
+		res = append(res, key)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,83 @@
+package jsonmask_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestStats(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithPostMaskHook(func(data []byte) ([]byte, error) {
+		if gjson.GetBytes(data, "fail").Bool() {
+			return nil, errors.New("boom")
+		}
+		return data, nil
+	}))
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "emails.#", Action: "email"},
+		{Path: "name", Action: "initials"},
+	}}
+
+	_, err := jm.Mask([]byte(`{"emails":["a@b.c","d@e.f"],"name":"John Smith"}`), smr)
+	assert.NoError(t, err)
+
+	_, err = jm.Mask([]byte(`{"fail":true}`), smr)
+	assert.Error(t, err)
+
+	stats := jm.Stats()
+	assert.Equal(t, uint64(1), stats.Documents)
+	assert.Equal(t, uint64(1), stats.Errors)
+	assert.Equal(t, uint64(48), stats.Bytes)
+	assert.Equal(t, map[string]uint64{"email": 2, "initials": 1}, stats.Values)
+	assert.False(t, stats.LastMasked.IsZero())
+	assert.Equal(t, "boom", stats.LastError)
+}
+
+func TestDebugHandler(t *testing.T) {
+	type Customer struct {
+		Email string `json:"email" mask:"email"`
+	}
+
+	jm := jsonmask.New()
+	jm.AddFunc("phone", jsonmask.Null)
+	jm.AddRules("customer", jm.ParseStruct(Customer{}))
+	_, err := jm.Mask([]byte(`{"email":"a@b.c","id":"12345678"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "email", Action: "email"},
+		{Path: "id", Action: "limit(4)"},
+	}})
+	assert.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/jsonmask/", jm.DebugHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	get := func(path string) gjson.Result {
+		resp, err := http.Get(srv.URL + path)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return gjson.ParseBytes(body)
+	}
+
+	all := get("/debug/jsonmask/")
+	assert.Contains(t, all.Get("funcs").String(), `"phone"`)
+	assert.Contains(t, all.Get("factories").String(), `"limit"`)
+	assert.Equal(t, "email", all.Get("rules.customer.0.rules.0.path").String())
+	assert.Equal(t, "email", all.Get("cache.types.jsonmask_test\\.Customer.0.action").String())
+	assert.Equal(t, []any{"limit(4)"}, all.Get("cache.actions").Value())
+	assert.Equal(t, int64(1), all.Get("stats.documents").Int())
+
+	stats := get("/debug/jsonmask/stats")
+	assert.Equal(t, int64(1), stats.Get("values.email").Int())
+	assert.False(t, stats.Get("funcs").Exists())
+}
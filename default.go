@@ -0,0 +1,50 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+var (
+	defaultOnce   sync.Once
+	defaultMasker *JsonMaskerImpl
+)
+
+// Default returns the package-level instance of JsonMaskerImpl created by New
+// on the first call. It's used by package functions Mask, MaskValue and MustMask.
+func Default() *JsonMaskerImpl {
+	defaultOnce.Do(func() {
+		defaultMasker = New()
+	})
+	return defaultMasker
+}
+
+// MaskValue marshals v to JSON and masks it based on rules extracted from its type.
+// Rules cached for the type are used as is, without copying them like ParseStruct.
+func (jm *JsonMaskerImpl) MaskValue(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return jm.Mask(data, StructMaskRules{Rules: jm.structRules(v), Fields: jm.structFields(v)})
+}
+
+// Mask applies masking to JSON based on the given rules using the default instance.
+func Mask(data []byte, smr StructMaskRules, opts ...MaskOption) ([]byte, error) {
+	return Default().Mask(data, smr, opts...)
+}
+
+// MaskValue marshals v to JSON and masks it based on rules extracted from its type
+// using the default instance.
+func MaskValue(v any) ([]byte, error) {
+	return Default().MaskValue(v)
+}
+
+// MustMask is like Mask but panics if masking fails.
+func MustMask(data []byte, smr StructMaskRules, opts ...MaskOption) []byte {
+	res, err := Mask(data, smr, opts...)
+	if err != nil {
+		panic("jsonmask: " + err.Error())
+	}
+	return res
+}
@@ -0,0 +1,29 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	assert.Same(t, jsonmask.Default(), jsonmask.Default())
+
+	rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "upper"}}}
+
+	result, err := jsonmask.Mask([]byte(`{"name":"john"}`), rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"JOHN"}`, string(result))
+
+	assert.Equal(t, `{"name":"JOHN"}`, string(jsonmask.MustMask([]byte(`{"name":"john"}`), rules)))
+}
+
+func TestMaskValue(t *testing.T) {
+	result, err := jsonmask.MaskValue(TestCustomer{ID: 1, Name: "john", Email: "john@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"name":"J","email":"j**n@e******.com"}`, string(result))
+
+	_, err = jsonmask.MaskValue(make(chan int))
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// DataDictionary lists masked fields of types and rule sets with their
+// annotations, e.g. for security reviews of what is masked and how.
+type DataDictionary struct {
+	Types []DictionaryType `json:"types"`
+}
+
+// DictionaryType lists masked fields of a type or a named rule set.
+type DictionaryType struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version,omitempty"`
+	Fields  []DictionaryField `json:"fields"`
+}
+
+// DictionaryField describes a masked field.
+type DictionaryField struct {
+	Path        string   `json:"path"`
+	Action      string   `json:"action"`
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Classes     []string `json:"classes,omitempty"`
+}
+
+// DataDictionary returns masked fields of the types, given by values or nil
+// pointers like for Precompile, followed by current versions of rule sets
+// registered by AddRules, sorted by name. Exclusion rules are not listed.
+// Values not being structs are reported in the joined error.
+func (jm *JsonMaskerImpl) DataDictionary(types ...any) (DataDictionary, error) {
+	dict := DataDictionary{Types: []DictionaryType{}}
+
+	var errs []error
+	for _, src := range types {
+		t, err := structType(src)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dict.Types = append(dict.Types, dictionaryType(t.String(), jm.ParseStruct(src)))
+	}
+
+	names := make(map[string]bool)
+	for p := jm; p != nil; p = p.parent {
+		for name, versions := range p.rules {
+			if len(versions) > 0 {
+				names[name] = true
+			}
+		}
+	}
+	for _, name := range sortedKeys(names) {
+		smr, _ := jm.Rules(name)
+		dict.Types = append(dict.Types, dictionaryType(name, smr))
+	}
+
+	return dict, errors.Join(errs...)
+}
+
+// dictionaryType returns masked fields of the rule set.
+func dictionaryType(name string, smr StructMaskRules) DictionaryType {
+	res := DictionaryType{Name: name, Version: smr.Version, Fields: []DictionaryField{}}
+	for _, rule := range smr.Rules {
+		if isExclusion(rule) {
+			continue
+		}
+		action := rule.Action
+		if rule.Keys {
+			action += " (keys)"
+		}
+		res.Fields = append(res.Fields, DictionaryField{
+			Path:        rule.Path,
+			Action:      action,
+			Description: rule.Description,
+			Owner:       rule.Owner,
+			Classes:     smr.Classes[rule.Path],
+		})
+	}
+	return res
+}
+
+// WriteJSON writes the dictionary to w as indented JSON.
+func (d DataDictionary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteMarkdown writes the dictionary to w as Markdown, a section with
+// a table of fields per type:
+//
+//	## Customer
+//
+//	| Path | Action | Description | Owner | Classes |
+//	|---|---|---|---|---|
+//	| email | email | login email | crm | PII, contact |
+func (d DataDictionary) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	for i, t := range d.Types {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("## " + t.Name + "\n\n")
+		if t.Version != "" {
+			b.WriteString("Version " + markdownCell(t.Version) + "\n\n")
+		}
+		b.WriteString("| Path | Action | Description | Owner | Classes |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, f := range t.Fields {
+			cells := []string{f.Path, f.Action, f.Description, f.Owner, strings.Join(f.Classes, ", ")}
+			for j, c := range cells {
+				cells[j] = markdownCell(c)
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes the text of a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,56 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestDataDictionary(t *testing.T) {
+	type Customer struct {
+		ID    int            `json:"id"`
+		Email string         `json:"email" mask:"email,desc=login email,owner=crm,class=PII|contact"`
+		Tags  map[string]int `json:"tags" mask:"upper,keys"`
+	}
+
+	jm := jsonmask.New()
+	require.NoError(t, jm.AddRules("payment", jsonmask.StructMaskRules{
+		Version: "2",
+		Rules: []jsonmask.Rule{
+			{Path: "card", Action: "truncate", Description: "card | PAN"},
+			{Path: "!card.last4"},
+		},
+		Classes: map[string][]string{"card": {"PCI"}},
+	}))
+
+	dict, err := jm.DataDictionary(Customer{}, 42)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidInput)
+	require.Len(t, dict.Types, 2)
+
+	var buf bytes.Buffer
+	require.NoError(t, dict.WriteJSON(&buf))
+	res := gjson.Parse(buf.String())
+	assert.Equal(t, "jsonmask_test.Customer", res.Get("types.0.name").String())
+	assert.JSONEq(t, `{"path":"email","action":"email","description":"login email","owner":"crm","classes":["PII","contact"]}`,
+		res.Get("types.0.fields.0").Raw)
+	assert.Equal(t, "upper (keys)", res.Get("types.0.fields.1.action").String())
+	assert.Equal(t, "payment", res.Get("types.1.name").String())
+	assert.Equal(t, "2", res.Get("types.1.version").String())
+	assert.Equal(t, int64(1), res.Get("types.1.fields.#").Int())
+
+	buf.Reset()
+	require.NoError(t, dict.WriteMarkdown(&buf))
+	assert.Equal(t, "## jsonmask_test.Customer\n\n"+
+		"| Path | Action | Description | Owner | Classes |\n"+
+		"|---|---|---|---|---|\n"+
+		"| email | email | login email | crm | PII, contact |\n"+
+		"| tags | upper (keys) |  |  |  |\n"+
+		"\n## payment\n\nVersion 2\n\n"+
+		"| Path | Action | Description | Owner | Classes |\n"+
+		"|---|---|---|---|---|\n"+
+		"| card | truncate | card \\| PAN |  | PCI |\n", buf.String())
+}
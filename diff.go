@@ -0,0 +1,41 @@
+package jsonmask
+
+import (
+	"bytes"
+
+	"github.com/tidwall/gjson"
+)
+
+// MaskDiff masks curr like Mask but only values that changed since prev,
+// i.e. values with no equal counterpart at the same path of prev. It's meant
+// for audit trails storing change events, where only newly introduced
+// sensitive values need to be redacted. Values are compared as raw JSON.
+func (jm *JsonMaskerImpl) MaskDiff(prev, curr []byte, smr StructMaskRules, opts ...MaskOption) ([]byte, error) {
+	if jm.disabled || bytes.Equal(prev, curr) {
+		return curr, nil
+	}
+
+	rules := jm.unknownFieldRules(curr, smr.Fields, applyMaskOptions(smr.Rules, opts))
+
+	// unchanged values are exempted from rules like values of exclusion rules
+	run := &maskRun{}
+	for _, rule := range rules {
+		if isExclusion(rule) {
+			continue
+		}
+		for _, p := range expandPath(curr, rule.Path) {
+			if p == "" {
+				continue
+			}
+			if old := gjson.GetBytes(prev, p); old.Exists() && old.Raw == gjson.GetBytes(curr, p).Raw {
+				run.exclusions = append(run.exclusions, p)
+			}
+		}
+	}
+
+	masked, err := jm.maskRun(curr, rules, run)
+	if err != nil {
+		return nil, err
+	}
+	return jm.postMask(masked)
+}
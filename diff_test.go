@@ -0,0 +1,36 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskDiff(t *testing.T) {
+	jm := jsonmask.New()
+	smr := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "email"},
+			{Path: "phone", Action: "null"},
+			{Path: "cards.#.number", Action: "first4"},
+			{Path: "address", Action: "-"},
+		},
+	}
+
+	prev := []byte(`{"email":"john@example.com","phone":"123","cards":[{"number":"4111111111111111"}],"address":{"city":"Prague"}}`)
+	curr := []byte(`{"email":"john@example.com","phone":"456","cards":[{"number":"4111111111111111"},{"number":"5500000000000004"}],"address":{"city":"Brno"}}`)
+
+	result, err := jm.MaskDiff(prev, curr, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"email":"john@example.com","phone":null,"cards":[{"number":"4111111111111111"},{"number":"5500"}]}`, string(result))
+
+	result, err = jm.MaskDiff(curr, curr, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, string(curr), string(result))
+
+	// everything is new
+	result, err = jm.MaskDiff(nil, []byte(`{"email":"john@example.com"}`), smr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"email":"j**n@e******.com"}`, string(result))
+}
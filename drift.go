@@ -0,0 +1,102 @@
+package jsonmask
+
+import (
+	"strconv"
+
+	"github.com/tidwall/gjson"
+)
+
+// DriftReport describes differences between a document and rules applied to it,
+// e.g. to notice upstream payloads gaining new, possibly sensitive, fields.
+type DriftReport struct {
+	// Uncovered holds paths of values not matched by any rule, neither
+	// directly nor by an ancestor. Array indexes are replaced with "#".
+	Uncovered []string
+
+	// Unmatched holds paths of rules not matching any value of the document.
+	Unmatched []string
+}
+
+// WithDriftHandler makes Mask report fields not covered by rules and rules not
+// matching anything to f. The report is made before masking, only if there is
+// any drift. Exclusion rules count as covering their values.
+// Detection walks the whole document, so it's meant for sampling or testing
+// rather than for every call on hot paths.
+func WithDriftHandler(f func(DriftReport)) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.driftHandler = f
+	}
+}
+
+// reportDrift reports drift between data and rules to the drift handler, if set.
+func (jm *JsonMaskerImpl) reportDrift(data []byte, rules []Rule) {
+	if jm.driftHandler == nil || !gjson.ValidBytes(data) {
+		return
+	}
+
+	var report DriftReport
+	var covered []string
+	for _, rule := range rules {
+		path := rule.Path
+		if isExclusion(rule) {
+			path = path[1:]
+		}
+
+		paths := expandPath(data, path)
+		if len(paths) == 0 {
+			report.Unmatched = append(report.Unmatched, rule.Path)
+		}
+		covered = append(covered, paths...)
+	}
+
+	seen := make(map[string]bool)
+	uncovered := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			report.Uncovered = append(report.Uncovered, path)
+		}
+	}
+	uncoveredPaths(gjson.ParseBytes(data), "", "", covered, uncovered)
+
+	if len(report.Uncovered) > 0 || len(report.Unmatched) > 0 {
+		jm.driftHandler(report)
+	}
+}
+
+// uncoveredPaths calls fn with generalized paths of leaf values of v not covered
+// by any of covered concrete paths. Empty objects and arrays are leaves too.
+func uncoveredPaths(v gjson.Result, path, general string, covered []string, fn func(string)) {
+	for _, p := range covered {
+		if p == "" {
+			return
+		}
+	}
+	if path != "" && hasPathPrefix(path, covered, false) {
+		return
+	}
+
+	if !v.IsObject() && !v.IsArray() {
+		if path != "" {
+			fn(general)
+		}
+		return
+	}
+
+	leaf := true
+	i := 0
+	v.ForEach(func(key, value gjson.Result) bool {
+		leaf = false
+		if v.IsArray() {
+			uncoveredPaths(value, joinPath(path, strconv.Itoa(i)), joinPath(general, "#"), covered, fn)
+			i++
+			return true
+		}
+		k := pathEscaper.Replace(key.Str)
+		uncoveredPaths(value, joinPath(path, k), joinPath(general, k), covered, fn)
+		return true
+	})
+
+	if leaf && path != "" {
+		fn(general)
+	}
+}
@@ -0,0 +1,46 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDriftHandler(t *testing.T) {
+	var reports []jsonmask.DriftReport
+	jm := jsonmask.New(jsonmask.WithDriftHandler(func(r jsonmask.DriftReport) {
+		reports = append(reports, r)
+	}))
+
+	smr := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "email"},
+			{Path: "address", Action: "null"},
+			{Path: "items.#.number", Action: "first4"},
+			{Path: "!items.#.id"},
+			{Path: "phone", Action: "null"},
+		},
+	}
+
+	data := []byte(`{
+		"id": 1,
+		"email": "john@example.com",
+		"address": {"city": "Prague", "zip": "11000"},
+		"items": [{"id": 1, "number": "4111111111111111", "cvv": "123"}, {"id": 2, "tags": []}],
+		"a.b": true
+	}`)
+	_, err := jm.Mask(data, smr)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []jsonmask.DriftReport{{
+		Uncovered: []string{"id", "items.#.cvv", "items.#.tags", `a\.b`},
+		Unmatched: []string{"phone"},
+	}}, reports)
+
+	// no report without drift
+	reports = nil
+	_, err = jm.Mask([]byte(`{"email":"john@example.com","address":null,"items":[{"id":1,"number":"1"}],"phone":"1"}`), smr)
+	assert.NoError(t, err)
+	assert.Empty(t, reports)
+}
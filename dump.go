@@ -0,0 +1,64 @@
+package jsonmask
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// String returns rules formatted as a table of paths, actions and slice levels.
+func (smr StructMaskRules) String() string {
+	var buf bytes.Buffer
+	_ = smr.DebugDump(&buf) // writing to bytes.Buffer never fails
+	return buf.String()
+}
+
+// DebugDump writes rules formatted as a table of paths, actions and slice levels
+// to w, so developers can verify what ParseStruct derived from a complex type.
+//
+//	PATH                  ACTION  SLICE LEVEL
+//	items.#.currency      upper   1
+//	items.#.#.minorUnits  zero    2
+func (smr StructMaskRules) DebugDump(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if smr.Version != "" {
+		if _, err := io.WriteString(tw, "VERSION "+smr.Version+"\n"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(tw, "PATH\tACTION\tSLICE LEVEL\n"); err != nil {
+		return err
+	}
+
+	for _, rule := range smr.Rules {
+		action := rule.Action
+		if rule.Keys {
+			action += " (keys)"
+		}
+		line := rule.Path + "\t" + action + "\t" + strconv.Itoa(sliceLevel(rule.Path)) + "\n"
+		if _, err := io.WriteString(tw, line); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// sliceLevel returns count of array element placeholders in the path:
+// 0 - no slice, 1 - slice, 2 - slice of slices, etc.
+func sliceLevel(path string) int {
+	level := 0
+	for {
+		_, selector, itemPath, found := cutSelectorPath(path)
+		if !found {
+			return level
+		}
+		if selector[0] != '*' {
+			level++
+		}
+		path = itemPath
+	}
+}
@@ -0,0 +1,25 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructMaskRules_String(t *testing.T) {
+	var s TestStruct
+
+	jm := jsonmask.New()
+	rules := jm.ParseStruct(s.Matrix)
+	rules.Version = "v1"
+
+	expected := "" +
+		"VERSION v1\n" +
+		"PATH                  ACTION  SLICE LEVEL\n" +
+		"items.#.#.currency    upper   2\n" +
+		"items.#.#.minorUnits  zero    2\n" +
+		"hiddenItems           -       0\n"
+
+	assert.Equal(t, expected, rules.String())
+}
@@ -0,0 +1,355 @@
+package jsonmask
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// singlePass applies rules to the document in one traversal, writing the
+// result into a single buffer instead of looking up and rewriting the whole
+// document for every rule. Values matched by several rules are masked in the
+// order of rules, as if rules were applied one by one.
+type singlePass struct {
+	jm    *JsonMaskerImpl
+	rules []passRule
+	err   error
+}
+
+// passRule is a rule compiled for the single pass.
+type passRule struct {
+	rule     Rule
+	maskFunc func(string) []byte // nil for deletion
+	applied  int                 // number of values the rule was applied to
+}
+
+// passMatcher is a rule path partially matched at a value.
+type passMatcher struct {
+	rule *passRule
+	segs []pathSegment // segments left to match
+}
+
+// passMember is an attribute of an object or an element of an array.
+type passMember struct {
+	start    int    // position of the key, or the value of array elements
+	valStart int    // position of the value
+	key      string // unescaped key of object attributes
+	value    string // raw value
+}
+
+// maskSinglePass masks the document in a single pass if the engine supports
+// all rules: paths without modifiers, "**", key patterns and exclusions,
+// rules without Keys and Sample, deletions not selecting array elements,
+// which shift indexes seen by subsequent rules, and query selectors not
+// following rules that may mask the elements they inspect. It's not ok otherwise,
+// the document has to be masked rule by rule.
+func (jm *JsonMaskerImpl) maskSinglePass(data []byte, rules []Rule) ([]byte, bool, error) {
+	if !gjson.ValidBytes(data) {
+		return nil, false, nil
+	}
+	e, matchers, ok, err := jm.compileSinglePass(rules)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+
+	res := e.descend(make([]byte, 0, len(data)), string(data), "", matchers)
+	if e.err != nil {
+		return nil, true, e.err
+	}
+	e.finish()
+	return res, true, nil
+}
+
+// compileSinglePass returns the single pass of rules and matchers of the
+// document root. It's not ok if the single pass doesn't support the rules.
+func (jm *JsonMaskerImpl) compileSinglePass(rules []Rule) (*singlePass, []passMatcher, bool, error) {
+	if jm.arrayLimit > 0 {
+		return nil, nil, false, nil
+	}
+
+	segs := make([][]pathSegment, len(rules))
+	for i, rule := range rules {
+		if segs[i] = jm.singlePassPath(rule); segs[i] == nil {
+			return nil, nil, false, nil
+		}
+	}
+	if queryAfterRule(segs) {
+		return nil, nil, false, nil
+	}
+
+	e := &singlePass{jm: jm, rules: make([]passRule, 0, len(rules))}
+	matchers := make([]passMatcher, 0, len(rules))
+	for i, rule := range rules {
+		maskFunc, ok, err := jm.ruleFunc(rule)
+		if err != nil {
+			return nil, nil, true, err
+		}
+		if !ok {
+			continue
+		}
+		e.rules = append(e.rules, passRule{rule: rule, maskFunc: maskFunc})
+		matchers = append(matchers, passMatcher{segs: segs[i]})
+	}
+	for i := range matchers {
+		matchers[i].rule = &e.rules[i]
+	}
+	return e, matchers, true, nil
+}
+
+// finish logs rules not applied to any value and counts masked values.
+func (e *singlePass) finish() {
+	for _, r := range e.rules {
+		if r.applied == 0 {
+			e.jm.log("jsonmask: path not found", "path", r.rule.Path, "action", r.rule.Action)
+		}
+		e.jm.stats.applied(r.rule.Action, r.applied)
+	}
+}
+
+// passPathKey is a key of cached segments of a rule path.
+type passPathKey struct {
+	path string
+}
+
+// singlePassPath returns segments of the rule path, or nil if the single
+// pass doesn't support the rule. Segments are cached by path.
+func (jm *JsonMaskerImpl) singlePassPath(rule Rule) []pathSegment {
+	if rule.Keys || rule.Sample != 0 {
+		return nil
+	}
+
+	var segs []pathSegment
+	if cached, ok := jm.cache.Load(passPathKey{rule.Path}); ok {
+		segs = cached.([]pathSegment)
+	} else {
+		segs = passPath(rule.Path)
+		jm.cache.Store(passPathKey{rule.Path}, segs)
+	}
+
+	if len(segs) > 0 && rule.Action == "-" {
+		last := segs[len(segs)-1]
+		if _, err := strconv.Atoi(last.key); err == nil || last.selector {
+			return nil
+		}
+	}
+	return segs
+}
+
+// queryAfterRule reports whether a path with a query selector, e.g. "#(b==1)",
+// follows a path that may reach into elements the query inspects. Queries see
+// the original elements in the single pass, rule by rule they see elements
+// masked by preceding rules.
+func queryAfterRule(segs [][]pathSegment) bool {
+	for i, path := range segs {
+		for k, seg := range path {
+			if !seg.selector || !strings.HasPrefix(seg.key, "#(") {
+				continue
+			}
+			for _, prev := range segs[:i] {
+				if len(prev) > k && segmentsOverlap(prev[:k], path[:k]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// segmentsOverlap reports whether paths of the segments may select the same
+// value. Selectors may select any key.
+func segmentsOverlap(a, b []pathSegment) bool {
+	for i := range a {
+		if !a[i].selector && !b[i].selector && a[i].key != b[i].key {
+			return false
+		}
+	}
+	return true
+}
+
+// passPath returns segments of the path, or nil if the single pass doesn't
+// support it.
+func passPath(path string) []pathSegment {
+	if path == "" || strings.HasPrefix(path, "!") {
+		return nil
+	}
+	if _, found := cutModifierPath(path); found {
+		return nil
+	}
+
+	segs := splitPath(path)
+	for _, seg := range segs {
+		switch {
+		case seg.selector && seg.key == "**":
+			return nil
+		case !seg.selector && (strings.ContainsAny(seg.key, "*?|") || strings.HasPrefix(seg.key, "#") || strings.HasPrefix(seg.key, "@")):
+			return nil // gjson patterns and special keys
+		}
+	}
+	return segs
+}
+
+// apply appends the value masked by rules of matchers, sorted by rule order,
+// to dst. It's not kept if it has to be deleted.
+func (e *singlePass) apply(dst []byte, raw, path string, matchers []passMatcher) ([]byte, bool) {
+	for i := 0; i < len(matchers); {
+		if r := matchers[i].rule; len(matchers[i].segs) == 0 {
+			r.applied++
+			if r.maskFunc == nil {
+				return dst, false
+			}
+			res := r.maskFunc(raw)
+			if ok, err := e.jm.checkOutput(r.rule, path, res); err != nil {
+				e.err = err
+				return dst, true
+			} else if !ok {
+				return dst, false
+			}
+			raw = string(res)
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(matchers) && len(matchers[j].segs) > 0 {
+			j++
+		}
+		if j == len(matchers) {
+			return e.descend(dst, raw, path, matchers[i:]), true
+		}
+		// rules masking the value follow, they see masked descendants
+		raw = string(e.descend(nil, raw, path, matchers[i:j]))
+		i = j
+	}
+	return append(dst, raw...), true
+}
+
+// descend appends the value with descendants masked by rules of matchers,
+// all having segments left, to dst.
+func (e *singlePass) descend(dst []byte, raw, path string, matchers []passMatcher) []byte {
+	start := strings.TrimLeft(raw, " \t\r\n")
+	if start == "" || (start[0] != '{' && start[0] != '[') || e.err != nil {
+		return append(dst, raw...)
+	}
+	isObject := start[0] == '{'
+
+	var members []passMember
+	gjson.Result{Type: gjson.JSON, Raw: raw}.ForEach(func(key, value gjson.Result) bool {
+		m := passMember{start: value.Index, valStart: value.Index, value: value.Raw}
+		if isObject {
+			m.start, m.key = key.Index, key.Str
+		}
+		members = append(members, m)
+		return true
+	})
+
+	// matchers of members are laid out in a single slice, in the order of rules
+	type selection struct {
+		member  int
+		matcher passMatcher
+	}
+	var selected []selection
+	for _, m := range matchers {
+		for _, i := range selectMembers(members, m.segs[0], isObject) {
+			selected = append(selected, selection{i, passMatcher{rule: m.rule, segs: m.segs[1:]}})
+		}
+	}
+	if len(selected) == 0 {
+		return append(dst, raw...)
+	}
+
+	offsets := make([]int, len(members)+1)
+	for _, s := range selected {
+		offsets[s.member+1]++
+	}
+	for i := 1; i < len(offsets); i++ {
+		offsets[i] += offsets[i-1]
+	}
+	children := make([][]passMatcher, len(members))
+	backing := make([]passMatcher, len(selected))
+	for i := range children {
+		children[i] = backing[offsets[i]:offsets[i]:offsets[i+1]]
+	}
+	for _, s := range selected {
+		children[s.member] = append(children[s.member], s.matcher)
+	}
+
+	pos := 0         // raw is copied to dst up to the position
+	lastKept := -1   // end of the last kept member
+	skipSep := false // members deleted so far were first, the following separator is dropped
+	for i, m := range members {
+		end := m.valStart + len(m.value)
+		from := pos
+		if skipSep {
+			from = m.start
+		}
+
+		if len(children[i]) == 0 {
+			pos, lastKept, skipSep = from, end, false
+			continue
+		}
+
+		childPath := ""
+		if e.jm.validOutput {
+			key := strconv.Itoa(i)
+			if isObject {
+				key = pathEscaper.Replace(m.key)
+			}
+			childPath = joinPath(path, key)
+		}
+
+		mark := len(dst)
+		dst = append(dst, raw[from:m.valStart]...)
+
+		var keep bool
+		if dst, keep = e.apply(dst, m.value, childPath, children[i]); keep {
+			pos, lastKept, skipSep = end, end, false
+			continue
+		}
+
+		dst = dst[:mark]
+		switch {
+		case lastKept >= 0:
+			// drop the separator preceding the member
+			if pos < lastKept {
+				dst = append(dst, raw[pos:lastKept]...)
+			}
+		case !skipSep:
+			dst = append(dst, raw[pos:m.start]...)
+			skipSep = true
+		}
+		pos = end
+	}
+	return append(dst, raw[pos:]...)
+}
+
+// selectMembers returns indexes of members selected by the path segment,
+// the way gjson and expandPath do: the first attribute with the key,
+// an array element by index or elements matching the selector.
+func selectMembers(members []passMember, seg pathSegment, isObject bool) []int {
+	if isObject {
+		if seg.selector && seg.key == "*" {
+			return selectElements(len(members), "#", nil)
+		}
+		if seg.selector && seg.key[0] != '-' {
+			return nil
+		}
+		for i, m := range members {
+			if m.key == seg.key {
+				return []int{i}
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case !seg.selector:
+		if i, err := strconv.Atoi(seg.key); err == nil && i >= 0 && i < len(members) {
+			return []int{i}
+		}
+		return nil
+	case seg.key == "*":
+		return selectElements(len(members), "#", nil)
+	}
+	return selectElements(len(members), seg.key, func(i int) string { return members[i].value })
+}
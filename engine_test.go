@@ -0,0 +1,126 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskSinglePass(t *testing.T) {
+	data := []byte(`{
+  "id": 7,
+  "password": "secret",
+  "customer": {"name": "John Smith", "email": "john@example.com", "email": "dup@example.com"},
+  "items": [
+    {"type": "card", "number": "4111111111111111", "tags": ["a", "b"]},
+    {"type": "cash", "number": "12345", "tags": []}
+  ],
+  "notes": {"-1": "x", "a.b": "y"},
+  "token": "abc"
+}`)
+
+	tests := []struct {
+		name  string
+		rules []jsonmask.Rule
+	}{
+		{"values", []jsonmask.Rule{
+			{Path: "customer.name", Action: "initials"},
+			{Path: "customer.email", Action: "email"},
+			{Path: "items.#.number", Action: "truncate"},
+			{Path: "notes.a\\.b", Action: "upper"},
+		}},
+		{"deletions", []jsonmask.Rule{
+			{Path: "id", Action: "-"},
+			{Path: "password", Action: "-"},
+			{Path: "token", Action: "-"},
+			{Path: "customer.email", Action: "-"},
+		}},
+		{"selectors", []jsonmask.Rule{
+			{Path: `items.#(type=="card")#.number`, Action: "null"},
+			{Path: "items.-1.type", Action: "upper"},
+			{Path: "items.[0:1].tags.*", Action: "upper"},
+			{Path: "notes.*", Action: "truncate"},
+		}},
+		{"overlapping", []jsonmask.Rule{
+			{Path: "customer.name", Action: "upper"},
+			{Path: "customer", Action: "count"},
+			{Path: "items.0", Action: "null"},
+			{Path: "items.0.type", Action: "upper"},
+			{Path: "customer.name", Action: "lower"},
+			{Path: "missing.path", Action: "null"},
+			{Path: "items.1.type", Action: "unknown"},
+		}},
+		{"query of masked elements", []jsonmask.Rule{
+			{Path: "token", Action: "upper"},
+			{Path: "items.#.type", Action: "upper"},
+			{Path: `items.#(type=="card").number`, Action: "null"},
+		}},
+		{"query all of masked elements", []jsonmask.Rule{
+			{Path: "token", Action: "upper"},
+			{Path: "items.#.tags", Action: "count"},
+			{Path: "items.#(tags==0)#.number", Action: "-"},
+		}},
+	}
+
+	jm := jsonmask.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := jm.Mask(data, jsonmask.StructMaskRules{Rules: tt.rules})
+			require.NoError(t, err)
+
+			// sampling every value makes rules be applied one by one
+			rules := append([]jsonmask.Rule(nil), tt.rules...)
+			rules[0].Sample = 1
+			expected, err := jm.Mask(data, jsonmask.StructMaskRules{Rules: rules})
+			require.NoError(t, err)
+
+			assert.Equal(t, string(expected), string(res))
+		})
+	}
+}
+
+func TestMaskSinglePassInvalidOutput(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithValidOutput(jsonmask.InvalidOutputError))
+	jm.AddFunc("broken", func(string) []byte { return []byte("{") })
+
+	_, err := jm.Mask([]byte(`{"a":[{"b":1}]}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "a.#.b", Action: "broken"},
+	}})
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidOutput)
+	assert.ErrorContains(t, err, "a.0.b by broken")
+}
+
+func BenchmarkMaskSinglePass(b *testing.B) {
+	data := []byte(`{"id":7,"password":"secret","customer":{"name":"John Smith","email":"john@example.com"},` +
+		`"items":[{"type":"card","number":"4111111111111111"},{"type":"cash","number":"12345"}],"token":"abc"}`)
+	rules := []jsonmask.Rule{
+		{Path: "password", Action: "-"},
+		{Path: "customer.name", Action: "initials"},
+		{Path: "customer.email", Action: "email"},
+		{Path: "items.#.number", Action: "truncate"},
+		{Path: "token", Action: "-"},
+	}
+	sampled := append([]jsonmask.Rule(nil), rules...)
+	sampled[0].Sample = 1
+
+	jm := jsonmask.New()
+	for _, bb := range []struct {
+		name  string
+		rules []jsonmask.Rule
+	}{
+		{"single pass", rules},
+		{"rule by rule", sampled},
+	} {
+		smr := jsonmask.StructMaskRules{Rules: bb.rules}
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := jm.Mask(data, smr); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
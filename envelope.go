@@ -0,0 +1,42 @@
+package jsonmask
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// MaskEnvelope masks values of attributes of the JSON object with rules by
+// attribute name, e.g. for map[string]json.RawMessage style envelopes like
+// {"card": {...}, "transfer": {...}}. Attributes without rules are kept as is.
+func (jm *JsonMaskerImpl) MaskEnvelope(data []byte, rules map[string]StructMaskRules, opts ...MaskOption) ([]byte, error) {
+	if jm.disabled {
+		return data, nil
+	}
+
+	doc := gjson.ParseBytes(data)
+	if !doc.IsObject() {
+		jm.log("jsonmask: envelope is not an object")
+		return data, nil
+	}
+
+	var keys []string
+	doc.ForEach(func(key, _ gjson.Result) bool {
+		if _, ok := rules[key.Str]; ok {
+			keys = append(keys, key.Str)
+		}
+		return true
+	})
+
+	for _, key := range keys {
+		path := pathEscaper.Replace(key)
+		smr := rules[key]
+		masked, err := jm.maskDocument([]byte(gjson.GetBytes(data, path).Raw), smr, applyMaskOptions(smr.Rules, opts))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = sjson.SetRawBytes(data, path, masked); err != nil {
+			return nil, err
+		}
+	}
+	return jm.postMask(data)
+}
@@ -0,0 +1,30 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskEnvelope(t *testing.T) {
+	jm := jsonmask.New()
+	rules := map[string]jsonmask.StructMaskRules{
+		"card":     {Rules: []jsonmask.Rule{{Path: "number", Action: "first4"}}},
+		"transfer": {Rules: []jsonmask.Rule{{Path: "iban", Action: "-"}}},
+		"a.b":      {Rules: []jsonmask.Rule{{Path: "@this", Action: "null"}}},
+	}
+
+	data := []byte(`{"card":{"number":"4111111111111111"},"transfer":{"iban":"CZ65","amount":5},"a.b":"x","note":{"number":"1"}}`)
+	result, err := jm.MaskEnvelope(data, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"card":{"number":"4111"},"transfer":{"amount":5},"a.b":null,"note":{"number":"1"}}`, string(result))
+
+	result, err = jm.MaskEnvelope(data, rules, jsonmask.WithDisable("number"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"card":{"number":"4111111111111111"},"transfer":{"amount":5},"a.b":null,"note":{"number":"1"}}`, string(result))
+
+	result, err = jm.MaskEnvelope([]byte(`[1]`), rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `[1]`, string(result))
+}
@@ -0,0 +1,116 @@
+package jsonmask
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErasureScope defines attributes erased on a right-to-erasure request
+// (GDPR Article 17) and how.
+type ErasureScope struct {
+	// Classes holds classes of attributes to erase, e.g. "contact".
+	// All classified attributes are erased if empty.
+	Classes []string
+
+	// Action erases values, "-" (deletion) by default, e.g. "null" or
+	// "tombstone(gdpr-art17)".
+	Action string
+}
+
+// classesKey is a key of cached attribute classes of the type.
+type classesKey struct {
+	t reflect.Type
+}
+
+// structClasses returns cached classes of JSON attributes of the type of src
+// by path, set by the mask tag option "class", classes are separated by '|'.
+func (jm *JsonMaskerImpl) structClasses(src any) map[string][]string {
+	if jm.parent != nil {
+		return jm.parent.structClasses(src)
+	}
+
+	t := reflect.TypeOf(src)
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if classes, ok := jm.cache.Load(classesKey{t}); ok {
+		return classes.(map[string][]string)
+	}
+
+	var classes map[string][]string
+	walkTypeFields(t, "", map[reflect.Type]bool{}, func(path string, sf reflect.StructField) {
+		_, opts := parseMaskTag(sf.Tag.Get(jm.tag))
+		if opts["class"] == "" {
+			return
+		}
+		if classes == nil {
+			classes = make(map[string][]string)
+		}
+		classes[path] = strings.Split(opts["class"], "|")
+	})
+	jm.cache.Store(classesKey{t}, classes)
+	return classes
+}
+
+// Erasure returns rules erasing attributes of the rule set classified within
+// the scope. Attributes nested in erased ones are skipped.
+func Erasure(smr StructMaskRules, scope ErasureScope) StructMaskRules {
+	action := scope.Action
+	if action == "" {
+		action = "-"
+	}
+
+	inScope := make(map[string]bool, len(scope.Classes))
+	for _, c := range scope.Classes {
+		inScope[c] = true
+	}
+
+	paths := make([]string, 0, len(smr.Classes))
+	for path, classes := range smr.Classes {
+		for _, c := range classes {
+			if len(inScope) == 0 || inScope[c] {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	res := StructMaskRules{Version: smr.Version}
+	var erased []string
+	for _, path := range paths {
+		if hasPathPrefix(path, erased, false) {
+			continue
+		}
+		erased = append(erased, path)
+		res.Rules = append(res.Rules, Rule{Path: path, Action: action})
+	}
+	return res
+}
+
+// ErasureRules returns rules erasing attributes classified within the scope
+// for current versions of all registered rule sets by name, including rule sets
+// of the parent, e.g. to process an erasure request across all document types.
+// Rule sets without such attributes are omitted.
+func (jm *JsonMaskerImpl) ErasureRules(scope ErasureScope) map[string]StructMaskRules {
+	res := make(map[string]StructMaskRules)
+	seen := make(map[string]bool)
+	for p := jm; p != nil; p = p.parent {
+		for name := range p.rules {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			smr, _ := jm.Rules(name)
+			if erasure := Erasure(smr, scope); len(erasure.Rules) > 0 {
+				res[name] = erasure
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,62 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+type ErasureContact struct {
+	Email string `json:"email" mask:"email,class=contact"`
+	Phone string `json:"phone" mask:"class=contact"`
+}
+
+type ErasureCustomer struct {
+	ID       string           `json:"id"`
+	Name     string           `json:"name" mask:"initials,class=identity"`
+	Contacts []ErasureContact `json:"contacts" mask:"class=contact"`
+	Notes    string           `json:"notes" mask:"class=identity|free-text"`
+}
+
+func TestJsonMaskerImpl_ErasureRules(t *testing.T) {
+	jm := jsonmask.New()
+
+	customer := jm.ParseStruct(ErasureCustomer{})
+	assert.Equal(t, map[string][]string{
+		"name":             {"identity"},
+		"contacts":         {"contact"},
+		"contacts.#.email": {"contact"},
+		"contacts.#.phone": {"contact"},
+		"notes":            {"identity", "free-text"},
+	}, customer.Classes)
+	// classification alone doesn't make rules
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "name", Action: "initials"},
+		{Path: "contacts.#.email", Action: "email"},
+	}, customer.Rules)
+
+	jm.AddRules("customer", customer)
+	jm.AddRules("contact", jm.ParseStruct(ErasureContact{}))
+	jm.AddRules("audit", jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "ip", Action: "null"}}})
+
+	assert.Equal(t, map[string]jsonmask.StructMaskRules{
+		"customer": {Rules: []jsonmask.Rule{{Path: "contacts", Action: "-"}}},
+		"contact": {Rules: []jsonmask.Rule{
+			{Path: "email", Action: "-"},
+			{Path: "phone", Action: "-"},
+		}},
+	}, jm.ErasureRules(jsonmask.ErasureScope{Classes: []string{"contact"}}))
+
+	all := jm.ErasureRules(jsonmask.ErasureScope{Action: "null"})
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "contacts", Action: "null"},
+		{Path: "name", Action: "null"},
+		{Path: "notes", Action: "null"},
+	}, all["customer"].Rules)
+
+	masked, err := jm.Mask([]byte(`{"id":"1","name":"John","contacts":[{"email":"a@b.c"}],"notes":"x"}`),
+		jm.ErasureRules(jsonmask.ErasureScope{Classes: []string{"free-text", "contact"}})["customer"])
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"1","name":"John"}`, string(masked))
+}
@@ -0,0 +1,135 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Algorithm identifies a cryptographic algorithm used by a masking function.
+type Algorithm string
+
+// Algorithms of crypto maskers.
+const (
+	AlgSHA1       Algorithm = "SHA-1"
+	AlgSHA256     Algorithm = "SHA-256"
+	AlgSHA384     Algorithm = "SHA-384"
+	AlgSHA512     Algorithm = "SHA-512"
+	AlgHMACSHA256 Algorithm = "HMAC-SHA-256"
+	AlgHMACSHA384 Algorithm = "HMAC-SHA-384"
+	AlgAESGCM     Algorithm = "AES-GCM"
+	AlgMD5        Algorithm = "MD5"
+)
+
+// fipsApproved holds algorithms allowed in FIPS mode.
+var fipsApproved = map[Algorithm]bool{
+	AlgSHA256:     true,
+	AlgSHA384:     true,
+	AlgHMACSHA256: true,
+	AlgHMACSHA384: true,
+	AlgAESGCM:     true,
+}
+
+// WithFIPS restricts crypto maskers to FIPS-approved algorithms (SHA-256/384,
+// HMAC with them and AES-GCM) for regulated deployments, whatever the order of
+// options. Built-in crypto maskers are tagged with their algorithms, the ones
+// of WithHashKey and WithSecretProvider use HMAC-SHA-256 and AES-GCM. Maskers
+// of algorithms not approved are removed, AddCryptoFunc and AddCryptoFactory
+// fail for them. AddFunc, AddReversibleFunc and AddFuncFactory can't replace
+// crypto maskers, such registrations are skipped and reported by SelfCheck.
+// It doesn't make the build use a FIPS-validated module, that's up to the Go
+// toolchain configuration.
+func WithFIPS() Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.fips = true
+		for name, alg := range jm.algs {
+			if !fipsApproved[alg] {
+				delete(jm.funcs, name)
+				delete(jm.factories, name)
+				delete(jm.unmaskFuncs, name)
+				delete(jm.algs, name)
+				jm.resetResolved()
+			}
+		}
+	}
+}
+
+// AddCryptoFunc adds a masking function based on the algorithm associated with
+// a name, together with its reverse function used by Unmask, if not nil.
+// In FIPS mode it fails with ErrNotFIPSApproved for algorithms not approved.
+func (jm *JsonMaskerImpl) AddCryptoFunc(name string, alg Algorithm, mask func(string) []byte, unmask func(string) ([]byte, error)) error {
+	if err := jm.tagAlgorithm(name, alg); err != nil {
+		return err
+	}
+
+	jm.addFunc(name, mask)
+	if unmask != nil {
+		jm.unmaskFuncs[name] = unmask
+	}
+	return nil
+}
+
+// AddCryptoFactory adds a factory of parametrized masking functions based on
+// the algorithm, see AddFuncFactory. In FIPS mode it fails with
+// ErrNotFIPSApproved for algorithms not approved.
+func (jm *JsonMaskerImpl) AddCryptoFactory(name string, alg Algorithm, f func(arg string) (func(string) []byte, error)) error {
+	if err := jm.tagAlgorithm(name, alg); err != nil {
+		return err
+	}
+
+	jm.addFuncFactory(name, f)
+	return nil
+}
+
+// tagAlgorithm records the algorithm of the crypto masker with the name.
+// In FIPS mode it fails for algorithms not approved.
+func (jm *JsonMaskerImpl) tagAlgorithm(name string, alg Algorithm) error {
+	if jm.fips && !fipsApproved[alg] {
+		return fmt.Errorf("%w: %s (%s)", ErrNotFIPSApproved, alg, name)
+	}
+	jm.algs[name] = alg
+	return nil
+}
+
+// algorithm returns the algorithm of the crypto masker with the name,
+// parents' included.
+func (jm *JsonMaskerImpl) algorithm(name string) (Algorithm, bool) {
+	for p := jm; p != nil; p = p.parent {
+		if alg, ok := p.algs[name]; ok {
+			return alg, true
+		}
+	}
+	return "", false
+}
+
+// checkReplace reports whether a masker without a declared algorithm may be
+// added with the name. In FIPS mode crypto maskers can't be replaced, the
+// registration is logged and reported by SelfCheck. Otherwise the tag of
+// the replaced crypto masker is dropped.
+func (jm *JsonMaskerImpl) checkReplace(name string) bool {
+	alg, ok := jm.algorithm(name)
+	if !ok {
+		return true
+	}
+	if jm.fips {
+		err := fmt.Errorf("%w: %s replaced by a function of undeclared algorithm", ErrNotFIPSApproved, name)
+		jm.errs = append(jm.errs, err)
+		jm.log("jsonmask: crypto masker can't be replaced in FIPS mode, skipped", "action", name, "algorithm", alg)
+		return false
+	}
+	delete(jm.algs, name)
+	return true
+}
+
+// registerErr records the error of a registration made by an option,
+// reported by SelfCheck.
+func (jm *JsonMaskerImpl) registerErr(err error) {
+	if err != nil {
+		jm.errs = append(jm.errs, err)
+		jm.log("jsonmask: registration failed", "error", err)
+	}
+}
+
+// Error definitions
+var (
+	ErrNotFIPSApproved = errors.New("algorithm not FIPS approved")
+)
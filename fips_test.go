@@ -0,0 +1,60 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFIPS(t *testing.T) {
+	kp := jsonmask.NewStaticKeyProvider("k1", bytes.Repeat([]byte{1}, 32))
+	jm := jsonmask.New(jsonmask.WithFIPS(), jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+
+	err := jm.AddCryptoFunc("encrypt", jsonmask.AlgAESGCM, jsonmask.EncryptFn(kp), jsonmask.DecryptFn(kp))
+	assert.NoError(t, err)
+	err = jm.AddCryptoFunc("hmac", jsonmask.AlgHMACSHA256, jsonmask.HMACFn(kp), nil)
+	assert.NoError(t, err)
+	err = jm.AddCryptoFunc("md5", jsonmask.AlgMD5, jsonmask.Null, nil)
+	assert.ErrorIs(t, err, jsonmask.ErrNotFIPSApproved)
+
+	_, err = jm.Mask([]byte(`{"a":"x"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a", Action: "md5"}}})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+
+	rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a", Action: "encrypt"}, {Path: "b", Action: "hmac"}}}
+	masked, err := jm.Mask([]byte(`{"a":"x","b":"y"}`), rules)
+	assert.NoError(t, err)
+	restored, _, err := jm.Unmask(masked, rules)
+	assert.NoError(t, err)
+	assert.Contains(t, string(restored), `"a":"x"`)
+
+	// crypto maskers can't be replaced by functions of undeclared algorithm
+	assert.NoError(t, jm.SelfCheck())
+	jm.AddFunc("encrypt", jsonmask.Null)
+	jm.AddReversibleFunc("hmac", jsonmask.Null, nil)
+	jm.AddFuncFactory("encrypt", func(string) (func(string) []byte, error) { return jsonmask.Null, nil })
+	masked, err = jm.Mask([]byte(`{"a":"x","b":"y"}`), rules)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(masked), "null")
+	assert.ErrorIs(t, jm.SelfCheck(), jsonmask.ErrNotFIPSApproved)
+
+	err = jm.AddCryptoFactory("md5", jsonmask.AlgMD5, func(string) (func(string) []byte, error) { return jsonmask.Null, nil })
+	assert.ErrorIs(t, err, jsonmask.ErrNotFIPSApproved)
+
+	// maskers of algorithms not approved are removed whatever the order of options
+	md5 := func(jm *jsonmask.JsonMaskerImpl) {
+		assert.NoError(t, jm.AddCryptoFunc("md5", jsonmask.AlgMD5, jsonmask.Null, nil))
+	}
+	jm = jsonmask.New(md5, jsonmask.WithHashKey([]byte("key")), jsonmask.WithFIPS(), jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+	_, err = jm.Mask([]byte(`{"a":"x"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a", Action: "md5"}}})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+	_, err = jm.Mask([]byte(`{"a":"x"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a", Action: "uuidHash"}}})
+	assert.NoError(t, err)
+
+	// without FIPS mode any algorithm is allowed
+	jm = jsonmask.New()
+	assert.NoError(t, jm.AddCryptoFunc("md5", jsonmask.AlgMD5, jsonmask.Null, nil))
+	jm.AddFunc("md5", jsonmask.Upper)
+	assert.NoError(t, jm.SelfCheck())
+}
@@ -3,9 +3,11 @@ module github.com/axkit/jsonmask
 go 1.20
 
 require (
+	github.com/fxamacker/cbor/v2 v2.7.0
 	github.com/stretchr/testify v1.10.0
 	github.com/tidwall/gjson v1.18.0
 	github.com/tidwall/sjson v1.2.5
+	go.mongodb.org/mongo-driver/v2 v2.0.0
 )
 
 require (
@@ -13,5 +15,6 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,141 @@
+package jsonmask
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// GraphQLRules holds rules of variables of GraphQL operations.
+type GraphQLRules struct {
+	// Operations holds rules of variables by operation name, e.g. "Login".
+	Operations map[string]StructMaskRules
+
+	// Default holds rules of operations not listed and anonymous ones.
+	Default StructMaskRules
+}
+
+// MaskGraphQL masks the variables object of the GraphQL request body, e.g.
+// before logging it, with rules of its operation, since mutation inputs like
+// passwords and card data are the most sensitive part of GraphQL traffic.
+// The operation is named by operationName or, if it's missing, by the only
+// operation of the query; Default applies if the query has several operations. Batched requests, arrays of requests, are masked
+// per request. Values written inline in queries are not masked.
+func (jm *JsonMaskerImpl) MaskGraphQL(body []byte, rules GraphQLRules) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, ErrInvalidJSON
+	}
+
+	doc := gjson.ParseBytes(body)
+	if !doc.IsArray() {
+		return jm.maskGraphQLRequest(body, doc, "", rules)
+	}
+
+	var err error
+	for i, req := range doc.Array() {
+		if body, err = jm.maskGraphQLRequest(body, req, strconv.Itoa(i)+".", rules); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// maskGraphQLRequest masks variables of the request found in the body by the prefix.
+func (jm *JsonMaskerImpl) maskGraphQLRequest(body []byte, req gjson.Result, prefix string, rules GraphQLRules) ([]byte, error) {
+	if !req.Get("variables").IsObject() {
+		return body, nil
+	}
+
+	name := req.Get("operationName").Str
+	if name == "" {
+		name = graphQLOperation(req.Get("query").Str)
+	}
+
+	smr, ok := rules.Operations[name]
+	if !ok {
+		smr = rules.Default
+	}
+	return jm.MaskAt(body, prefix+"variables", smr)
+}
+
+// graphQLOperation returns the name of the only operation of the GraphQL
+// document, skipping comments and strings. It returns "" if the operation is
+// anonymous or the document has several operations.
+func graphQLOperation(query string) string {
+	var (
+		names   []string
+		depth   int  // nesting of braces, parentheses and brackets
+		pending bool // a definition waits for its selection set
+		named   bool // the last token is an operation keyword
+	)
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+			continue
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(query[i:], `"""`):
+			i += 3
+			for i < len(query) && !strings.HasPrefix(query[i:], `"""`) {
+				if strings.HasPrefix(query[i:], `\"""`) {
+					i += 3
+				}
+				i++
+			}
+			i += 3
+			named = false
+			continue
+		case c == '"':
+			for i++; i < len(query) && query[i] != '"' && query[i] != '\n'; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case c == '{' || c == '(' || c == '[':
+			if depth == 0 && c == '{' {
+				if !pending {
+					names = append(names, "") // query shorthand
+				}
+				pending = false
+			}
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(query) && (query[j] == '_' || query[j] >= '0' && query[j] <= '9' ||
+				query[j] >= 'A' && query[j] <= 'Z' || query[j] >= 'a' && query[j] <= 'z') {
+				j++
+			}
+			token := query[i:j]
+			i = j
+			if depth != 0 {
+				continue
+			}
+			switch {
+			case named:
+				names[len(names)-1] = token
+				named = false
+			case token == "query" || token == "mutation" || token == "subscription":
+				names = append(names, "")
+				pending, named = true, true
+			case token == "fragment":
+				pending = true
+			}
+			continue
+		}
+		named = false
+		i++
+	}
+
+	if len(names) != 1 {
+		return ""
+	}
+	return names[0]
+}
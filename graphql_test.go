@@ -0,0 +1,64 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskGraphQL(t *testing.T) {
+	jm := jsonmask.New()
+	rules := jsonmask.GraphQLRules{
+		Operations: map[string]jsonmask.StructMaskRules{
+			"Login": {Rules: []jsonmask.Rule{{Path: "password", Action: "-"}}},
+			"Pay":   {Rules: []jsonmask.Rule{{Path: "input.card.number", Action: "truncate"}}},
+		},
+		Default: jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}}},
+	}
+
+	tests := []struct {
+		name, body, expected string
+	}{
+		{
+			"operation name",
+			`{"operationName":"Login","query":"mutation Login($login: String!, $password: String!) { login }","variables":{"login":"john","password":"secret"}}`,
+			`{"operationName":"Login","query":"mutation Login($login: String!, $password: String!) { login }","variables":{"login":"john"}}`,
+		},
+		{
+			"name from query",
+			`{"query":"# pay\nmutation Pay($input: PayInput!) { pay(input: $input) { id } }","variables":{"input":{"card":{"number":"4111111111111111"}}}}`,
+			`{"query":"# pay\nmutation Pay($input: PayInput!) { pay(input: $input) { id } }","variables":{"input":{"card":{"number":""}}}}`,
+		},
+		{
+			"comments and strings",
+			`{"query":"# query Public\nmutation Login($password: String! = \"query Public\") { login }","variables":{"password":"secret"}}`,
+			`{"query":"# query Public\nmutation Login($password: String! = \"query Public\") { login }","variables":{}}`,
+		},
+		{
+			"several operations",
+			`{"query":"query Public { me { id } }\nmutation Login { login }","variables":{"email":"john@example.com","password":"secret"}}`,
+			`{"query":"query Public { me { id } }\nmutation Login { login }","variables":{"email":"j**n@e******.com","password":"secret"}}`,
+		},
+		{
+			"default",
+			`{"query":"{ me { id } }","variables":{"email":"john@example.com"}}`,
+			`{"query":"{ me { id } }","variables":{"email":"j**n@e******.com"}}`,
+		},
+		{
+			"batch",
+			`[{"operationName":"Login","variables":{"password":"secret"}},{"query":"query Me { me { id } }"},{"operationName":"Other","variables":{"email":"john@example.com"}}]`,
+			`[{"operationName":"Login","variables":{}},{"query":"query Me { me { id } }"},{"operationName":"Other","variables":{"email":"j**n@e******.com"}}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := jm.MaskGraphQL([]byte(tt.body), rules)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(res))
+		})
+	}
+
+	_, err := jm.MaskGraphQL([]byte(`{"query":`), rules)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidJSON)
+}
@@ -0,0 +1,204 @@
+package jsonmask
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// HARRules holds rules of HAR (HTTP Archive) scrubbing by MaskHAR.
+type HARRules struct {
+	Request  StructMaskRules // rules of JSON request bodies and request headers
+	Response StructMaskRules // rules of JSON response bodies and response headers
+
+	// Headers, QueryParams and Cookies hold names of headers (case-insensitive),
+	// query parameters and cookies whose values are masked by the Action.
+	Headers     []string
+	QueryParams []string
+	Cookies     []string
+
+	// Action masks values of headers, query parameters and cookies,
+	// "truncate" by default. Results other than strings are converted to strings.
+	Action string
+}
+
+// MaskHAR masks HAR entries, e.g. browser captures shared with support: JSON
+// request bodies (postData.text), JSON response bodies (content.text, base64
+// encoded included) and values of configured headers, query parameters, also
+// in request URLs, and cookies. Bodies not being JSON are kept as is.
+func (jm *JsonMaskerImpl) MaskHAR(har []byte, rules HARRules) ([]byte, error) {
+	if jm.disabled {
+		return har, nil
+	}
+	if !gjson.ValidBytes(har) {
+		return nil, ErrInvalidJSON
+	}
+
+	maskValue, err := jm.stringMasker(rules.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	count := int(gjson.GetBytes(har, "log.entries.#").Int())
+	for i := 0; i < count; i++ {
+		entry := "log.entries." + strconv.Itoa(i)
+
+		har, err = jm.maskHARBody(har, entry+".request.postData", "", rules.Request)
+		if err != nil {
+			return nil, err
+		}
+		har, err = jm.maskHARBody(har, entry+".response.content", "encoding", rules.Response)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := []struct {
+			path    string
+			headers []HeaderRule
+		}{
+			{".request", rules.Request.Headers},
+			{".response", rules.Response.Headers},
+		}
+		for _, part := range parts {
+			if har, err = maskHARPairs(har, entry+part.path+".headers", jm.headerMasker(part.headers)); err != nil {
+				return nil, err
+			}
+			if har, err = maskHARPairs(har, entry+part.path+".headers", namesMasker(rules.Headers, true, maskValue)); err != nil {
+				return nil, err
+			}
+			if har, err = maskHARPairs(har, entry+part.path+".cookies", namesMasker(rules.Cookies, false, maskValue)); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(rules.QueryParams) > 0 {
+			if har, err = maskHARPairs(har, entry+".request.queryString", namesMasker(rules.QueryParams, false, maskValue)); err != nil {
+				return nil, err
+			}
+			rawURL := gjson.GetBytes(har, entry+".request.url").Str
+			if har, err = sjson.SetBytes(har, entry+".request.url", maskURLQuery(rawURL, rules.QueryParams, maskValue)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return har, nil
+}
+
+// stringMasker returns a function masking plain strings, e.g. header values,
+// by the action, "truncate" if empty. Results other than strings are converted
+// to strings.
+func (jm *JsonMaskerImpl) stringMasker(action string) (func(string) string, error) {
+	if action == "" {
+		action = "truncate"
+	}
+	maskFunc, ok := jm.lookupFunc(action)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAction, action)
+	}
+	return func(v string) string {
+		masked := gjson.ParseBytes(maskFunc(string(quote(v))))
+		if masked.Type == gjson.String {
+			return masked.Str
+		}
+		return masked.Raw
+	}, nil
+}
+
+// maskHARBody masks the JSON text of the HAR body (postData or content) found by the path.
+// If encodingAttr is set, the text may be base64 encoded as denoted by the attribute.
+func (jm *JsonMaskerImpl) maskHARBody(har []byte, path, encodingAttr string, smr StructMaskRules) ([]byte, error) {
+	if len(smr.Rules) == 0 {
+		return har, nil
+	}
+
+	text := gjson.GetBytes(har, path+".text")
+	if text.Type != gjson.String {
+		return har, nil
+	}
+
+	body := []byte(text.Str)
+	encoded := encodingAttr != "" && gjson.GetBytes(har, path+"."+encodingAttr).Str == "base64"
+	if encoded {
+		decoded, err := base64.StdEncoding.DecodeString(text.Str)
+		if err != nil {
+			return har, nil
+		}
+		body = decoded
+	}
+	if !gjson.ValidBytes(body) {
+		return har, nil
+	}
+
+	masked, err := jm.Mask(body, smr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := string(masked)
+	if encoded {
+		res = base64.StdEncoding.EncodeToString(masked)
+	}
+	return sjson.SetBytes(har, path+".text", res)
+}
+
+// maskHARPairs masks values of name/value pairs of the array found by the path.
+func maskHARPairs(har []byte, path string, mask pairMasker) ([]byte, error) {
+	for j, pair := range gjson.GetBytes(har, path).Array() {
+		masked, ok, err := mask(pair.Get("name").Str, pair.Get("value").Str)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if har, err = sjson.SetBytes(har, path+"."+strconv.Itoa(j)+".value", masked); err != nil {
+			return nil, err
+		}
+	}
+	return har, nil
+}
+
+// maskURLQuery masks values of query parameters of the URL having one of names,
+// keeping the rest of the URL as is.
+func maskURLQuery(rawURL string, names []string, maskValue func(string) string) string {
+	base, query, found := strings.Cut(rawURL, "?")
+	if !found {
+		return rawURL
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil || !hasName(names, name, false) {
+			continue
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		params[i] = key + "=" + url.QueryEscape(maskValue(value))
+	}
+
+	res := base + "?" + strings.Join(params, "&")
+	if hasFragment {
+		res += "#" + fragment
+	}
+	return res
+}
+
+// hasName reports whether names contain the name.
+func hasName(names []string, name string, ignoreCase bool) bool {
+	for _, n := range names {
+		if n == name || (ignoreCase && strings.EqualFold(n, name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package jsonmask_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestJsonMaskerImpl_MaskHAR(t *testing.T) {
+	body := base64.StdEncoding.EncodeToString([]byte(`{"token":"abc","id":1}`))
+	har := []byte(`{"log":{"version":"1.2","entries":[{
+		"request":{
+			"method":"POST",
+			"url":"https://api.example.com/login?user=john&api_key=s3cr%20t#top",
+			"headers":[{"name":"Authorization","value":"Bearer xyz"},{"name":"Accept","value":"*/*"}],
+			"queryString":[{"name":"user","value":"john"},{"name":"api_key","value":"s3cr t"}],
+			"cookies":[{"name":"session","value":"abc"}],
+			"postData":{"mimeType":"application/json","text":"{\"email\":\"john@example.com\",\"password\":\"x\"}"}
+		},
+		"response":{
+			"status":200,
+			"headers":[{"name":"set-cookie","value":"session=abc"}],
+			"cookies":[],
+			"content":{"mimeType":"application/json","encoding":"base64","text":"` + body + `"}
+		}
+	},{
+		"request":{"method":"GET","url":"https://example.com/","headers":[]},
+		"response":{"status":200,"content":{"mimeType":"text/html","text":"<html>"}}
+	}]}}`)
+
+	jm := jsonmask.New()
+	masked, err := jm.MaskHAR(har, jsonmask.HARRules{
+		Request:     jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}, {Path: "password", Action: "-"}}},
+		Response:    jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "token", Action: "null"}}},
+		Headers:     []string{"authorization", "Set-Cookie"},
+		QueryParams: []string{"api_key"},
+		Cookies:     []string{"session"},
+	})
+	assert.NoError(t, err)
+
+	req := gjson.GetBytes(masked, "log.entries.0.request")
+	assert.Equal(t, "https://api.example.com/login?user=john&api_key=#top", req.Get("url").Str)
+	assert.Equal(t, `[{"name":"Authorization","value":""},{"name":"Accept","value":"*/*"}]`, req.Get("headers").Raw)
+	assert.Equal(t, `[{"name":"user","value":"john"},{"name":"api_key","value":""}]`, req.Get("queryString").Raw)
+	assert.Equal(t, `[{"name":"session","value":""}]`, req.Get("cookies").Raw)
+	assert.Equal(t, `{"email":"j**n@e******.com"}`, req.Get("postData.text").Str)
+
+	resp := gjson.GetBytes(masked, "log.entries.0.response")
+	assert.Equal(t, `[{"name":"set-cookie","value":""}]`, resp.Get("headers").Raw)
+	decoded, err := base64.StdEncoding.DecodeString(resp.Get("content.text").Str)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"token":null,"id":1}`, string(decoded))
+
+	assert.Equal(t, "<html>", gjson.GetBytes(masked, "log.entries.1.response.content.text").Str)
+
+	_, err = jm.MaskHAR(har, jsonmask.HARRules{Headers: []string{"Accept"}, Action: "unknown"})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+	_, err = jm.MaskHAR([]byte(`{`), jsonmask.HARRules{})
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidJSON)
+}
@@ -0,0 +1,101 @@
+package jsonmask
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// HeaderRule holds masking metadata of HTTP headers.
+type HeaderRule struct {
+	// Name is a header name or a pattern, case-insensitive, where "*" matches
+	// any characters, e.g. "Authorization" or "X-Api-*".
+	Name string `json:"name"`
+
+	// Action is a name of a masking function applied to every value of the
+	// header or "-" to remove the header.
+	Action string `json:"action"`
+}
+
+// matches reports whether the rule applies to the header name.
+func (hr HeaderRule) matches(name string) bool {
+	if strings.EqualFold(hr.Name, name) {
+		return true
+	}
+	ok, _ := path.Match(strings.ToLower(hr.Name), strings.ToLower(name))
+	return ok
+}
+
+// headerRule returns the first header rule of the set applying to the header name.
+func headerRule(rules []HeaderRule, name string) (HeaderRule, bool) {
+	for _, hr := range rules {
+		if hr.matches(name) {
+			return hr, true
+		}
+	}
+	return HeaderRule{}, false
+}
+
+// MaskHeader returns a copy of h with values of headers masked by header rules
+// of the rule set, e.g. before logging a request next to its masked body.
+// Values are masked by the same registered functions as JSON values, results
+// other than strings are converted to strings. The original h is not modified.
+func (jm *JsonMaskerImpl) MaskHeader(h http.Header, smr StructMaskRules) (http.Header, error) {
+	res := h.Clone()
+	if jm.disabled || len(smr.Headers) == 0 {
+		return res, nil
+	}
+
+	for name, values := range res {
+		hr, ok := headerRule(smr.Headers, name)
+		if !ok {
+			continue
+		}
+		if hr.Action == "-" {
+			delete(res, name)
+			continue
+		}
+
+		maskValue, err := jm.stringMasker(hr.Action)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			values[i] = maskValue(v)
+		}
+	}
+	return res, nil
+}
+
+// pairMasker masks the value of a name/value pair, e.g. a header, and reports
+// whether the pair is masked.
+type pairMasker func(name, value string) (masked string, ok bool, err error)
+
+// namesMasker returns a pairMasker masking values of pairs having one of names.
+func namesMasker(names []string, ignoreCase bool, maskValue func(string) string) pairMasker {
+	return func(name, value string) (string, bool, error) {
+		if !hasName(names, name, ignoreCase) {
+			return value, false, nil
+		}
+		return maskValue(value), true, nil
+	}
+}
+
+// headerMasker returns a pairMasker masking values of headers by header rules.
+// Values of headers removed by "-" are masked to empty strings.
+func (jm *JsonMaskerImpl) headerMasker(rules []HeaderRule) pairMasker {
+	return func(name, value string) (string, bool, error) {
+		hr, ok := headerRule(rules, name)
+		if !ok {
+			return value, false, nil
+		}
+		if hr.Action == "-" {
+			return "", true, nil
+		}
+		maskValue, err := jm.stringMasker(hr.Action)
+		if err != nil {
+			return "", false, err
+		}
+		return maskValue(value), true, nil
+	}
+}
@@ -0,0 +1,57 @@
+package jsonmask_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskHeader(t *testing.T) {
+	var smr jsonmask.StructMaskRules
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"rules": [{"path": "password", "action": "-"}],
+		"headers": [
+			{"name": "authorization", "action": "truncate"},
+			{"name": "Cookie", "action": "-"},
+			{"name": "X-Api-*", "action": "first4"}
+		]
+	}`), &smr))
+
+	h := http.Header{
+		"Authorization": {"Bearer abc"},
+		"Cookie":        {"session=s3cr3t"},
+		"X-Api-Key":     {"key-123456"},
+		"Accept":        {"*/*"},
+	}
+
+	jm := jsonmask.New()
+	masked, err := jm.MaskHeader(h, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, http.Header{
+		"Authorization": {""},
+		"X-Api-Key":     {"key-"},
+		"Accept":        {"*/*"},
+	}, masked)
+	assert.Equal(t, "Bearer abc", h.Get("Authorization"), "original is kept")
+
+	t.Run("lambda", func(t *testing.T) {
+		event := `{"headers":{"authorization":"Bearer abc","x-api-key":"key-123456"},"multiValueHeaders":{"Cookie":["a=1","b=2"]},"body":"{\"password\":\"x\"}"}`
+		res, err := jm.MaskLambdaEvent([]byte(event), jsonmask.LambdaRules{Body: smr})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"headers":{"authorization":"","x-api-key":"key-"},"multiValueHeaders":{"Cookie":["",""]},"body":"{}"}`, string(res))
+	})
+
+	t.Run("har", func(t *testing.T) {
+		har := `{"log":{"entries":[{"request":{"headers":[{"name":"Authorization","value":"Bearer abc"}]},"response":{"headers":[{"name":"Authorization","value":"Bearer abc"}]}}]}}`
+		res, err := jm.MaskHAR([]byte(har), jsonmask.HARRules{Request: smr})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"log":{"entries":[{"request":{"headers":[{"name":"Authorization","value":""}]},"response":{"headers":[{"name":"Authorization","value":"Bearer abc"}]}}]}}`, string(res))
+	})
+
+	_, err = jm.MaskHeader(h, jsonmask.StructMaskRules{Headers: []jsonmask.HeaderRule{{Name: "Accept", Action: "unknown"}}})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+}
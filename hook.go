@@ -0,0 +1,67 @@
+package jsonmask
+
+import (
+	"encoding/hex"
+	"hash"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// PostMaskHook adjusts the masked document, e.g. recomputes fields depending on
+// masked or deleted values, keeping the document consistent for downstream validators.
+type PostMaskHook func(data []byte) ([]byte, error)
+
+// WithPostMaskHook adds hooks called in order with the document masked by Mask,
+// MaskAt, MaskDiff, MaskSplit and MaskEnvelope. Documents nested in values,
+// e.g. by the "json" action, are not passed to hooks.
+func WithPostMaskHook(hooks ...PostMaskHook) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.postMaskHooks = append(jm.postMaskHooks, hooks...)
+	}
+}
+
+// postMask returns the masked document adjusted by post-mask hooks
+// and canonicalized, if configured. Invalid documents fail in valid output mode.
+func (jm *JsonMaskerImpl) postMask(data []byte) ([]byte, error) {
+	var err error
+	for _, hook := range jm.postMaskHooks {
+		if data, err = hook(data); err != nil {
+			return nil, err
+		}
+	}
+	if jm.validOutput && !gjson.ValidBytes(data) {
+		return nil, ErrInvalidOutput
+	}
+	if jm.canonical {
+		data = Canonicalize(data)
+	}
+	return data, nil
+}
+
+// CountHook returns a hook setting the attribute found by countPath to the
+// number of elements of the array found by arrayPath, e.g. "itemCount" after
+// some "items" were deleted. Documents without the array are left as is.
+func CountHook(arrayPath, countPath string) PostMaskHook {
+	return func(data []byte) ([]byte, error) {
+		arr := gjson.GetBytes(data, arrayPath)
+		if !arr.IsArray() {
+			return data, nil
+		}
+		return sjson.SetBytes(data, countPath, len(arr.Array()))
+	}
+}
+
+// HashHook returns a hook setting the attribute found by hashPath to the hex
+// encoded hash of the document without the attribute, e.g. "contentHash".
+func HashHook(hashPath string, newHash func() hash.Hash) PostMaskHook {
+	return func(data []byte) ([]byte, error) {
+		content, err := sjson.DeleteBytes(data, hashPath)
+		if err != nil {
+			return nil, err
+		}
+		h := newHash()
+		h.Write(content)
+		return sjson.SetBytes(data, hashPath, hex.EncodeToString(h.Sum(nil)))
+	}
+}
@@ -0,0 +1,31 @@
+package jsonmask_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPostMaskHook(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithPostMaskHook(
+		jsonmask.CountHook("items", "itemCount"),
+		jsonmask.HashHook("contentHash", sha256.New),
+	))
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: `items.#(secret==true)#`, Action: "-"}}}
+
+	data := []byte(`{"items":[{"id":1},{"id":2,"secret":true}],"itemCount":2,"contentHash":"x"}`)
+	result, err := jm.Mask(data, smr)
+	assert.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(`{"items":[{"id":1}],"itemCount":1}`))
+	assert.Equal(t, `{"items":[{"id":1}],"itemCount":1,"contentHash":"`+hex.EncodeToString(sum[:])+`"}`, string(result))
+
+	// hooks are applied to the whole document
+	result, err = jm.MaskAt([]byte(`{"payload":{"items":[]}}`), "payload", smr)
+	assert.NoError(t, err)
+	sum = sha256.Sum256([]byte(`{"payload":{"items":[]}}`))
+	assert.Equal(t, `{"payload":{"items":[]},"contentHash":"`+hex.EncodeToString(sum[:])+`"}`, string(result))
+}
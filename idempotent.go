@@ -0,0 +1,122 @@
+package jsonmask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// WithIdempotentMarker makes Mask record fingerprints of applied rule sets in the
+// attribute of the root object, e.g. "_masked", and return documents already
+// masked by the same rules as is, so pipelines passing payloads through several
+// masking hops don't mask values twice. Rules never apply to the attribute.
+// Documents other than objects are masked every time.
+//
+// Every fingerprint is signed with the key together with the content of the
+// document, so markers forged in untrusted input or copied from other documents
+// don't exempt the document from masking. All hops should share the key.
+// It panics if the key is empty.
+func WithIdempotentMarker(attr string, key []byte) Option {
+	if len(key) == 0 {
+		panic("jsonmask: idempotent marker requires a key")
+	}
+	key = append([]byte(nil), key...)
+	return func(jm *JsonMaskerImpl) {
+		jm.marker = attr
+		jm.markerKey = key
+	}
+}
+
+// rulesFingerprint returns a short fingerprint of rules identifying the rule set.
+func rulesFingerprint(rules []Rule) string {
+	h := sha256.New()
+	for _, r := range rules {
+		h.Write([]byte(r.Path + "\x00" + r.Action + "\x00" + strconv.FormatBool(r.Keys) + "\x00" +
+			strconv.FormatFloat(r.Sample, 'g', -1, 64) + "\x00" + r.When + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// markerEntry returns the marker entry of the fingerprint signed together with
+// the content of the document without the marker, as "fingerprint:signature".
+func (jm *JsonMaskerImpl) markerEntry(fingerprint string, content []byte) string {
+	mac := hmac.New(sha256.New, jm.markerKey)
+	mac.Write([]byte(fingerprint + "\n"))
+	mac.Write(content)
+	return fingerprint + ":" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// markerContent returns the canonical document without the marker, signed by
+// marker entries, so formatting changes don't invalidate the marker.
+func (jm *JsonMaskerImpl) markerContent(data []byte) ([]byte, error) {
+	res, err := sjson.DeleteBytes(data, pathEscaper.Replace(jm.marker))
+	if err != nil {
+		return nil, err
+	}
+	return Canonicalize(res), nil
+}
+
+// markedBy returns fingerprints of rule sets the document is masked by,
+// having valid signatures.
+func (jm *JsonMaskerImpl) markedBy(data []byte) map[string]bool {
+	doc := gjson.ParseBytes(data)
+	if !doc.IsObject() {
+		return nil
+	}
+
+	entries := doc.Get(pathEscaper.Replace(jm.marker))
+	if !entries.IsArray() {
+		return nil
+	}
+	content, err := jm.markerContent(data)
+	if err != nil {
+		return nil
+	}
+
+	var res map[string]bool
+	entries.ForEach(func(_, value gjson.Result) bool {
+		fingerprint, _, _ := strings.Cut(value.Str, ":")
+		if hmac.Equal([]byte(value.Str), []byte(jm.markerEntry(fingerprint, content))) {
+			if res == nil {
+				res = make(map[string]bool)
+			}
+			res[fingerprint] = true
+		}
+		return true
+	})
+	return res
+}
+
+// mark sets the marker of the masked document to entries of fingerprints of
+// rule sets applied before, found by markedBy, and the fingerprint, signed
+// with the content of the document.
+func (jm *JsonMaskerImpl) mark(data []byte, applied map[string]bool, fingerprint string) ([]byte, error) {
+	if !gjson.ParseBytes(data).IsObject() {
+		return data, nil
+	}
+
+	content, err := jm.markerContent(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	gjson.GetBytes(data, pathEscaper.Replace(jm.marker)).ForEach(func(_, value gjson.Result) bool {
+		if fp, _, _ := strings.Cut(value.Str, ":"); applied[fp] && fp != fingerprint {
+			entries = append(entries, jm.markerEntry(fp, content))
+		}
+		return true
+	})
+	entries = append(entries, jm.markerEntry(fingerprint, content))
+
+	res, err := sjson.SetBytes(data, pathEscaper.Replace(jm.marker), entries)
+	if err == nil && jm.canonical {
+		res = Canonicalize(res)
+	}
+	return res, err
+}
@@ -0,0 +1,65 @@
+package jsonmask
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// InventoryItem is a sensitive attribute of a type known to the masker.
+type InventoryItem struct {
+	Type    string   `json:"type"`
+	Path    string   `json:"path"`
+	Action  string   `json:"action"`
+	Classes []string `json:"classes,omitempty"`
+	Owner   string   `json:"owner,omitempty"`
+}
+
+// Inventory returns masked attributes of all types the masker has extracted
+// rules of, by ParseStruct, Precompile or masking of Go values, so inventories
+// of personal data can be produced from code, e.g. at startup after Precompile.
+// Items are sorted by classes, type and path; unclassified items come last. Tenant maskers of a Manager report types of the base masker.
+func (jm *JsonMaskerImpl) Inventory() []InventoryItem {
+	root := jm
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	res := []InventoryItem{}
+	root.cache.Range(func(key, value any) bool {
+		t, ok := key.(reflect.Type)
+		if !ok {
+			return true
+		}
+		classes := root.structClasses(reflect.Zero(t).Interface())
+		for _, rule := range value.([]Rule) {
+			if isExclusion(rule) {
+				continue
+			}
+			action := rule.Action
+			if rule.Keys {
+				action += " (keys)"
+			}
+			res = append(res, InventoryItem{
+				Type:    t.String(),
+				Path:    rule.Path,
+				Action:  action,
+				Classes: classes[rule.Path],
+				Owner:   rule.Owner,
+			})
+		}
+		return true
+	})
+
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if ac, bc := strings.Join(a.Classes, "|"), strings.Join(b.Classes, "|"); ac != bc {
+			return bc == "" || (ac != "" && ac < bc)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Path < b.Path
+	})
+	return res
+}
@@ -0,0 +1,31 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventory(t *testing.T) {
+	type Customer struct {
+		Email string `json:"email" mask:"email,class=PII|contact"`
+		Notes string `json:"notes" mask:"truncate"`
+	}
+	type Card struct {
+		PAN string `json:"pan" mask:"truncate,class=PCI,owner=payments"`
+	}
+
+	m := jsonmask.NewManager()
+	assert.Empty(t, m.Base().Inventory())
+
+	require.NoError(t, m.Base().Precompile(Customer{}))
+	_ = m.Tenant("acme").ParseStruct(&Card{})
+
+	assert.Equal(t, []jsonmask.InventoryItem{
+		{Type: "jsonmask_test.Card", Path: "pan", Action: "truncate", Classes: []string{"PCI"}, Owner: "payments"},
+		{Type: "jsonmask_test.Customer", Path: "email", Action: "email", Classes: []string{"PII", "contact"}},
+		{Type: "jsonmask_test.Customer", Path: "notes", Action: "truncate"},
+	}, m.Tenant("acme").Inventory())
+}
@@ -2,10 +2,18 @@
 package jsonmask
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -13,18 +21,71 @@ import (
 
 // StructMaskRules holds metadata for a structure.
 type StructMaskRules struct {
-	Rules []Rule
+	// Version identifies the rule set among other versions registered with the same name.
+	Version string `json:"version,omitempty"`
+
+	Rules []Rule `json:"rules"`
+
+	// Fields holds paths of all attributes of the structure the rules were
+	// parsed from, array elements are denoted by "#". See WithUnknownFields.
+	Fields []string `json:"fields,omitempty"`
+
+	// Classes holds classes of attributes by path, e.g. "PII" or "contact",
+	// set by the mask tag option "class", e.g. mask:"email,class=contact|PII".
+	// See ErasureRules, DataDictionary and Inventory.
+	Classes map[string][]string `json:"classes,omitempty"`
+
+	// Headers holds rules of HTTP headers accompanying the documents, so one
+	// rule set covers both. See MaskHeader.
+	Headers []HeaderRule `json:"headers,omitempty"`
 }
 
 // Rule holds metadata for a single field of a structure.
 type Rule struct {
 	// Path is a JSON path to the field.
-	Path string
+	// A path starting with "!" exempts values from other rules of the set,
+	// e.g. "!items.#.publicId" keeps public IDs when "items" is masked.
+	Path string `json:"path"`
 
 	// Action is a value of the mask tag.
 	// It can be a name of a custom masking function or "-" to delete the field.
-	Action     string
-	sliceLevel int // 0 - no slice, 1 - slice, 2 - slice of slices, etc.
+	// Actions separated by '|' are chained, e.g. "lower|initialChar", the output
+	// of every action feeds the next one, see Actions.
+	Action string `json:"action"`
+
+	// Keys makes the action apply to attribute names of the object found by
+	// the path instead of the object itself, e.g. to mask emails used as map keys.
+	// Attribute values are preserved, "-" removes all attributes.
+	Keys bool `json:"keys,omitempty"`
+
+	// Sample is the share of matching values the rule applies to, in (0, 1],
+	// e.g. 0.3 masks about 30% of values. Values are selected deterministically
+	// by the hash of their content, so equal values share the fate. 0 applies
+	// the rule to all values.
+	Sample float64 `json:"sample,omitempty"`
+
+	// When is a condition on the document the rule applies under, a gjson query
+	// like `consents.marketing==false`, so flags in the document, e.g. consents
+	// of the subject, control masking. A leading "!" negates the condition,
+	// e.g. `!consents.marketing==true` applies the rule unless consent is given,
+	// also when the flag is missing. Conditions see the document before masking.
+	// Conditions of masking rules should be negated: a positive condition, e.g.
+	// `consents.marketing==false`, leaves the value unmasked when the flag is
+	// missing or forged by the sender, see LintFailOpen.
+	// MaskMap and MaskStruct don't evaluate conditions, the rule always applies.
+	When string `json:"when,omitempty"`
+
+	// Description and Owner annotate the rule for reviewers, e.g. "customer
+	// email" and "crm-team". They don't affect masking and are listed by
+	// DataDictionary. Attributes are classified by StructMaskRules.Classes.
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+// Actions returns the chain of actions of the rule in order of application,
+// e.g. ["lower", "initialChar"] for "lower|initialChar".
+func (r Rule) Actions() []string {
+	return splitActions(r.Action)
 }
 
 // DefaultStructFieldTag is a default tag name for struct fields.
@@ -33,20 +94,74 @@ const DefaultStructFieldTag = "mask"
 // JsonMaskerImpl provides functionality to mask JSON data based on field metadata
 // and custom masking functions.
 type JsonMaskerImpl struct {
-	tag   string // tag name for struct fields
-	funcs map[string]func(string) []byte
+	settings // inherited by tenant maskers
+
+	funcs     map[string]func(string) []byte
+	factories map[string]func(string) (func(string) []byte, error)
+	resolved  sync.Map                     // action -> func(string) []byte, resolved by factories
+	rules     map[string][]StructMaskRules // name -> versions in order of registration
+	cache     sync.Map                     // reflect.Type -> []Rule
+
+	unmaskFuncs map[string]func(string) ([]byte, error)
+	middleware  []Middleware // wrapping masking functions of applied rules
+
+	algs map[string]Algorithm // algorithms of crypto maskers by action name
+	errs []error              // registration errors reported by SelfCheck
+
+	stats     *maskStats    // counters of masking calls
+	overrides ruleOverrides // temporary overrides of rule actions
+
+	parent *JsonMaskerImpl // functions, factories, rule sets and type cache fallback
+}
+
+// settings holds options of JsonMaskerImpl, copied to tenant maskers as is.
+type settings struct {
+	tag string // tag name for struct fields
+
+	unmaskAuthorizer UnmaskAuthorizer // guarding Unmask, if set
+	modifiers        map[string]bool  // gjson modifiers allowed in rule paths
+
+	logger   Logger
+	logLevel LogLevel
+	disabled bool // masking is turned off, data is returned as is
+	strict   bool // unknown actions are reported as errors
+	fips     bool // crypto maskers are restricted to FIPS-approved algorithms
+
+	arrayLimit     int // max number of array elements processed by a rule, 0 - no limit
+	overflowPolicy OverflowPolicy
+
+	validOutput   bool // values returned by masking functions are validated
+	invalidPolicy InvalidOutputPolicy
+
+	driftHandler  func(DriftReport) // receives fields not covered by rules, if set
+	unknownFields string            // action applied to attributes unknown to the struct, if set
+	postMaskHooks []PostMaskHook    // adjusting masked documents
+	canonical     bool              // masked documents are canonicalized
+	marker        string            // attribute recording fingerprints of applied rule sets, if set
+	markerKey     []byte            // key signing marker entries
+	ruleLimits    RuleLimits        // caps of rule sets registered by AddRules
 }
 
 // New creates a new instance of JsonMaskerImpl.
-func New() *JsonMaskerImpl {
-	return NewWithMaskTag(DefaultStructFieldTag)
+func New(opts ...Option) *JsonMaskerImpl {
+	return NewWithMaskTag(DefaultStructFieldTag, opts...)
 }
 
 // NewWithMaskTag creates a new instance of JsonMaskerImpl with a custom tag name.
-func NewWithMaskTag(tag string) *JsonMaskerImpl {
+func NewWithMaskTag(tag string, opts ...Option) *JsonMaskerImpl {
 	jm := JsonMaskerImpl{
-		tag:   DefaultStructFieldTag,
-		funcs: make(map[string]func(string) []byte),
+		settings: settings{
+			tag:       DefaultStructFieldTag,
+			modifiers: make(map[string]bool),
+		},
+		funcs:     make(map[string]func(string) []byte),
+		factories: make(map[string]func(string) (func(string) []byte, error)),
+		rules:     make(map[string][]StructMaskRules),
+
+		unmaskFuncs: make(map[string]func(string) ([]byte, error)),
+		algs:        make(map[string]Algorithm),
+
+		stats: new(maskStats),
 	}
 
 	jm.AddFunc("upper", Upper)
@@ -57,26 +172,113 @@ func NewWithMaskTag(tag string) *JsonMaskerImpl {
 	jm.AddFunc("email", Email)
 	jm.AddFunc("first4", PrefixFn(4, false))
 	jm.AddFunc("zero", Zero)
+	jm.AddFunc("passport", Passport)
+	jm.AddFunc("initials", Initials)
+	jm.AddFunc("translit", Translit)
+	jm.AddFunc("address", Address)
+	jm.AddFunc("addressObject", AddressObject)
+	jm.AddFunc("ageBucket", AgeBucketFn(10))
+	jm.AddFunc("uuid", UUID)
+	jm.AddFunc("stripHTML", StripHTML)
+	jm.AddFunc("count", Count)
+	jm.AddFunc("summary", Summary)
+	jm.AddFunc("true", True)
+	jm.AddFunc("false", False)
+	jm.AddFunc("sign", Sign)
+	jm.AddFunc("magnitude", Magnitude)
+	jm.AddFunc("length", Length)
+	jm.AddFunc("lengthLabel", LengthLabel)
+	jm.AddFunc("syntheticCard", SyntheticCard)
+	jm.AddFunc("tombstone", TombstoneFn(""))
+
+	jm.AddFuncFactory("base64", jm.base64Factory)
+	jm.AddFuncFactory("json", jm.jsonFactory)
+	jm.AddFuncFactory("stripHTML", stripHTMLFactory)
+	jm.AddFuncFactory("limit", limitFactory)
+	jm.AddFuncFactory("round", roundFactory)
+	jm.AddFuncFactory("ageBucket", ageBucketFactory)
+	jm.AddFuncFactory("laplace", laplaceFactory)
+	jm.AddFuncFactory("tombstone", tombstoneFactory)
+	jm.AddFuncFactory("quoted", jm.quotedFactory)
+
+	for _, name := range DefaultModifiers {
+		jm.modifiers[name] = true
+	}
+
+	for _, opt := range opts {
+		opt(&jm)
+	}
 
 	return &jm
 }
 
 // AddFunc adds a masking function associated with a name.
 func (jm *JsonMaskerImpl) AddFunc(name string, f func(string) []byte) {
+	if jm.checkReplace(name) {
+		jm.addFunc(name, f)
+	}
+}
+
+// addFunc adds the masking function without checking its algorithm.
+func (jm *JsonMaskerImpl) addFunc(name string, f func(string) []byte) {
 	jm.funcs[name] = f
+	jm.resetResolved() // chains and factory arguments may include the function
+}
+
+// AddStringFunc adds a masking function of string values associated with a name.
+// The function receives and returns unquoted, unescaped strings, JSON encoding
+// is handled by StringFn. Values other than strings are kept as is.
+func (jm *JsonMaskerImpl) AddStringFunc(name string, f func(string) string) {
+	jm.AddFunc(name, StringFn(f))
 }
 
 // ParseStruct extracts metadata fields from the given structure based on the provided tag.
+// Extracted rules are cached per type, instantiated generic types included,
+// so repeated calls for the same type do not pay the reflection cost again.
 func (jm *JsonMaskerImpl) ParseStruct(src any) StructMaskRules {
-	res := StructMaskRules{
-		Rules: jm.extractStructRules(src, ""),
+	rules := jm.structRules(src)
+	fields := jm.structFields(src)
+	if rules == nil && fields == nil {
+		return StructMaskRules{}
 	}
 
-	for i := range res.Rules {
-		res.Rules[i].sliceLevel = strings.Count(res.Rules[i].Path, ".#")
+	var classes map[string][]string
+	for path, c := range jm.structClasses(src) {
+		if classes == nil {
+			classes = make(map[string][]string)
+		}
+		classes[path] = append([]string(nil), c...)
 	}
 
-	return res
+	// return a copy, so the caller can't modify cached rules.
+	return StructMaskRules{
+		Rules:   append([]Rule(nil), rules...),
+		Fields:  append([]string(nil), fields...),
+		Classes: classes,
+	}
+}
+
+// structRules returns cached rules for the type of src, extracting them on the first call.
+func (jm *JsonMaskerImpl) structRules(src any) []Rule {
+	if jm.parent != nil {
+		return jm.parent.structRules(src)
+	}
+
+	t := reflect.TypeOf(src)
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if rules, ok := jm.cache.Load(t); ok {
+		return rules.([]Rule)
+	}
+
+	rules := jm.extractStructRules(src, "")
+	jm.cache.Store(t, rules)
+	return rules
 }
 
 // joinPath joins parent and child attribute names using JSON path separator.
@@ -145,37 +347,67 @@ func (jm *JsonMaskerImpl) extractStructFieldRules(
 	kind = val.Kind()
 	jsonAttrName, jsonMaskTag := jm.parseFieldTag(sf)
 
-	if jsonMaskTag == "-" {
-		// quick return if tag holds "-".
-		return []Rule{{Path: joinPath(parentAttr, jsonAttrName), Action: jsonMaskTag}}
+	action, opts := parseMaskTag(jsonMaskTag)
+	isContainer := (kind == reflect.Slice || kind == reflect.Array || kind == reflect.Struct) && !isLeafType(val.Type())
+	if action != "" {
+		// the action is applied to the whole field value even if it's a struct or slice.
+		rule := fieldRule(joinPath(parentAttr, jsonAttrName), jsonMaskTag, sf.Type)
+		if !isContainer || rule.Action == "-" || rule.Keys {
+			return []Rule{rule}
+		}
+		// actions like limit keep the container, rules of nested fields follow
+		// to mask what is kept; they find nothing if the container is replaced.
+		rules = append(rules, rule)
+	} else if !isContainer {
+		// quick return if no mask tag and it's basic type, map or a type with
+		// custom JSON encoding like decimal or time.
+		return nil
 	}
 
-	if !(kind == reflect.Ptr || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Struct || kind == reflect.Map) {
-		// quick return if no mask tag and it's basic type.
-		if jsonMaskTag == "" {
-			return nil
-		}
-		return []Rule{{Path: joinPath(parentAttr, jsonAttrName), Action: jsonMaskTag}}
+	override := opts["override"]
+	if override == "none" {
+		// rules of the nested type are suppressed at the embedding site
+		return nil
 	}
 
-	if isSlice {
+	switch {
+	case isSlice:
 		jsonAttrName = joinPath(parentAttr, jsonAttrName+".#")
+	case sf.Anonymous && !hasJSONName(sf):
+		// fields of embedded structs are promoted to the parent object
+		jsonAttrName = parentAttr
+	default:
+		jsonAttrName = joinPath(parentAttr, jsonAttrName)
 	}
 
+	var nested []Rule
 	switch val.Kind() {
 	case reflect.Struct:
-		rules = append(rules, jm.extractStructRules(val.Interface(), jsonAttrName)...)
+		nested = jm.extractStructRules(val.Interface(), jsonAttrName)
 	case reflect.Slice:
 		for val.Kind() == reflect.Slice {
 			val = reflect.New(val.Type().Elem()).Elem()
 			jsonAttrName += ".#"
 		}
-		rules = append(rules, jm.extractStructRules(val.Interface(), jsonAttrName)...)
-	default:
-		rules = append(rules, Rule{Path: joinPath(parentAttr, jsonAttrName), Action: sf.Tag.Get(jm.tag)})
+		nested = jm.extractStructRules(val.Interface(), jsonAttrName)
 	}
 
-	return rules
+	if override != "" {
+		// actions of the nested type are replaced at the embedding site
+		for i := range nested {
+			if !isExclusion(nested[i]) {
+				nested[i].Action = override
+			}
+		}
+	}
+
+	return append(rules, nested...)
+}
+
+// hasJSONName reports whether the json tag of the field sets the attribute name.
+func hasJSONName(sf reflect.StructField) bool {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	return name != ""
 }
 
 func (jm *JsonMaskerImpl) parseFieldTag(field reflect.StructField) (string, string) {
@@ -188,24 +420,259 @@ func (jm *JsonMaskerImpl) parseFieldTag(field reflect.StructField) (string, stri
 	return jsonAttr, field.Tag.Get(jm.tag)
 }
 
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isLeafType reports whether values of the type have custom JSON encoding,
+// e.g. decimal.Decimal, big.Int or time.Time, so their fields are not inspected.
+func isLeafType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	pt := reflect.PtrTo(t)
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+}
+
+// isNumericStringType reports whether values of the leaf type are encoded as
+// JSON strings holding numbers, e.g. decimal.Decimal, judging by the zero value.
+func isNumericStringType(t reflect.Type) (res bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !isLeafType(t) {
+		return false
+	}
+
+	defer func() {
+		if recover() != nil {
+			res = false // zero value can't be marshaled
+		}
+	}()
+
+	raw, err := json.Marshal(reflect.New(t).Interface())
+	if err != nil {
+		return false
+	}
+	v := gjson.ParseBytes(raw)
+	return v.Type == gjson.String && gjson.Parse(v.Str).Type == gjson.Number && gjson.Valid(v.Str)
+}
+
+// fieldRule returns the rule of the field of the type with the path and the mask tag.
+// Actions of types encoded as strings holding numbers, like decimals, are applied
+// to the numbers keeping the string encoding, see QuotedFn.
+func fieldRule(path, tag string, t reflect.Type) Rule {
+	rule := ruleFromTag(path, tag)
+	if !rule.Keys && !strings.HasPrefix(rule.Action, "quoted(") && rule.Action != "-" && isNumericStringType(t) {
+		rule.Action = "quoted(" + rule.Action + ")"
+	}
+	return rule
+}
+
+// ruleFromTag returns the rule of the field with the path and the mask tag.
+// Option "keys" applies the action to map keys, option "quoted" applies it
+// to numbers encoded as strings, option "sample=0.3" sets the sampling rate,
+// option "when=cond" sets the condition, options "desc" and "owner" annotate
+// the rule, e.g. "email,desc=login email,owner=crm".
+func ruleFromTag(path, tag string) Rule {
+	action, opts := parseMaskTag(tag)
+	if opts.has("quoted") {
+		action = "quoted(" + action + ")"
+	}
+	sample, _ := strconv.ParseFloat(opts["sample"], 64)
+	return Rule{
+		Path:        path,
+		Action:      action,
+		Keys:        opts.has("keys"),
+		Sample:      sample,
+		When:        opts["when"],
+		Description: opts["desc"],
+		Owner:       opts["owner"],
+	}
+}
+
+// parseMaskTag splits the mask tag like "email,keys" to the action and its options.
+// Commas inside parentheses belong to the action, e.g. "limit(3,more)".
+// Options are flags like "keys" or name=value pairs like "override=none",
+// the latter may stand in place of the action.
+func parseMaskTag(tag string) (string, tagOptions) {
+	var parts []string
+
+	depth, start := 0, 0
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, tag[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, tag[start:])
+
+	action := parts[0]
+	if strings.Contains(action, "=") && !strings.Contains(action, "(") {
+		action = ""
+	} else {
+		parts = parts[1:]
+	}
+
+	var opts tagOptions
+	for _, opt := range parts {
+		if opts == nil {
+			opts = make(tagOptions)
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		opts[name] = value
+	}
+	return action, opts
+}
+
+// ParseMaskTag splits the mask tag to the action and options by name, the way
+// ParseStruct does, e.g. for static checks of struct tags.
+func ParseMaskTag(tag string) (action string, opts map[string]string) {
+	return parseMaskTag(tag)
+}
+
+// tagOptions holds options of the mask tag by name.
+type tagOptions map[string]string
+
+// has reports whether the option is set.
+func (o tagOptions) has(name string) bool {
+	_, ok := o[name]
+	return ok
+}
+
 // Mask applies masking to JSON based on the given rules.
-func (jm *JsonMaskerImpl) Mask(data []byte, smr StructMaskRules) ([]byte, error) {
-	return jm.mask(data, smr.Rules)
+// Options adjust the rules for this call only, e.g. WithOverride, WithDisable.
+func (jm *JsonMaskerImpl) Mask(data []byte, smr StructMaskRules, opts ...MaskOption) ([]byte, error) {
+	if jm.disabled {
+		return data, nil
+	}
+
+	rules := applyMaskOptions(smr.Rules, opts)
+
+	var fingerprint string
+	var applied map[string]bool
+	if jm.marker != "" {
+		// documents masked under overrides, e.g. unmasking a field, aren't
+		// exempted from masking by the base rules after the overrides expire
+		fingerprint = rulesFingerprint(jm.overriddenRules(rules))
+		if applied = jm.markedBy(data); applied[fingerprint] {
+			return data, nil
+		}
+		if marker := pathEscaper.Replace(jm.marker); gjson.GetBytes(data, marker).Exists() {
+			// masked by other rules before, or forged
+			rules = append(rules[:len(rules):len(rules)], Rule{Path: "!" + marker})
+		}
+	}
+
+	size := len(data)
+	data, err := jm.maskDocument(data, smr, rules)
+	if err == nil {
+		data, err = jm.postMask(data)
+	}
+	if err == nil && fingerprint != "" {
+		data, err = jm.mark(data, applied, fingerprint)
+	}
+	jm.stats.masked(size, err)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// maskDocument masks the document with the rules adjusted for the call,
+// reporting drift and masking unknown fields of the rule set if configured.
+func (jm *JsonMaskerImpl) maskDocument(data []byte, smr StructMaskRules, rules []Rule) ([]byte, error) {
+	jm.reportDrift(data, rules)
+	return jm.mask(data, jm.unknownFieldRules(data, smr.Fields, rules))
+}
+
+// MaskChanged is like Mask but also reports whether the result differs from data,
+// so callers can skip rewriting bodies or re-signing payloads nothing was masked in.
+func (jm *JsonMaskerImpl) MaskChanged(data []byte, smr StructMaskRules, opts ...MaskOption) ([]byte, bool, error) {
+	res, err := jm.Mask(data, smr, opts...)
+	if err != nil {
+		return nil, false, err
+	}
+	return res, !bytes.Equal(res, data), nil
+}
+
+// MaskAt applies rules relative to the sub-document found by the path, so envelope
+// formats can reuse rule sets written for the inner object, e.g. "payload.customer".
+// The path may select several sub-documents, e.g. "events.#.payload".
+func (jm *JsonMaskerImpl) MaskAt(data []byte, path string, smr StructMaskRules, opts ...MaskOption) ([]byte, error) {
+	if jm.disabled {
+		return data, nil
+	}
+
+	paths := expandPath(data, path)
+	if len(paths) == 0 {
+		jm.log("jsonmask: path not found", "path", path)
+	}
+
+	rules := applyMaskOptions(smr.Rules, opts)
+	for _, p := range paths {
+		if p == "" {
+			masked, err := jm.mask(data, jm.unknownFieldRules(data, smr.Fields, rules))
+			if err != nil {
+				return nil, err
+			}
+			return jm.postMask(masked)
+		}
+
+		sub := []byte(gjson.GetBytes(data, p).Raw)
+		masked, err := jm.mask(sub, jm.unknownFieldRules(sub, smr.Fields, rules))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = sjson.SetRawBytes(data, p, masked); err != nil {
+			return nil, err
+		}
+	}
+	return jm.postMask(data)
+}
+
+// maskRun holds state of a single masking call.
+type maskRun struct {
+	exclusions []string          // paths exempted from rules
+	originals  map[string]string // path in the input -> original raw value, if captured
+	deleted    map[string][]int  // path of array in the input -> indexes of deleted elements, if captured
 }
 
 func (jm *JsonMaskerImpl) mask(data []byte, rules []Rule) ([]byte, error) {
+	return jm.maskRun(data, rules, &maskRun{})
+}
+
+func (jm *JsonMaskerImpl) maskRun(data []byte, rules []Rule, run *maskRun) ([]byte, error) {
 	var err error
 
+	rules = conditionalRules(data, rules)
+
+	if run.originals == nil && len(run.exclusions) == 0 {
+		if res, ok, err := jm.maskSinglePass(data, rules); ok {
+			return res, err
+		}
+	}
+
 	for _, rule := range rules {
-		if rule.sliceLevel == 0 {
-			data, err = jm.maskSimplePath(data, rule.Path, rule.Action)
-		} else {
-			idx := strings.Index(rule.Path, ".#")
-			if idx < 0 {
-				return nil, errors.New("invalid json array path")
-			}
-			data, err = jm.rangeOverArray(data, rule, rule.Path[:idx+2], rule.Path[idx+2:])
+		if isExclusion(rule) {
+			run.exclusions = append(run.exclusions, rule.Path[1:])
 		}
+	}
+
+	for _, rule := range rules {
+		if isExclusion(rule) {
+			continue
+		}
+		data, err = jm.applyRule(data, rule, run)
 		if err != nil {
 			return nil, err
 		}
@@ -214,74 +681,466 @@ func (jm *JsonMaskerImpl) mask(data []byte, rules []Rule) ([]byte, error) {
 	return data, nil
 }
 
-func (jm *JsonMaskerImpl) maskSimplePath(data []byte, path, action string) ([]byte, error) {
+// conditionalRules returns rules with conditions not met by data left out.
+func conditionalRules(data []byte, rules []Rule) []Rule {
+	var res []Rule
+	for i, rule := range rules {
+		if rule.When == "" || conditionMet(data, rule.When) {
+			if res != nil {
+				res = append(res, rule)
+			}
+			continue
+		}
+		if res == nil {
+			res = append(make([]Rule, 0, len(rules)-1), rules[:i]...)
+		}
+	}
+	if res == nil {
+		return rules
+	}
+	return res
+}
+
+// conditionMet reports whether the document matches the gjson query condition,
+// negated by a leading "!".
+func conditionMet(data []byte, cond string) bool {
+	cond, negated := strings.CutPrefix(cond, "!")
+	doc := gjson.ParseBytes(data)
+	if !doc.IsObject() && !doc.IsArray() {
+		return negated
+	}
+	matched := gjson.Get("["+doc.Raw+"]", "#("+cond+")#|#").Int() > 0
+	return matched != negated
+}
 
-	if action == "-" {
-		return sjson.DeleteBytes(data, path)
+// capture records the original value of the path, if capturing is on.
+// Values are recorded by their paths in the input document, as array elements
+// may have been shifted by deletions of preceding rules. Values changed by
+// several rules keep the value seen first.
+func (run *maskRun) capture(data []byte, path string) {
+	if run.originals == nil {
+		return
+	}
+	orig := run.inputPath(path)
+	if _, ok := run.originals[orig]; ok {
+		return
+	}
+	if path == "" {
+		run.originals[orig] = string(data)
+		return
 	}
+	run.originals[orig] = gjson.GetBytes(data, path).Raw
+}
 
-	maskFunc, exists := jm.funcs[action]
-	if !exists {
-		return data, nil
+// captureDeletion records deletion of the value of the path, if capturing is
+// on, so paths of following array elements are mapped to the input by inputPath.
+func (run *maskRun) captureDeletion(data []byte, path string) {
+	if run.originals == nil {
+		return
+	}
+	parent, last := splitLastKey(path)
+	idx, err := strconv.Atoi(last)
+	if err != nil || idx < 0 {
+		return
+	}
+	if arr := gjson.GetBytes(data, parent); (parent == "" && !gjson.ParseBytes(data).IsArray()) || (parent != "" && !arr.IsArray()) {
+		return
 	}
-	value := gjson.GetBytes(data, path)
-	maskedValue := maskFunc(value.Raw)
-	return sjson.SetRawBytes(data, path, maskedValue)
+
+	orig := run.inputPath(path)
+	origParent, origLast := splitLastKey(orig)
+	origIdx, _ := strconv.Atoi(origLast)
+	if run.deleted == nil {
+		run.deleted = make(map[string][]int)
+	}
+	deleted := append(run.deleted[origParent], origIdx)
+	sort.Ints(deleted)
+	run.deleted[origParent] = deleted
 }
 
-// items.#.#.currency
-// items.#.balances.#.currency
-// items.#.balances.#.#.amount
+// inputPath returns the path of the value in the input document, given its
+// path in the document masked so far, skipping deleted array elements.
+func (run *maskRun) inputPath(path string) string {
+	if len(run.deleted) == 0 || path == "" {
+		return path
+	}
 
-func (jm *JsonMaskerImpl) rangeOverArray(data []byte, rule Rule, arrPath, arrItemPath string) ([]byte, error) {
-	var err error
+	var res string
+	for _, key := range splitKeys(path) {
+		if deleted := run.deleted[res]; len(deleted) > 0 {
+			if idx, err := strconv.Atoi(key); err == nil && idx >= 0 {
+				for _, d := range deleted {
+					if d > idx {
+						break
+					}
+					idx++ // elements up to the index were deleted before it
+				}
+				key = strconv.Itoa(idx)
+			}
+		}
+		res = joinPath(res, key)
+	}
+	return res
+}
 
-	arr := gjson.GetBytes(data, arrPath)
-	if !arr.Exists() {
-		return data, errors.New("json array not found")
+// splitKeys splits the concrete path to escaped keys.
+func splitKeys(path string) []string {
+	var keys []string
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++ // skip escaped character
+		case '.':
+			keys = append(keys, path[start:i])
+			start = i + 1
+		}
 	}
+	return append(keys, path[start:])
+}
+
+// splitLastKey splits the concrete path to the parent path and the last escaped key.
+func splitLastKey(path string) (parent, key string) {
+	keys := splitKeys(path)
+	return strings.Join(keys[:len(keys)-1], "."), keys[len(keys)-1]
+}
 
-	var subArrPath, subArrItemPath string
-	subArrIdx := strings.Index(arrItemPath, ".#")
-	if subArrIdx >= 0 {
-		subArrPath = arrItemPath[:subArrIdx+2]
-		subArrItemPath = arrItemPath[subArrIdx+2:]
+// isExclusion reports whether the rule exempts its path from other rules.
+func isExclusion(rule Rule) bool {
+	return strings.HasPrefix(rule.Path, "!")
+}
+
+// isDeletion reports whether the rule always deletes values of its path:
+// its action is "-" and it has no condition, sampling or Keys.
+func isDeletion(rule Rule) bool {
+	return rule.Action == "-" && !rule.Keys && rule.When == "" && rule.Sample == 0
+}
+
+// samplePaths returns paths of values selected by the sampling rate,
+// all paths if the rate is 0.
+func samplePaths(data []byte, paths []string, rate float64) []string {
+	if rate <= 0 || rate >= 1 {
+		return paths
 	}
 
-	// range over array
-	for i := 0; i < int(arr.Int()); i++ {
-		path := strings.ReplaceAll(arrPath, "#", strconv.Itoa(i))
-		if rule.Action == "-" {
-			data, err = sjson.DeleteBytes(data, path+arrItemPath)
-			if err != nil {
-				return nil, err
+	res := paths[:0:0]
+	for _, p := range paths {
+		raw := string(data)
+		if p != "" {
+			raw = gjson.GetBytes(data, p).Raw
+		}
+		sum := sha256.Sum256([]byte(raw))
+		if float64(binary.BigEndian.Uint64(sum[:8])>>11)/(1<<53) < rate {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// excludePaths drops paths of excluded values and replaces paths of their
+// ancestors with paths of children, so the ancestor is masked except the
+// excluded values. Paths of values preserved by keys rules are only dropped.
+func excludePaths(data []byte, paths, exclusions []string, keys bool) []string {
+	if len(exclusions) == 0 {
+		return paths
+	}
+
+	var excluded []string
+	for _, ex := range exclusions {
+		excluded = append(excluded, expandPath(data, ex)...)
+	}
+	return excludeConcretePaths(data, paths, excluded, keys)
+}
+
+func excludeConcretePaths(data []byte, paths, excluded []string, keys bool) []string {
+	var res []string
+	for _, p := range paths {
+		switch {
+		case hasPathPrefix(p, excluded, false):
+		case !keys && hasPathPrefix(p, excluded, true):
+			v := gjson.ParseBytes(data)
+			if p != "" {
+				v = gjson.GetBytes(data, p)
 			}
-			continue
+			var e pathExpander
+			var children []string
+			for _, child := range e.childKeys(p, v) {
+				children = append(children, joinPath(p, child))
+			}
+			res = append(res, excludeConcretePaths(data, children, excluded, keys)...)
+		default:
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// hasPathPrefix reports whether the path equals or is nested in any of paths.
+// If ancestor is true, it reports whether the path is an ancestor of any of paths.
+func hasPathPrefix(path string, paths []string, ancestor bool) bool {
+	for _, p := range paths {
+		if ancestor && (path == "" || strings.HasPrefix(p, path+".")) {
+			return true
+		}
+		if !ancestor && (path == p || strings.HasPrefix(path, p+".")) {
+			return true
 		}
+	}
+	return false
+}
+
+// ruleFunc returns the masking function of the rule action, nil for deletion.
+// Unknown actions are reported as errors in strict mode, otherwise the rule
+// is logged and skipped, i.e. ok is false. Actions overridden by
+// AddRuleOverride are replaced, the empty one skips the rule.
+func (jm *JsonMaskerImpl) ruleFunc(rule Rule) (maskFunc func(string) []byte, ok bool, err error) {
+	if o, found := jm.ruleOverride(rule.Path); found {
+		if o.Action == "" {
+			return nil, false, nil
+		}
+		rule.Action = o.Action
+	}
+
+	if rule.Action == "-" {
+		return nil, true, nil
+	}
+
+	if maskFunc, ok = jm.lookupFunc(rule.Action); !ok {
+		if jm.strict {
+			return nil, false, fmt.Errorf("%w: %s", ErrUnknownAction, rule.Action)
+		}
+		jm.log("jsonmask: unknown action, rule skipped", "path", rule.Path, "action", rule.Action)
+		return nil, false, nil
+	}
+	return jm.wrapFunc(rule.Action, maskFunc), true, nil
+}
+
+// applyRule applies the rule action to every value matching the rule path
+// except values excluded by exclusion paths. Paths not found in data are skipped.
+func (jm *JsonMaskerImpl) applyRule(data []byte, rule Rule, run *maskRun) ([]byte, error) {
+	maskFunc, ok, err := jm.ruleFunc(rule)
+	if !ok {
+		return data, err
+	}
+
+	if err := jm.checkModifiers(rule.Path); err != nil {
+		if jm.strict {
+			return nil, err
+		}
+		jm.log("jsonmask: modifier not allowed, rule skipped", "path", rule.Path, "action", rule.Action)
+		return data, nil
+	}
+
+	paths, overflows := expandPathLimit(data, rule.Path, jm.arrayLimit)
+	if len(paths) == 0 {
+		jm.log("jsonmask: path not found", "path", rule.Path, "action", rule.Action)
+	}
+
+	if len(overflows) > 0 && jm.overflowPolicy == OverflowError {
+		return nil, fmt.Errorf("%w: %s", ErrArrayLimit, overflows[0])
+	}
 
-		// if array has no sub-array
-		if subArrIdx < 0 {
-			value := gjson.GetBytes(data, path+arrItemPath)
-			maskFunc, exists := jm.funcs[rule.Action]
-			if !exists {
+	paths = excludePaths(data, paths, run.exclusions, rule.Keys)
+	paths = samplePaths(data, paths, rule.Sample)
+	jm.stats.applied(rule.Action, len(paths))
+
+	if edits, ok := valueEdits(data, paths); ok && maskFunc != nil && !rule.Keys {
+		// values are replaced in a single rewrite of data, invalid ones are deleted afterwards
+		var invalid []string
+		valid := edits[:0]
+		for i, e := range edits {
+			run.capture(data, paths[i])
+			e.raw = maskFunc(string(data[e.start:e.end]))
+			if ok, err := jm.checkOutput(rule, paths[i], e.raw); err != nil {
+				return nil, err
+			} else if !ok {
+				invalid = append(invalid, paths[i])
 				continue
 			}
+			valid = append(valid, e)
+		}
+		data = applyEdits(data, valid)
+		paths = sortByPosition(data, invalid)
+		maskFunc = nil
+	}
 
-			maskedValue := maskFunc(value.Raw)
-			data, err = sjson.SetRawBytes(data, path+arrItemPath, maskedValue)
+	// go backwards, so deletion of array elements doesn't shift indexes of remaining paths.
+	for i := len(paths) - 1; i >= 0; i-- {
+		if paths[i] == "" {
+			// the whole document is selected, e.g. by "@this".
+			if maskFunc != nil {
+				run.capture(data, "")
+				res := maskFunc(string(data))
+				if ok, err := jm.checkOutput(rule, "", res); err != nil {
+					return nil, err
+				} else if !ok {
+					res = []byte("null")
+				}
+				data = res
+			}
+			continue
+		}
+
+		run.capture(data, paths[i])
+
+		if rule.Keys {
+			value := gjson.GetBytes(data, paths[i])
+			if value.IsObject() {
+				data, err = sjson.SetRawBytes(data, paths[i], maskKeys(value, maskFunc))
+			}
+		} else if maskFunc == nil {
+			run.captureDeletion(data, paths[i])
+			data, err = sjson.DeleteBytes(data, paths[i])
 		} else {
-			// if array has sub-array
-			data, err = jm.rangeOverArray(data, rule, path+subArrPath, subArrItemPath)
+			value := gjson.GetBytes(data, paths[i])
+			res := maskFunc(value.Raw)
+			ok, checkErr := jm.checkOutput(rule, paths[i], res)
+			switch {
+			case checkErr != nil:
+				return nil, checkErr
+			case ok:
+				data, err = sjson.SetRawBytes(data, paths[i], res)
+			default:
+				run.captureDeletion(data, paths[i])
+				data, err = sjson.DeleteBytes(data, paths[i])
+			}
 		}
 		if err != nil {
 			return nil, err
 		}
+	}
 
+	if jm.overflowPolicy == OverflowDelete {
+		// go backwards, so nested arrays are truncated before outer ones.
+		for i := len(overflows) - 1; i >= 0; i-- {
+			if data, err = truncateArray(data, overflows[i], jm.arrayLimit); err != nil {
+				return nil, err
+			}
+		}
 	}
+
 	return data, nil
 }
 
+// checkOutput reports whether the value returned by the masking function of
+// the rule for the path is valid raw JSON, if output validation is on.
+// Invalid values fail with ErrInvalidOutput according to the policy.
+func (jm *JsonMaskerImpl) checkOutput(rule Rule, path string, raw []byte) (bool, error) {
+	if !jm.validOutput || gjson.ValidBytes(raw) {
+		return true, nil
+	}
+	if jm.invalidPolicy == InvalidOutputError {
+		return false, fmt.Errorf("%w: %s by %s", ErrInvalidOutput, path, rule.Action)
+	}
+	jm.log("jsonmask: invalid output, value deleted", "path", path, "action", rule.Action)
+	return false, nil
+}
+
+// sortByPosition returns paths sorted by positions of their values in data,
+// so processing them backwards doesn't shift indexes of remaining paths.
+func sortByPosition(data []byte, paths []string) []string {
+	sort.Slice(paths, func(i, j int) bool {
+		return gjson.GetBytes(data, paths[i]).Index < gjson.GetBytes(data, paths[j]).Index
+	})
+	return paths
+}
+
+// valueEdits returns edits replacing values found by the paths, in the order
+// of paths, without replacement yet. It's not ok if a position of any value
+// is unknown, e.g. the path uses modifiers, or values are nested in each other.
+func valueEdits(data []byte, paths []string) ([]edit, bool) {
+	edits := make([]edit, len(paths))
+	for i, p := range paths {
+		if p == "" {
+			return nil, false
+		}
+		value := gjson.GetBytes(data, p)
+		end := value.Index + len(value.Raw)
+		if value.Index <= 0 || end > len(data) || string(data[value.Index:end]) != value.Raw {
+			return nil, false
+		}
+		edits[i] = edit{start: value.Index, end: end}
+	}
+
+	sorted := append([]edit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].start < sorted[i-1].end {
+			return nil, false
+		}
+	}
+	return edits, true
+}
+
+// truncateArray keeps the first n elements of the array found by the path.
+func truncateArray(data []byte, path string, n int) ([]byte, error) {
+	arr := gjson.ParseBytes(data)
+	if path != "" {
+		arr = gjson.GetBytes(data, path)
+	}
+
+	raw := []byte{'['}
+	i := 0
+	arr.ForEach(func(_, value gjson.Result) bool {
+		if i == n {
+			return false
+		}
+		if i > 0 {
+			raw = append(raw, ',')
+		}
+		raw = append(raw, value.Raw...)
+		i++
+		return true
+	})
+	raw = append(raw, ']')
+
+	if path == "" {
+		return raw, nil
+	}
+	return sjson.SetRawBytes(data, path, raw)
+}
+
+// maskKeys returns the object with attribute names masked by maskFunc.
+// Names masked to non-string values are converted to strings. Names masked
+// to a name already used get a suffix, e.g. "3~2", so no attribute is lost
+// to duplicate names. Nil maskFunc removes all attributes.
+func maskKeys(obj gjson.Result, maskFunc func(string) []byte) []byte {
+	res := []byte{'{'}
+	if maskFunc != nil {
+		used := make(map[string]bool)
+		obj.ForEach(func(key, value gjson.Result) bool {
+			masked := maskFunc(key.Raw)
+			name := gjson.ParseBytes(masked)
+			if name.Type != gjson.String {
+				name = gjson.Result{Type: gjson.String, Str: string(masked)}
+			}
+
+			unique := name.Str
+			for n := 2; used[unique]; n++ {
+				unique = name.Str + "~" + strconv.Itoa(n)
+			}
+			used[unique] = true
+
+			if len(res) > 1 {
+				res = append(res, ',')
+			}
+			res = append(res, quote(unique)...)
+			res = append(res, ':')
+			res = append(res, value.Raw...)
+			return true
+		})
+	}
+	return append(res, '}')
+}
+
 // Error definitions
 var (
-	ErrInvalidInput = errors.New("input must be a struct")
+	ErrInvalidInput  = errors.New("input must be a struct")
+	ErrRulesNotFound = errors.New("rule set not found")
+	ErrInvalidJSON   = errors.New("invalid json")
+	ErrUnknownAction = errors.New("unknown action")
+	ErrModifier      = errors.New("modifier not allowed")
+	ErrArrayLimit    = errors.New("array limit exceeded")
+	ErrInvalidOutput = errors.New("invalid masking function output")
+	ErrInvalidPath   = errors.New("invalid path")
 )
@@ -3,10 +3,16 @@ package jsonmask_test
 import (
 	"bytes"
 	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/axkit/jsonmask"
 	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 type TestHiddenAttr struct {
@@ -181,6 +187,52 @@ func TestJsonMaskerImpl_ParseStruct(t *testing.T) {
 	})
 }
 
+type TestCustomer struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name" mask:"initialChar"`
+	Email string `json:"email" mask:"email"`
+}
+
+type TestPage[T any] struct {
+	Total int `json:"total"`
+	Items []T `json:"items"`
+}
+
+func TestJsonMaskerImpl_ParseStruct_Generic(t *testing.T) {
+	jm := jsonmask.New()
+
+	fields := jm.ParseStruct(TestPage[TestCustomer]{})
+	assert.Len(t, fields.Rules, 2)
+	checkRule(t, fields.Rules, 0, "items.#.name", "initialChar")
+	checkRule(t, fields.Rules, 1, "items.#.email", "email")
+
+	// different instantiation of the same generic type must not reuse cached rules.
+	fields = jm.ParseStruct(TestPage[TestHiddenAttr]{})
+	assert.Len(t, fields.Rules, 1)
+	checkRule(t, fields.Rules, 0, "items.#.amount", "-")
+
+	page := TestPage[TestCustomer]{
+		Total: 1,
+		Items: []TestCustomer{{ID: 1, Name: "john", Email: "john@example.com"}},
+	}
+	jsonData, err := json.Marshal(page)
+	assert.NoError(t, err)
+
+	result, err := jm.Mask(jsonData, jm.ParseStruct(&page))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"total":1,"items":[{"id":1,"name":"J","email":"j**n@e******.com"}]}`, string(result))
+}
+
+func TestJsonMaskerImpl_ParseStruct_Cache(t *testing.T) {
+	jm := jsonmask.New()
+
+	first := jm.ParseStruct(TestCustomer{})
+	first.Rules[0].Action = "changed"
+
+	second := jm.ParseStruct(TestCustomer{})
+	checkRule(t, second.Rules, 0, "name", "initialChar")
+}
+
 func checkRule(t *testing.T, rules []jsonmask.Rule, index int, path, action string) {
 	t.Helper()
 	assert.Equal(t, path, rules[index].Path)
@@ -302,3 +354,690 @@ func TestMask(t *testing.T) {
 	})
 
 }
+
+func TestMask_StructFieldAction(t *testing.T) {
+	type Address struct {
+		Street string `json:"street"`
+		City   string `json:"city"`
+	}
+	type Customer struct {
+		Name    string  `json:"name"`
+		Address Address `json:"address" mask:"addressObject"`
+		Billing string  `json:"billing" mask:"address"`
+	}
+
+	jm := jsonmask.New()
+	rules := jm.ParseStruct(Customer{})
+	assert.Len(t, rules.Rules, 2)
+	checkRule(t, rules.Rules, 0, "address", "addressObject")
+	checkRule(t, rules.Rules, 1, "billing", "address")
+
+	c := Customer{
+		Name:    "john",
+		Address: Address{Street: "Baker Street 221B", City: "London"},
+		Billing: "Wenceslas Square 1, 11000 Prague, Czechia",
+	}
+	jsonData, err := json.Marshal(c)
+	assert.NoError(t, err)
+
+	result, err := jm.Mask(jsonData, rules)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"john","address":{"street":"","city":"London"},"billing":"Prague, Czechia"}`, string(result))
+}
+
+func TestMask_Count(t *testing.T) {
+	type Transaction struct {
+		Amount int `json:"amount"`
+	}
+	type Account struct {
+		ID           int           `json:"id"`
+		Transactions []Transaction `json:"transactions" mask:"count"`
+	}
+
+	jm := jsonmask.New()
+	result, err := jm.Mask([]byte(`{"id":1,"transactions":[{"amount":1},{"amount":2}]}`), jm.ParseStruct(Account{}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"transactions":2}`, string(result))
+}
+
+func TestMask_Keys(t *testing.T) {
+	type Ledger struct {
+		Balances map[string]int    `json:"balances" mask:"email,keys"`
+		Limits   map[string]string `json:"limits" mask:"limit(1,more),keys"`
+	}
+
+	jm := jsonmask.New()
+	rules := jm.ParseStruct(Ledger{})
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "balances", Action: "email", Keys: true},
+		{Path: "limits", Action: "limit(1,more)", Keys: true},
+	}, rules.Rules)
+
+	result, err := jm.Mask([]byte(`{"balances":{"john@example.com":10,"jane@example.com":{"eur":5},"joan@example.com":1,"j**n@e******.com~2":3},"limits":{}}`), rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"balances":{"j**n@e******.com":10,"j**e@e******.com":{"eur":5},"j**n@e******.com~2":1,"j**n@e******.com~2~2":3},"limits":{}}`, string(result))
+
+	result, err = jm.Mask([]byte(`{"accounts":{"123":1,"456":2},"other":[1]}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "accounts", Action: "length", Keys: true},
+			{Path: "other", Action: "-", Keys: true},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"accounts":{"3":1,"3~2":2},"other":[1]}`, string(result))
+
+	result, err = jm.Mask([]byte(`{"accounts":{"123":1}}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{{Path: "accounts", Action: "-", Keys: true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"accounts":{}}`, string(result))
+}
+
+func TestMask_Options(t *testing.T) {
+	jm := jsonmask.New()
+	data := []byte(`{"customer":{"firstName":"john","lastName":"doe","email":"john@example.com"}}`)
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "customer.firstName", Action: "initialChar"},
+			{Path: "customer.lastName", Action: "upper"},
+		},
+	}
+
+	result, err := jm.Mask(data, rules,
+		jsonmask.WithOverride("customer.firstName", "null"),
+		jsonmask.WithOverride("customer.email", "email"),
+		jsonmask.WithDisable("customer.lastName"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"customer":{"firstName":null,"lastName":"doe","email":"j**n@e******.com"}}`, string(result))
+
+	result, err = jm.Mask(data, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"customer":{"firstName":"J","lastName":"DOE","email":"john@example.com"}}`, string(result))
+	assert.Equal(t, "initialChar", rules.Rules[0].Action, "base rules are not modified")
+}
+
+func TestMask_ArrayLimit(t *testing.T) {
+	data := []byte(`{"items":[{"c":"a","tags":["x","y","z"]},{"c":"b"},{"c":"c"}],"short":["a"]}`)
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{{Path: "items.#.c", Action: "upper"}, {Path: "items.#.tags.#", Action: "upper"}, {Path: "short.#", Action: "upper"}},
+	}
+
+	tests := []struct {
+		policy   jsonmask.OverflowPolicy
+		expected string
+	}{
+		{jsonmask.OverflowSkip, `{"items":[{"c":"A","tags":["X","Y","z"]},{"c":"B"},{"c":"c"}],"short":["A"]}`},
+		{jsonmask.OverflowDelete, `{"items":[{"c":"A","tags":["X","Y"]},{"c":"B"}],"short":["A"]}`},
+	}
+
+	for _, tt := range tests {
+		jm := jsonmask.New(jsonmask.WithArrayLimit(2, tt.policy))
+		result, err := jm.Mask(data, rules)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, string(result))
+	}
+
+	jm := jsonmask.New(jsonmask.WithArrayLimit(2, jsonmask.OverflowError))
+	_, err := jm.Mask(data, rules)
+	assert.ErrorIs(t, err, jsonmask.ErrArrayLimit)
+
+	result, err := jm.Mask([]byte(`["a","b","c"]`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "upper"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `["a","b","c"]`, string(result))
+
+	jm = jsonmask.New(jsonmask.WithArrayLimit(1, jsonmask.OverflowDelete))
+	result, err = jm.Mask([]byte(`["a","b","c"]`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "*", Action: "upper"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `["A"]`, string(result))
+}
+
+func TestJsonMaskerImpl_MaskAt(t *testing.T) {
+	jm := jsonmask.New()
+	rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}, {Path: "name", Action: "-"}}}
+
+	result, err := jm.MaskAt([]byte(`{"meta":{"name":"event"},"payload":{"customer":{"name":"john","email":"john@example.com"}}}`), "payload.customer", rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"meta":{"name":"event"},"payload":{"customer":{"email":"j**n@e******.com"}}}`, string(result))
+
+	result, err = jm.MaskAt([]byte(`{"events":[{"payload":{"name":"a"}},{"payload":{"name":"b"}}]}`), "events.#.payload", rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"events":[{"payload":{}},{"payload":{}}]}`, string(result))
+
+	result, err = jm.MaskAt([]byte(`{"meta":{}}`), "payload", rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"meta":{}}`, string(result))
+}
+
+func TestMask_Exclusions(t *testing.T) {
+	jm := jsonmask.New()
+	data := []byte(`{"items":[{"publicId":1,"name":"a","tags":["x"]},{"publicId":2,"name":"b"}],"owner":{"publicId":3}}`)
+
+	tests := []struct {
+		rules    []jsonmask.Rule
+		expected string
+	}{
+		{
+			[]jsonmask.Rule{{Path: "items", Action: "null"}, {Path: "!items.#.publicId"}},
+			`{"items":[{"publicId":1,"name":null,"tags":null},{"publicId":2,"name":null}],"owner":{"publicId":3}}`,
+		},
+		{
+			[]jsonmask.Rule{{Path: "!items.#.publicId"}, {Path: "items", Action: "-"}},
+			`{"items":[{"publicId":1},{"publicId":2}],"owner":{"publicId":3}}`,
+		},
+		{
+			[]jsonmask.Rule{{Path: "**.publicId", Action: "zero"}, {Path: "!items.-1"}},
+			`{"items":[{"publicId":0,"name":"a","tags":["x"]},{"publicId":2,"name":"b"}],"owner":{"publicId":0}}`,
+		},
+		{
+			[]jsonmask.Rule{{Path: "items.#", Action: "-"}, {Path: "!items.1"}},
+			`{"items":[{"publicId":2,"name":"b"}],"owner":{"publicId":3}}`,
+		},
+		{
+			[]jsonmask.Rule{{Path: "@this", Action: "null"}, {Path: "!owner"}},
+			`{"items":null,"owner":{"publicId":3}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		result, err := jm.Mask(data, jsonmask.StructMaskRules{Rules: tt.rules})
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, string(result))
+	}
+}
+
+func TestMask_Quoted(t *testing.T) {
+	type Payment struct {
+		Amount string  `json:"amount" mask:"round(100),quoted"`
+		Fee    string  `json:"fee" mask:"zero,quoted"`
+		Total  float64 `json:"total" mask:"round(10)"`
+	}
+
+	jm := jsonmask.New()
+	rules := jm.ParseStruct(Payment{})
+	checkRule(t, rules.Rules, 0, "amount", "quoted(round(100))")
+
+	result, err := jm.MaskValue(Payment{Amount: "1234.56", Fee: "1.5", Total: 1234.56})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":"1200","fee":"0","total":1230}`, string(result))
+
+	// redefined functions replace resolved actions taking them as arguments
+	jm.AddFunc("zero", func(string) []byte { return []byte(`"n/a"`) })
+	result, err = jm.MaskValue(Payment{Amount: "1234.56", Fee: "1.5", Total: 1234.56})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":"1200","fee":"n/a","total":1230}`, string(result))
+}
+
+// decimal mimics decimal types encoded as strings, like shopspring/decimal.Decimal.
+type decimal struct {
+	Value    int64
+	Exponent int32
+}
+
+func (d decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + new(big.Float).SetInt64(d.Value).Text('f', -1) + `"`), nil
+}
+
+func TestMask_Decimal(t *testing.T) {
+	type Invoice struct {
+		Amount  decimal    `json:"amount" mask:"round(100)"`
+		Fee     decimal    `json:"fee"`
+		Balance *big.Int   `json:"balance" mask:"magnitude"`
+		Rate    *big.Float `json:"rate" mask:"zero"`
+		Issued  time.Time  `json:"issued"`
+	}
+
+	jm := jsonmask.New()
+	rules := jm.ParseStruct(Invoice{})
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "amount", Action: "quoted(round(100))"},
+		{Path: "balance", Action: "magnitude"},
+		{Path: "rate", Action: "quoted(zero)"}, // big.Float is encoded as a string
+	}, rules.Rules)
+
+	result, err := jm.MaskValue(Invoice{
+		Amount:  decimal{Value: 1234},
+		Fee:     decimal{Value: 5},
+		Balance: big.NewInt(54321),
+		Rate:    big.NewFloat(1.5),
+		Issued:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":"1200","fee":"5","balance":10000,"rate":"0","issued":"2024-01-02T00:00:00Z"}`, string(result))
+}
+
+func TestJsonMaskerImpl_ParseStruct_Override(t *testing.T) {
+	type Contact struct {
+		Email string `json:"email" mask:"email"`
+		Phone string `json:"phone" mask:"-"`
+	}
+	type Audit struct {
+		Contact
+		Author Contact `json:"author"`
+	}
+	type Order struct {
+		Audit    Audit   `json:"audit"`
+		Shipping Contact `json:"shipping" mask:"override=none"`
+		Billing  Contact `json:"billing" mask:"override=null"`
+	}
+
+	jm := jsonmask.New()
+	rules := jm.ParseStruct(Order{})
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "audit.email", Action: "email"},
+		{Path: "audit.phone", Action: "-"},
+		{Path: "audit.author.email", Action: "email"},
+		{Path: "audit.author.phone", Action: "-"},
+		{Path: "billing.email", Action: "null"},
+		{Path: "billing.phone", Action: "null"},
+	}, rules.Rules)
+
+	o := Order{
+		Audit:    Audit{Contact: Contact{Email: "a@example.com", Phone: "1"}, Author: Contact{Email: "ann@example.com", Phone: "2"}},
+		Shipping: Contact{Email: "s@example.com", Phone: "3"},
+		Billing:  Contact{Email: "b@example.com", Phone: "4"},
+	}
+	result, err := jm.MaskValue(o)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"audit":{"email":"a@e******.com","author":{"email":"a*n@e******.com"}},"shipping":{"email":"s@example.com","phone":"3"},"billing":{"email":null,"phone":null}}`, string(result))
+
+	assert.NoError(t, jm.MaskStruct(&o))
+	assert.Equal(t, Order{
+		Audit:    Audit{Contact: Contact{Email: "a@e******.com"}, Author: Contact{Email: "a*n@e******.com"}},
+		Shipping: Contact{Email: "s@example.com", Phone: "3"},
+	}, o)
+}
+
+func TestMask_ArrayPaths(t *testing.T) {
+	jm := jsonmask.New()
+
+	t.Run("MissingArray", func(t *testing.T) {
+		result, err := jm.Mask([]byte(`{"items":null}`), jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "items.#.currency", Action: "upper"}, {Path: "missing", Action: "null"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"items":null}`, string(result))
+	})
+
+	t.Run("DeleteElements", func(t *testing.T) {
+		result, err := jm.Mask([]byte(`{"items":[1,2,3],"matrix":[[1,2],[3]]}`), jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "items.#", Action: "-"}, {Path: "matrix.#.#", Action: "-"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"items":[],"matrix":[[],[]]}`, string(result))
+	})
+
+	t.Run("RootArray", func(t *testing.T) {
+		result, err := jm.Mask([]byte(`[{"currency":"usd"},{"currency":"eur"}]`), jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "#.currency", Action: "upper"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `[{"currency":"USD"},{"currency":"EUR"}]`, string(result))
+	})
+
+	t.Run("MissingAttributes", func(t *testing.T) {
+		// attributes missing in some elements are neither created nor reported as errors
+		result, err := jm.Mask([]byte(`{"items":[{"currency":"usd"},{}],"id":1}`), jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "items.#.currency", Action: "upper"}, {Path: "name", Action: "upper"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"items":[{"currency":"USD"},{}],"id":1}`, string(result))
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		data := []byte(`{"items":[{"type":"card","number":"4111"},{"type":"cash","number":"1"},{"type":"card","number":"5500"}]}`)
+
+		result, err := jm.Mask(data, jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: `items.#(type=="card")#.number`, Action: "null"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"items":[{"type":"card","number":null},{"type":"cash","number":"1"},{"type":"card","number":null}]}`, string(result))
+
+		result, err = jm.Mask(data, jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: `items.#(type=="card").number`, Action: "null"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"items":[{"type":"card","number":null},{"type":"cash","number":"1"},{"type":"card","number":"5500"}]}`, string(result))
+	})
+
+	t.Run("NestedQuery", func(t *testing.T) {
+		result, err := jm.Mask([]byte(`{"orders":[{"lines":[{"kind":"a.b","v":1},{"kind":"c","v":2}]}]}`), jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: `orders.#.lines.#(kind=="a.b")#`, Action: "-"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"orders":[{"lines":[{"kind":"c","v":2}]}]}`, string(result))
+	})
+
+	t.Run("NegativeIndex", func(t *testing.T) {
+		result, err := jm.Mask([]byte(`{"history":[{"secret":"a"},{"secret":"b"},{"secret":"c"}],"empty":[],"obj":{"-1":{"secret":"d"}}}`), jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{
+				{Path: "history.-1.secret", Action: "upper"},
+				{Path: "history.-3.secret", Action: "upper"},
+				{Path: "history.-4.secret", Action: "null"},
+				{Path: "empty.-1", Action: "null"},
+				{Path: "obj.-1.secret", Action: "upper"},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"history":[{"secret":"A"},{"secret":"b"},{"secret":"C"}],"empty":[],"obj":{"-1":{"secret":"D"}}}`, string(result))
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		data := []byte(`{"tx":[{"c":"a"},{"c":"b"},{"c":"c"},{"c":"d"}]}`)
+
+		tests := []struct {
+			path     string
+			expected string
+		}{
+			{"tx.[1:].c", `{"tx":[{"c":"a"},{"c":"B"},{"c":"C"},{"c":"D"}]}`},
+			{"tx.[:2].c", `{"tx":[{"c":"A"},{"c":"B"},{"c":"c"},{"c":"d"}]}`},
+			{"tx.[1:-1].c", `{"tx":[{"c":"a"},{"c":"B"},{"c":"C"},{"c":"d"}]}`},
+			{"tx.[-2:].c", `{"tx":[{"c":"a"},{"c":"b"},{"c":"C"},{"c":"D"}]}`},
+			{"tx.[3:1].c", `{"tx":[{"c":"a"},{"c":"b"},{"c":"c"},{"c":"d"}]}`},
+			{"tx.[:10].c", `{"tx":[{"c":"A"},{"c":"B"},{"c":"C"},{"c":"D"}]}`},
+		}
+
+		for _, tt := range tests {
+			result, err := jm.Mask(data, jsonmask.StructMaskRules{
+				Rules: []jsonmask.Rule{{Path: tt.path, Action: "upper"}},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result), tt.path)
+		}
+
+		result, err := jm.Mask(data, jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "tx.[2:]", Action: "-"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"tx":[{"c":"a"},{"c":"b"}]}`, string(result))
+	})
+
+	t.Run("Wildcard", func(t *testing.T) {
+		result, err := jm.Mask([]byte(`{"contacts":{"home":{"email":"a@x"},"work":{"email":"b@x"},"list":[{"email":"c@x"}]}}`), jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "contacts.*.email", Action: "upper"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"contacts":{"home":{"email":"A@X"},"work":{"email":"B@X"},"list":[{"email":"c@x"}]}}`, string(result))
+	})
+
+	t.Run("DeepWildcard", func(t *testing.T) {
+		data := []byte(`{"cardNumber":"1","a":{"cardNumber":"2","b":[{"cardNumber":"3"},{"c":{"cardNumber":{"cardNumber":"4"}}}]},"x.y":{"cardNumber":"5"}}`)
+
+		result, err := jm.Mask(data, jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "**.cardNumber", Action: "-"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a":{"b":[{},{"c":{}}]},"x.y":{}}`, string(result))
+
+		result, err = jm.Mask(data, jsonmask.StructMaskRules{
+			Rules: []jsonmask.Rule{{Path: "a.**.**.cardNumber", Action: "null"}, {Path: "x\\.y.**", Action: "null"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"cardNumber":"1","a":{"cardNumber":null,"b":[{"cardNumber":null},{"c":{"cardNumber":null}}]},"x.y":null}`, string(result))
+	})
+}
+
+func TestJsonMaskerImpl_WithMaskStyle(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithMaskStyle(jsonmask.MaskStyle{Char: '•', Ellipsis: "…"}))
+
+	result, err := jm.Mask([]byte(`{"email":"john@example.com","card":"4111111111111111","passport":"AB1234567"}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "email"},
+			{Path: "card", Action: "first4"},
+			{Path: "passport", Action: "passport"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"email":"j••n@e••••••.com","card":"4111…","passport":"AB•••••67"}`, string(result))
+}
+
+func TestJsonMaskerImpl_AddStringFunc(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddStringFunc("redactQuotes", func(s string) string {
+		return strings.ReplaceAll(s, `"`, "'")
+	})
+
+	result, err := jm.Mask([]byte(`{"note":"say \"hi\"","count":2}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "note", Action: "redactQuotes"},
+			{Path: "count", Action: "redactQuotes"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"note":"say 'hi'","count":2}`, string(result))
+}
+
+func TestMask_BatchedValues(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddFunc("wrap", func(s string) []byte { return []byte(`[` + s + `]`) })
+
+	data := []byte(`{"items":[{"n":"ab","m":{"n":1}},{"n":"abcdef"},{"x":1}],"n":true}`)
+
+	result, err := jm.Mask(data, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "items.#.n", Action: "null"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":[{"n":null,"m":{"n":1}},{"n":null},{"x":1}],"n":true}`, string(result))
+
+	// values at any depth
+	result, err = jm.Mask(data, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "**.n", Action: "wrap"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":[{"n":["ab"],"m":{"n":[1]}},{"n":["abcdef"]},{"x":1}],"n":[true]}`, string(result))
+
+	// values nested in each other are masked one by one
+	result, err = jm.Mask([]byte(`{"a":{"b":1}}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a.**", Action: "wrap"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":[{"b":[1]}]}`, string(result))
+}
+
+func TestJsonMaskerImpl_MaskChanged(t *testing.T) {
+	jm := jsonmask.New()
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "null"}}}
+
+	result, changed, err := jm.MaskChanged([]byte(`{"email":"john@example.com"}`), smr)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, `{"email":null}`, string(result))
+
+	for _, data := range []string{`{"name":"john"}`, `{"email":null}`} {
+		result, changed, err = jm.MaskChanged([]byte(data), smr)
+		assert.NoError(t, err)
+		assert.False(t, changed, data)
+		assert.Equal(t, data, string(result))
+	}
+}
+
+func TestJsonMaskerImpl_WithSeed(t *testing.T) {
+	data := []byte(`{"id":"a","card":"4111-1111-1111-1111","count":1000,"born":"1990-06-01","note":"x","old":"y"}`)
+	smr := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "id", Action: "uuid"},
+			{Path: "card", Action: "syntheticCard"},
+			{Path: "count", Action: "laplace(0.01,1)"},
+			{Path: "born", Action: "ageBucket(5)"},
+			{Path: "note", Action: "tombstone(gdpr)"},
+			{Path: "old", Action: "tombstone"},
+		},
+	}
+
+	mask := func(seed int64) string {
+		result, err := jsonmask.New(jsonmask.WithSeed(seed)).Mask(data, smr)
+		assert.NoError(t, err)
+		return string(result)
+	}
+
+	first := mask(1)
+	assert.Equal(t, first, mask(1))
+	assert.NotEqual(t, first, mask(2))
+	assert.Regexp(t, `^{"id":"[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}","card":"4111-11\d\d-\d{4}-\d{4}"`, first)
+	assert.Contains(t, first, `"born":"30-34","note":{"erased":true,"at":"2024-01-01T00:00:00Z","policy":"gdpr"},"old":{"erased":true,"at":"2024-01-01T00:00:00Z"}}`)
+}
+
+func TestJsonMaskerImpl_WithHashKey(t *testing.T) {
+	data := []byte(`{"code":"AB-1234-cd"}`)
+	mask := func(jm *jsonmask.JsonMaskerImpl, action string) string {
+		result, err := jm.Mask(data, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "code", Action: action}}})
+		assert.NoError(t, err)
+		return string(result)
+	}
+
+	jm := jsonmask.New(jsonmask.WithHashKey([]byte("key")))
+	first := mask(jm, "scramble")
+	assert.Regexp(t, `^{"code":"[A-Z]{2}-\d{4}-[a-z]{2}"}$`, first)
+	assert.Equal(t, first, mask(jm, "scramble"))
+	assert.NotEqual(t, first, mask(jsonmask.New(jsonmask.WithHashKey([]byte("other"))), "scramble"))
+	assert.Equal(t, mask(jm, "scramble(1)"), mask(jm, "scramble(1)"))
+
+	assert.Regexp(t, `^{"code":"[A-Z][a-z]+ [A-Z][a-z]+"}$`, mask(jm, "fakeName"))
+	assert.Regexp(t, `^{"code":"[a-z]+\.[a-z]+\d+@example\.(com|org|net)"}$`, mask(jm, "fakeEmail"))
+
+	// without the key, keyed maskers aren't registered
+	for _, action := range []string{"scramble", "syntheticCardHash", "fakeName", "fakeEmail", "uuidHash"} {
+		assert.Equal(t, string(data), mask(jsonmask.New(), action), action)
+	}
+	assert.Panics(t, func() { jsonmask.WithHashKey(nil) })
+}
+
+func TestJsonMaskerImpl_WithValidOutput(t *testing.T) {
+	broken := func(s string) []byte {
+		if strings.Contains(s, "bad") {
+			return []byte(`"unterminated`)
+		}
+		return []byte(`"ok"`)
+	}
+	data := []byte(`{"items":[{"v":"bad"},{"v":"good"},{"v":"bad"}],"note":"bad","obj":{"v":1}}`)
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "items.#.v", Action: "broken"},
+		{Path: "note", Action: "broken"},
+	}}
+
+	jm := jsonmask.New(jsonmask.WithValidOutput(jsonmask.InvalidOutputDelete))
+	jm.AddFunc("broken", broken)
+	result, err := jm.Mask(data, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":[{},{"v":"ok"},{}],"obj":{"v":1}}`, string(result))
+
+	// nested values are masked one by one
+	result, err = jm.Mask(data, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "items.#.v", Action: "broken"},
+		{Path: "items.#", Action: "broken"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":["ok","ok","ok"],"note":"bad","obj":{"v":1}}`, string(result))
+
+	result, err = jm.Mask([]byte(`"bad"`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "@this", Action: "broken"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `null`, string(result))
+
+	jm = jsonmask.New(jsonmask.WithValidOutput(jsonmask.InvalidOutputError))
+	jm.AddFunc("broken", broken)
+	_, err = jm.Mask(data, smr)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidOutput)
+
+	jm = jsonmask.New(
+		jsonmask.WithValidOutput(jsonmask.InvalidOutputDelete),
+		jsonmask.WithPostMaskHook(func(data []byte) ([]byte, error) { return data[1:], nil }),
+	)
+	_, err = jm.Mask(data, smr)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidOutput)
+
+	// without the option invalid values are written as is
+	jm = jsonmask.New()
+	jm.AddFunc("broken", broken)
+	result, err = jm.Mask([]byte(`{"note":"bad"}`), smr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"note":"unterminated}`, string(result))
+}
+
+func TestJsonMaskerImpl_WithIdempotentMarker(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithIdempotentMarker("_masked", []byte("key")))
+	jm.AddStringFunc("wrap", func(s string) string { return "[" + s + "]" })
+	wrap := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "wrap"}}}
+	upper := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "upper"}, {Path: "*", Action: "truncate"}}}
+
+	once, err := jm.Mask([]byte(`{"name":"john"}`), wrap)
+	assert.NoError(t, err)
+	assert.Equal(t, "[john]", gjson.GetBytes(once, "name").Str)
+	assert.Len(t, gjson.GetBytes(once, "_masked").Array(), 1)
+
+	twice, err := jm.Mask(once, wrap)
+	assert.NoError(t, err)
+	assert.Equal(t, string(once), string(twice))
+
+	// other rules are applied, the marker is kept and extended
+	extended, err := jm.Mask(once, upper)
+	assert.NoError(t, err)
+	assert.Equal(t, "", gjson.GetBytes(extended, "name").Str)
+	assert.Len(t, gjson.GetBytes(extended, "_masked").Array(), 2)
+
+	again, err := jm.Mask(extended, wrap)
+	assert.NoError(t, err)
+	assert.Equal(t, string(extended), string(again))
+
+	// documents other than objects are masked every time
+	arr, err := jm.Mask([]byte(`["john"]`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "0", Action: "wrap"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `["[john]"]`, string(arr))
+
+	// markers copied to other documents or signed by other keys are ignored
+	forged, err := sjson.SetBytes([]byte(`{"name":"jane"}`), "_masked", gjson.GetBytes(once, "_masked").Value())
+	assert.NoError(t, err)
+	result, err := jm.Mask(forged, wrap)
+	assert.NoError(t, err)
+	assert.Equal(t, "[jane]", gjson.GetBytes(result, "name").Str)
+	assert.Len(t, gjson.GetBytes(result, "_masked").Array(), 1)
+
+	other := jsonmask.New(jsonmask.WithIdempotentMarker("_masked", []byte("other")))
+	other.AddStringFunc("wrap", func(s string) string { return "[" + s + "]" })
+	result, err = other.Mask(once, wrap)
+	assert.NoError(t, err)
+	assert.Equal(t, "[[john]]", gjson.GetBytes(result, "name").Str)
+
+	// formatting changes keep the marker valid
+	result, err = jm.Mask(append([]byte("  "), once...), wrap)
+	assert.NoError(t, err)
+	assert.Equal(t, "[john]", gjson.GetBytes(result, "name").Str)
+
+	assert.Panics(t, func() { jsonmask.WithIdempotentMarker("_masked", nil) })
+}
+
+func TestJsonMaskerImpl_Sample(t *testing.T) {
+	jm := jsonmask.New()
+
+	var sb strings.Builder
+	sb.WriteString(`{"items":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`{"id":` + strconv.Itoa(i) + `,"v":"value-` + strconv.Itoa(i) + `"}`)
+	}
+	sb.WriteString(`]}`)
+	data := []byte(sb.String())
+
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "items.#.v", Action: "null", Sample: 0.3}}}
+	result, err := jm.Mask(data, smr)
+	assert.NoError(t, err)
+
+	masked := 0
+	for _, v := range gjson.GetBytes(result, "items.#.v").Array() {
+		if v.Type == gjson.Null {
+			masked++
+		}
+	}
+	assert.InDelta(t, 300, masked, 50)
+
+	again, err := jm.Mask(data, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, string(result), string(again), "sampling is deterministic")
+
+	// deletion is sampled too
+	smr.Rules[0].Action = "-"
+	result, err = jm.Mask(data, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000-masked, len(gjson.GetBytes(result, "items.#.v").Array()))
+
+	type Record struct {
+		Name string `json:"name" mask:"null,sample=0.5"`
+	}
+	rules := jm.ParseStruct(Record{})
+	assert.Equal(t, []jsonmask.Rule{{Path: "name", Action: "null", Sample: 0.5}}, rules.Rules)
+}
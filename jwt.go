@@ -0,0 +1,41 @@
+package jsonmask
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// MaskedJWTSignature replaces the signature of tokens masked by MaskJWTClaims,
+// so nobody mistakes them for valid ones.
+const MaskedJWTSignature = "masked-signature-invalid"
+
+// MaskJWTClaims masks claims of the JWS compact serialized token by rules and
+// returns the token with the payload re-encoded and the signature replaced with
+// MaskedJWTSignature, e.g. for debug traces. The header is kept as is.
+// Encrypted tokens (JWE) are not supported.
+func (jm *JsonMaskerImpl) MaskJWTClaims(token string, smr StructMaskRules) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidJWT
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil || !gjson.ValidBytes(payload) {
+		return "", ErrInvalidJWT
+	}
+
+	masked, err := jm.Mask(payload, smr)
+	if err != nil {
+		return "", err
+	}
+
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(masked) + "." + MaskedJWTSignature, nil
+}
+
+// Error definitions
+var (
+	ErrInvalidJWT = errors.New("invalid jwt")
+)
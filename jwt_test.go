@@ -0,0 +1,36 @@
+package jsonmask_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskJWTClaims(t *testing.T) {
+	jm := jsonmask.New()
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}, {Path: "name", Action: "-"}}}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"42","email":"john@example.com","name":"John"}`))
+
+	masked, err := jm.MaskJWTClaims(header+"."+payload+".c2lnbmF0dXJl", smr)
+	assert.NoError(t, err)
+
+	parts := strings.Split(masked, ".")
+	assert.Len(t, parts, 3)
+	assert.Equal(t, header, parts[0])
+	assert.Equal(t, jsonmask.MaskedJWTSignature, parts[2])
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, err)
+	assert.Equal(t, `{"sub":"42","email":"j**n@e******.com"}`, string(claims))
+
+	_, err = jm.MaskJWTClaims("a.b.c.d.e", smr)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidJWT)
+	_, err = jm.MaskJWTClaims(header+".!!!.sig", smr)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidJWT)
+	_, err = jm.MaskJWTClaims(header+"."+base64.RawURLEncoding.EncodeToString([]byte("{"))+".sig", smr)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidJWT)
+}
@@ -0,0 +1,167 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// QuasiIdentifier is an attribute identifying individuals when combined with
+// others, e.g. age, zip code or city, with actions generalizing its values.
+type QuasiIdentifier struct {
+	// Path is a JSON path to the attribute in every document, without selectors.
+	Path string
+
+	// Levels hold actions generalizing the original value, from the least to
+	// the most general, e.g. "round(5)", "round(10)", "ageBucket".
+	Levels []string
+}
+
+// KAnonymity holds parameters of KAnonymize.
+type KAnonymity struct {
+	// K is the minimal number of documents sharing every combination of
+	// quasi-identifier values.
+	K int
+
+	QuasiIdentifiers []QuasiIdentifier
+
+	// MaxSuppression is the share of documents, in [0, 1], allowed to have
+	// quasi-identifiers suppressed (set to null) instead of generalizing all
+	// documents further.
+	MaxSuppression float64
+}
+
+// KAnonymityReport describes generalization made by KAnonymize.
+type KAnonymityReport struct {
+	// Levels holds the number of generalization levels applied by path,
+	// 0 means original values are kept.
+	Levels map[string]int
+
+	// Classes is the number of distinct combinations of quasi-identifier
+	// values among documents not suppressed.
+	Classes int
+
+	// Suppressed holds indexes of documents with suppressed quasi-identifiers.
+	Suppressed []int
+}
+
+// KAnonymize generalizes quasi-identifiers of the batch of documents, e.g. rows
+// of a research dataset, until every combination of their values is shared by
+// at least K documents, using the greedy Datafly algorithm: the quasi-identifier
+// with most distinct values is generalized a level further while documents
+// breaking K-anonymity exceed the suppression limit. Documents are returned in
+// the same order. Documents missing a quasi-identifier share the combination
+// with others missing it.
+func (jm *JsonMaskerImpl) KAnonymize(docs [][]byte, cfg KAnonymity) ([][]byte, KAnonymityReport, error) {
+	report := KAnonymityReport{Levels: make(map[string]int)}
+	if cfg.K <= 0 {
+		return nil, report, errors.New("k must be positive")
+	}
+
+	// generalized[q][l][d] holds raw value of the quasi-identifier q of the document d at the level l.
+	generalized := make([][][]string, len(cfg.QuasiIdentifiers))
+	for q, qi := range cfg.QuasiIdentifiers {
+		levels := make([][]string, len(qi.Levels)+1)
+		levels[0] = make([]string, len(docs))
+		for d, doc := range docs {
+			if !gjson.ValidBytes(doc) {
+				return nil, report, fmt.Errorf("%w: document %d", ErrInvalidJSON, d)
+			}
+			levels[0][d] = gjson.GetBytes(doc, qi.Path).Raw
+		}
+
+		for l, action := range qi.Levels {
+			f, ok := jm.lookupFunc(action)
+			if !ok {
+				return nil, report, fmt.Errorf("%w: %s", ErrUnknownAction, action)
+			}
+			levels[l+1] = make([]string, len(docs))
+			for d, raw := range levels[0] {
+				if raw != "" {
+					levels[l+1][d] = string(f(raw))
+				}
+			}
+		}
+		generalized[q] = levels
+	}
+
+	level := make([]int, len(cfg.QuasiIdentifiers))
+	var small []int // documents in classes smaller than K
+	for {
+		classes := make(map[string][]int)
+		for d := range docs {
+			var key strings.Builder
+			for q := range generalized {
+				key.WriteString(generalized[q][level[q]][d])
+				key.WriteByte(0)
+			}
+			classes[key.String()] = append(classes[key.String()], d)
+		}
+
+		small = small[:0]
+		report.Classes = 0
+		for _, members := range classes {
+			if len(members) < cfg.K {
+				small = append(small, members...)
+			} else {
+				report.Classes++
+			}
+		}
+		if float64(len(small)) <= cfg.MaxSuppression*float64(len(docs)) {
+			break
+		}
+
+		// generalize the quasi-identifier with most distinct values
+		next, most := -1, 0
+		for q := range generalized {
+			if level[q] == len(generalized[q])-1 {
+				continue
+			}
+			distinct := make(map[string]bool)
+			for _, raw := range generalized[q][level[q]] {
+				distinct[raw] = true
+			}
+			if len(distinct) > most {
+				next, most = q, len(distinct)
+			}
+		}
+		if next < 0 {
+			break // fully generalized, the rest is suppressed
+		}
+		level[next]++
+	}
+
+	suppressed := make(map[int]bool, len(small))
+	for _, d := range small {
+		suppressed[d] = true
+	}
+
+	res := make([][]byte, len(docs))
+	for d, doc := range docs {
+		var err error
+		for q, qi := range cfg.QuasiIdentifiers {
+			raw := generalized[q][level[q]][d]
+			if raw == "" || (level[q] == 0 && !suppressed[d]) {
+				continue // missing or kept
+			}
+			if suppressed[d] {
+				raw = "null"
+			}
+			if doc, err = sjson.SetRawBytes(doc, qi.Path, []byte(raw)); err != nil {
+				return nil, report, err
+			}
+		}
+		res[d] = doc
+		if suppressed[d] {
+			report.Suppressed = append(report.Suppressed, d)
+		}
+	}
+
+	for q, qi := range cfg.QuasiIdentifiers {
+		report.Levels[qi.Path] = level[q]
+	}
+	return res, report, nil
+}
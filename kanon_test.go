@@ -0,0 +1,75 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_KAnonymize(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddFunc("zip3", jsonmask.PrefixFn(3, false))
+	jm.AddFunc("zip1", jsonmask.PrefixFn(1, false))
+
+	docs := [][]byte{
+		[]byte(`{"age":31,"zip":"12345","diagnosis":"flu"}`),
+		[]byte(`{"age":34,"zip":"12399","diagnosis":"cold"}`),
+		[]byte(`{"age":38,"zip":"12311","diagnosis":"flu"}`),
+		[]byte(`{"age":52,"zip":"54321","diagnosis":"asthma"}`),
+		[]byte(`{"age":57,"zip":"54388","diagnosis":"flu"}`),
+		[]byte(`{"age":55,"zip":"54300","diagnosis":"cold"}`),
+		[]byte(`{"age":90,"zip":"99999","diagnosis":"rare"}`),
+	}
+	cfg := jsonmask.KAnonymity{
+		K: 3,
+		QuasiIdentifiers: []jsonmask.QuasiIdentifier{
+			{Path: "age", Levels: []string{"round(10)", "round(100)"}},
+			{Path: "zip", Levels: []string{"zip3", "zip1"}},
+		},
+		MaxSuppression: 0.2,
+	}
+
+	res, report, err := jm.KAnonymize(docs, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"age":0,"zip":"123","diagnosis":"flu"}`,
+		`{"age":0,"zip":"123","diagnosis":"cold"}`,
+		`{"age":0,"zip":"123","diagnosis":"flu"}`,
+		`{"age":100,"zip":"543","diagnosis":"asthma"}`,
+		`{"age":100,"zip":"543","diagnosis":"flu"}`,
+		`{"age":100,"zip":"543","diagnosis":"cold"}`,
+		`{"age":null,"zip":null,"diagnosis":"rare"}`,
+	}, toStrings(res))
+	assert.Equal(t, jsonmask.KAnonymityReport{
+		Levels:     map[string]int{"age": 2, "zip": 1},
+		Classes:    2,
+		Suppressed: []int{6},
+	}, report)
+
+	// outliers beyond the last level are suppressed anyway
+	cfg.MaxSuppression = 0
+	_, report, err = jm.KAnonymize(docs, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"age": 2, "zip": 2}, report.Levels)
+	assert.Equal(t, []int{6}, report.Suppressed)
+
+	// already anonymous data is kept as is
+	res, report, err = jm.KAnonymize(docs, jsonmask.KAnonymity{K: 1, QuasiIdentifiers: cfg.QuasiIdentifiers})
+	assert.NoError(t, err)
+	assert.Equal(t, docs, res)
+	assert.Equal(t, 7, report.Classes)
+
+	_, _, err = jm.KAnonymize(docs, jsonmask.KAnonymity{K: 2, QuasiIdentifiers: []jsonmask.QuasiIdentifier{{Path: "age", Levels: []string{"nope"}}}})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+	_, _, err = jm.KAnonymize([][]byte{[]byte(`{`)}, cfg)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidJSON)
+}
+
+func toStrings(docs [][]byte) []string {
+	res := make([]string, len(docs))
+	for i, doc := range docs {
+		res[i] = string(doc)
+	}
+	return res
+}
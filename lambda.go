@@ -0,0 +1,149 @@
+package jsonmask
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// LambdaRules holds rules of AWS Lambda event scrubbing by MaskLambdaEvent.
+type LambdaRules struct {
+	Body StructMaskRules // rules of JSON bodies and headers
+
+	// Headers, QueryParams and Cookies hold names of headers (case-insensitive),
+	// query parameters and cookies whose values are masked by the Action.
+	Headers     []string
+	QueryParams []string
+	Cookies     []string
+
+	// Action masks values of headers, query parameters and cookies,
+	// "truncate" by default. Results other than strings are converted to strings.
+	Action string
+}
+
+// MaskLambdaEvent masks the Lambda event of API Gateway REST (v1) and HTTP (v2)
+// proxy integrations or ALB targets, and the proxy response, for safe event
+// logging in serverless functions: the JSON body, base64 encoded if
+// isBase64Encoded is set, and values of configured headers, multi-value ones
+// included, query parameters, also in rawQueryString, and cookies of v2 events.
+// Bodies not being JSON are kept as is.
+func (jm *JsonMaskerImpl) MaskLambdaEvent(event []byte, rules LambdaRules) ([]byte, error) {
+	if jm.disabled {
+		return event, nil
+	}
+	if !gjson.ValidBytes(event) {
+		return nil, ErrInvalidJSON
+	}
+
+	maskValue, err := jm.stringMasker(rules.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	if event, err = jm.maskLambdaBody(event, rules.Body); err != nil {
+		return nil, err
+	}
+
+	params := []struct {
+		attr string
+		mask pairMasker
+	}{
+		{"headers", jm.headerMasker(rules.Body.Headers)},
+		{"multiValueHeaders", jm.headerMasker(rules.Body.Headers)},
+		{"headers", namesMasker(rules.Headers, true, maskValue)},
+		{"multiValueHeaders", namesMasker(rules.Headers, true, maskValue)},
+		{"queryStringParameters", namesMasker(rules.QueryParams, false, maskValue)},
+		{"multiValueQueryStringParameters", namesMasker(rules.QueryParams, false, maskValue)},
+	}
+	for _, p := range params {
+		if event, err = maskLambdaParams(event, p.attr, p.mask); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw := gjson.GetBytes(event, "rawQueryString"); raw.Str != "" && len(rules.QueryParams) > 0 {
+		query := strings.TrimPrefix(maskURLQuery("?"+raw.Str, rules.QueryParams, maskValue), "?")
+		if event, err = sjson.SetBytes(event, "rawQueryString", query); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rules.Cookies) > 0 {
+		for i, cookie := range gjson.GetBytes(event, "cookies").Array() {
+			name, value, found := strings.Cut(cookie.Str, "=")
+			if !found || !hasName(rules.Cookies, name, false) {
+				continue
+			}
+			if event, err = sjson.SetBytes(event, "cookies."+strconv.Itoa(i), name+"="+maskValue(value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return event, nil
+}
+
+// maskLambdaBody masks the JSON body of the event or response.
+func (jm *JsonMaskerImpl) maskLambdaBody(event []byte, smr StructMaskRules) ([]byte, error) {
+	body := gjson.GetBytes(event, "body")
+	if len(smr.Rules) == 0 || body.Type != gjson.String {
+		return event, nil
+	}
+
+	data := []byte(body.Str)
+	encoded := gjson.GetBytes(event, "isBase64Encoded").Bool()
+	if encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body.Str)
+		if err != nil {
+			return event, nil
+		}
+		data = decoded
+	}
+	if !gjson.ValidBytes(data) {
+		return event, nil
+	}
+
+	masked, err := jm.Mask(data, smr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := string(masked)
+	if encoded {
+		res = base64.StdEncoding.EncodeToString(masked)
+	}
+	return sjson.SetBytes(event, "body", res)
+}
+
+// maskLambdaParams masks values of the object found by the attribute. Values
+// are strings or, for multi-value attributes, arrays of strings.
+func maskLambdaParams(event []byte, attr string, mask pairMasker) ([]byte, error) {
+	var err error
+	gjson.GetBytes(event, attr).ForEach(func(key, value gjson.Result) bool {
+		path := attr + "." + pathEscaper.Replace(key.Str)
+		if !value.IsArray() {
+			var masked string
+			var ok bool
+			if masked, ok, err = mask(key.Str, value.Str); ok {
+				event, err = sjson.SetBytes(event, path, masked)
+			}
+			return err == nil
+		}
+
+		values := make([]string, 0, len(value.Array()))
+		for _, v := range value.Array() {
+			masked, ok, maskErr := mask(key.Str, v.Str)
+			if maskErr != nil || !ok {
+				err = maskErr
+				return err == nil
+			}
+			values = append(values, masked)
+		}
+		event, err = sjson.SetBytes(event, path, values)
+		return err == nil
+	})
+	return event, err
+}
@@ -0,0 +1,63 @@
+package jsonmask_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskLambdaEvent(t *testing.T) {
+	jm := jsonmask.New()
+	rules := jsonmask.LambdaRules{
+		Body:        jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "password", Action: "-"}}},
+		Headers:     []string{"authorization"},
+		QueryParams: []string{"token"},
+		Cookies:     []string{"session"},
+	}
+
+	tests := []struct {
+		name, event, expected string
+	}{
+		{
+			"api gateway v1",
+			`{"resource":"/login","httpMethod":"POST",
+				"headers":{"Authorization":"Bearer abc","Accept":"*/*"},
+				"multiValueHeaders":{"Authorization":["Bearer abc"],"Accept":["*/*"]},
+				"queryStringParameters":{"token":"t0k","page":"1"},
+				"multiValueQueryStringParameters":{"token":["t0k"],"page":["1"]},
+				"body":"{\"login\":\"john\",\"password\":\"secret\"}","isBase64Encoded":false}`,
+			`{"resource":"/login","httpMethod":"POST",
+				"headers":{"Authorization":"","Accept":"*/*"},
+				"multiValueHeaders":{"Authorization":[""],"Accept":["*/*"]},
+				"queryStringParameters":{"token":"","page":"1"},
+				"multiValueQueryStringParameters":{"token":[""],"page":["1"]},
+				"body":"{\"login\":\"john\"}","isBase64Encoded":false}`,
+		},
+		{
+			"api gateway v2",
+			`{"version":"2.0","rawPath":"/login","rawQueryString":"token=t0k&page=1",
+				"cookies":["session=s3cr3t","theme=dark"],"headers":{"authorization":"Bearer abc"},
+				"body":"` + base64.StdEncoding.EncodeToString([]byte(`{"password":"secret"}`)) + `","isBase64Encoded":true}`,
+			`{"version":"2.0","rawPath":"/login","rawQueryString":"token=&page=1",
+				"cookies":["session=","theme=dark"],"headers":{"authorization":""},
+				"body":"` + base64.StdEncoding.EncodeToString([]byte(`{}`)) + `","isBase64Encoded":true}`,
+		},
+		{
+			"alb response with text body",
+			`{"statusCode":200,"headers":{"Content-Type":"text/plain"},"body":"password=secret"}`,
+			`{"statusCode":200,"headers":{"Content-Type":"text/plain"},"body":"password=secret"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := jm.MaskLambdaEvent([]byte(tt.event), rules)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(res))
+		})
+	}
+
+	_, err := jm.MaskLambdaEvent([]byte(`{}`), jsonmask.LambdaRules{Action: "unknown"})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+}
@@ -0,0 +1,95 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RuleLimits caps the complexity of rule sets registered by AddRules, so an
+// enormous generated rule set is rejected up front instead of degrading every
+// Mask call. Zero values mean no limit.
+type RuleLimits struct {
+	// MaxRules is the max number of rules of a rule set.
+	MaxRules int
+
+	// MaxWildcards is the max number of selectors of a path fanning out to
+	// several values, e.g. "#", "*", "**", "[1:]" or "#(query)#".
+	MaxWildcards int
+
+	// MaxDepth is the max number of segments of a path, e.g. 3 for "a.#.b".
+	MaxDepth int
+}
+
+// WithRuleLimits makes AddRules reject rule sets exceeding the limits with
+// ErrRuleLimit. Rule sets of WithConfig breaking the limits are skipped and
+// logged, so the option should precede WithConfig.
+func WithRuleLimits(l RuleLimits) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.ruleLimits = l
+	}
+}
+
+// checkLimits returns ErrRuleLimit if the rule set exceeds limits of the masker.
+func (jm *JsonMaskerImpl) checkLimits(smr StructMaskRules) error {
+	l := jm.ruleLimits
+	if l.MaxRules > 0 && len(smr.Rules) > l.MaxRules {
+		return fmt.Errorf("%w: %d rules, max %d", ErrRuleLimit, len(smr.Rules), l.MaxRules)
+	}
+	if l.MaxWildcards <= 0 && l.MaxDepth <= 0 {
+		return nil
+	}
+
+	for _, rule := range smr.Rules {
+		path, _ := cutModifierPath(strings.TrimPrefix(rule.Path, "!"))
+		if n := pathWildcards(path); l.MaxWildcards > 0 && n > l.MaxWildcards {
+			return fmt.Errorf("%w: %s has %d wildcards, max %d", ErrRuleLimit, rule.Path, n, l.MaxWildcards)
+		}
+		if n := pathDepth(path); l.MaxDepth > 0 && n > l.MaxDepth {
+			return fmt.Errorf("%w: %s has depth %d, max %d", ErrRuleLimit, rule.Path, n, l.MaxDepth)
+		}
+	}
+	return nil
+}
+
+// pathWildcards returns the number of selectors of the path selecting several values.
+func pathWildcards(path string) int {
+	n := 0
+	for {
+		_, selector, itemPath, found := cutSelectorPath(path)
+		if !found {
+			return n
+		}
+		single := selector[0] == '-' || (strings.HasPrefix(selector, "#(") && !strings.HasSuffix(selector, ")#"))
+		if !single {
+			n++
+		}
+		path = strings.TrimPrefix(itemPath, ".")
+	}
+}
+
+// pathDepth returns the number of segments of the path.
+func pathDepth(path string) int {
+	if path == "" {
+		return 0
+	}
+	n := 1
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++ // skip escaped character
+		case '.':
+			n++
+		case '(':
+			if end := strings.IndexByte(path[i:], ')'); end > 0 {
+				i += end // dots of queries don't nest
+			}
+		}
+	}
+	return n
+}
+
+// Error definitions
+var (
+	ErrRuleLimit = errors.New("rule set exceeds limits")
+)
@@ -0,0 +1,45 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRuleLimits(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithRuleLimits(jsonmask.RuleLimits{MaxRules: 3, MaxWildcards: 2, MaxDepth: 4}))
+
+	ok := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "orders.#.items.#", Action: "null"},
+		{Path: `orders.#(status=="a.b.c")#.note`, Action: "-"},
+		{Path: "orders.-1.id", Action: "zero"},
+	}}
+	assert.NoError(t, jm.AddRules("ok", ok))
+	_, found := jm.Rules("ok")
+	assert.True(t, found)
+
+	tests := map[string]jsonmask.Rule{
+		"wildcards": {Path: "a.*.b.#.c.[1:]", Action: "null"},
+		"depth":     {Path: "a.b.c.d.e", Action: "null"},
+		"any depth": {Path: "**.x.**.y.*", Action: "null"},
+	}
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := jm.AddRules(name, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{rule}})
+			assert.ErrorIs(t, err, jsonmask.ErrRuleLimit)
+			_, found := jm.Rules(name)
+			assert.False(t, found)
+		})
+	}
+
+	tooMany := jsonmask.StructMaskRules{Rules: append(ok.Rules, jsonmask.Rule{Path: "x", Action: "null"})}
+	assert.ErrorIs(t, jm.AddRules("many", tooMany), jsonmask.ErrRuleLimit)
+
+	cfg := jsonmask.Config{Rules: map[string]jsonmask.StructMaskRules{"many": tooMany}}
+	jm = jsonmask.New(jsonmask.WithRuleLimits(jsonmask.RuleLimits{MaxRules: 3}), jsonmask.WithConfig(cfg))
+	assert.ErrorIs(t, jm.SelfCheck(), jsonmask.ErrRuleLimit)
+
+	tenant := jsonmask.NewManager(jsonmask.WithRuleLimits(jsonmask.RuleLimits{MaxRules: 3})).Tenant("acme")
+	assert.ErrorIs(t, tenant.AddRules("many", tooMany), jsonmask.ErrRuleLimit)
+}
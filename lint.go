@@ -0,0 +1,177 @@
+package jsonmask
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintCode identifies a kind of rule problem found by Lint.
+type LintCode string
+
+// Problems found by Lint.
+const (
+	LintUnknownAction LintCode = "unknown_action" // action not registered
+	LintInvalidPath   LintCode = "invalid_path"   // path syntax error
+	LintUnreachable   LintCode = "unreachable"    // path inside a subtree deleted by a preceding rule
+	LintDuplicatePath LintCode = "duplicate_path" // path of a preceding rule repeated
+	LintFailOpen      LintCode = "fail_open"      // masking skipped unless the document asks for it
+)
+
+// LintFinding describes a problem of a rule.
+type LintFinding struct {
+	Code    LintCode
+	Index   int // index of the rule
+	Rule    Rule
+	Message string
+}
+
+// String returns the finding in a human-readable form.
+func (f LintFinding) String() string {
+	return fmt.Sprintf("rule %d (%s: %s): %s", f.Index, f.Rule.Path, f.Rule.Action, f.Message)
+}
+
+// Lint checks rules for unknown actions, syntactically invalid paths, rules
+// unreachable because a preceding rule deletes their subtree and duplicate paths,
+// e.g. in CI tests of rule sets loaded from configuration. Actions are known if
+// they are listed in registeredFuncs, parametrized actions "name(arg)" by the name,
+// chained actions "a|b" if every action is known.
+// Modifiers are checked against DefaultModifiers.
+//
+// Masking rules with conditions not negated, e.g. `consents.marketing==false`,
+// are reported as fail-open: they leave values unmasked when the flag is missing
+// or forged. Rules with conditions or sampling don't make other rules
+// unreachable or duplicate.
+func Lint(rules []Rule, registeredFuncs []string) []LintFinding {
+	known := make(map[string]bool, len(registeredFuncs))
+	for _, name := range registeredFuncs {
+		known[name] = true
+	}
+	modifiers := make(map[string]bool, len(DefaultModifiers))
+	for _, name := range DefaultModifiers {
+		modifiers[name] = true
+	}
+
+	return lintRules(rules, func(action string) bool {
+		for _, a := range splitActions(action) {
+			if name, _, ok := parseAction(a); ok {
+				a = name
+			}
+			if !known[a] {
+				return false
+			}
+		}
+		return true
+	}, (&JsonMaskerImpl{settings: settings{modifiers: modifiers}}).checkModifiers)
+}
+
+// Lint is like the package function Lint but checks actions and modifiers
+// against the ones registered in the masker, including its parents.
+func (jm *JsonMaskerImpl) Lint(rules []Rule) []LintFinding {
+	return lintRules(rules, func(action string) bool {
+		_, ok := jm.lookupFunc(action)
+		return ok
+	}, jm.checkModifiers)
+}
+
+// lintRules checks rules using the functions reporting whether the action is
+// known and whether modifiers of the path are allowed.
+func lintRules(rules []Rule, knownAction func(string) bool, checkModifiers func(string) error) []LintFinding {
+	var (
+		res        []LintFinding
+		deletions  []string
+		exclusions []string
+	)
+	for _, rule := range rules {
+		if isExclusion(rule) {
+			exclusions = append(exclusions, rule.Path[1:])
+		}
+	}
+
+	type pathKey struct {
+		path string
+		keys bool
+	}
+	seen := make(map[pathKey]int)
+
+	for i, rule := range rules {
+		report := func(code LintCode, format string, args ...any) {
+			res = append(res, LintFinding{Code: code, Index: i, Rule: rule, Message: fmt.Sprintf(format, args...)})
+		}
+
+		path := strings.TrimPrefix(rule.Path, "!")
+		if msg := pathSyntaxError(path); msg != "" {
+			report(LintInvalidPath, "%s", msg)
+		} else if err := checkModifiers(path); err != nil {
+			report(LintInvalidPath, "%s", err)
+		}
+
+		if !isExclusion(rule) && rule.Action != "-" && !knownAction(rule.Action) {
+			report(LintUnknownAction, "unknown action %q", rule.Action)
+		}
+		if !isExclusion(rule) && rule.When != "" && !strings.HasPrefix(rule.When, "!") {
+			report(LintFailOpen, "condition %q is not negated, values stay unmasked if the flag is missing or forged", rule.When)
+		}
+
+		key := pathKey{rule.Path, rule.Keys}
+		if conditional := rule.When != "" || rule.Sample != 0; !conditional {
+			if j, ok := seen[key]; ok {
+				report(LintDuplicatePath, "path repeats rule %d", j)
+				continue
+			}
+			seen[key] = i
+		}
+
+		if isExclusion(rule) {
+			continue
+		}
+		if hasPathPrefix(rule.Path, deletions, false) {
+			report(LintUnreachable, "path is deleted by a preceding rule")
+			continue
+		}
+		if isDeletion(rule) && !hasPathPrefix(rule.Path, exclusions, true) {
+			deletions = append(deletions, rule.Path)
+		}
+	}
+
+	return res
+}
+
+// pathSyntaxError returns a description of the syntax error of the rule path,
+// or an empty string if the path is valid. Parts behind modifiers are not checked.
+func pathSyntaxError(path string) string {
+	if path == "" {
+		return "empty path"
+	}
+	path, _ = cutModifierPath(path)
+
+	segment := 0 // start of the current path segment
+	for i := 0; i <= len(path); i++ {
+		if i == len(path) || path[i] == '.' {
+			if i == segment && (i > 0 || len(path) > 0) {
+				return fmt.Sprintf("empty segment at position %d", i)
+			}
+			segment = i + 1
+			continue
+		}
+		if path[i] == '\\' {
+			if i+1 == len(path) {
+				return "dangling escape at the end"
+			}
+			i++
+			continue
+		}
+		// other segments starting like selectors are valid attribute names
+		isQuery := path[i] == '#' && i+1 < len(path) && path[i+1] == '('
+		isRange := path[i] == '[' && strings.Contains(strings.SplitN(path[i:], ".", 2)[0], ":")
+		if i != segment || (!isQuery && !isRange) {
+			continue
+		}
+
+		end := selectorEnd(path, i)
+		if end < 0 || (end < len(path) && path[end] != '.') {
+			return fmt.Sprintf("invalid selector at position %d", i)
+		}
+		i = end - 1
+	}
+	return ""
+}
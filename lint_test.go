@@ -0,0 +1,71 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	rules := []jsonmask.Rule{
+		{Path: "customer", Action: "-"},
+		{Path: "customer.email", Action: "email"},
+		{Path: "items.#(type==\"card\".number", Action: "creditCard"},
+		{Path: "items.[1:x].note", Action: "truncate"},
+		{Path: "a..b", Action: "truncate"},
+		{Path: "name", Action: "nope"},
+		{Path: "name", Action: "truncate"},
+		{Path: "secret", Action: "hmac(v1)"},
+		{Path: "items|@pretty", Action: "truncate"},
+		{Path: "order", Action: "-"},
+		{Path: "!order.id", Action: ""},
+		{Path: "order.id", Action: "truncate"},
+		{Path: "items.#(type==\"card\")#.number", Action: "creditCard"},
+		{Path: "[x].#y", Action: "truncate"},
+		{Path: "email", Action: "email", When: "consents.marketing==false"},
+		{Path: "phone", Action: "truncate", When: "!consents.marketing==true"},
+		{Path: "address", Action: "-", When: "!consents.marketing==true"},
+		{Path: "address.city", Action: "truncate"},
+		{Path: "address", Action: "truncate"},
+		{Path: "notes", Action: "-", Sample: 0.5},
+		{Path: "notes.text", Action: "truncate"},
+	}
+
+	findings := jsonmask.Lint(rules, []string{"email", "creditCard", "truncate", "hmac"})
+
+	type finding struct {
+		code  jsonmask.LintCode
+		index int
+	}
+	var got []finding
+	for _, f := range findings {
+		got = append(got, finding{f.Code, f.Index})
+	}
+	assert.Equal(t, []finding{
+		{jsonmask.LintUnreachable, 1},
+		{jsonmask.LintInvalidPath, 2},
+		{jsonmask.LintInvalidPath, 3},
+		{jsonmask.LintInvalidPath, 4},
+		{jsonmask.LintUnknownAction, 5},
+		{jsonmask.LintDuplicatePath, 6},
+		{jsonmask.LintInvalidPath, 8},
+		{jsonmask.LintFailOpen, 14},
+	}, got)
+	assert.Equal(t, `rule 5 (name: nope): unknown action "nope"`, findings[4].String())
+}
+
+func TestJsonMaskerImpl_Lint(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithModifiers("pretty"))
+	jm.AddFunc("custom", jsonmask.Null)
+
+	findings := jm.Lint([]jsonmask.Rule{
+		{Path: "a", Action: "custom"},
+		{Path: "b", Action: "email"},
+		{Path: "c|@pretty", Action: "truncate"},
+		{Path: "d", Action: "missing"},
+	})
+	assert.Len(t, findings, 1)
+	assert.Equal(t, jsonmask.LintUnknownAction, findings[0].Code)
+	assert.Equal(t, 3, findings[0].Index)
+}
@@ -0,0 +1,35 @@
+package jsonmask
+
+// Logger is a structured logger receiving messages with key-value pairs.
+// It's implemented by *slog.Logger, other loggers can be used via a thin adapter.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// LogLevel is a level of messages sent to Logger.
+type LogLevel int
+
+// Log levels.
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+)
+
+// log sends the message to the logger, if it's set, at the configured level.
+func (jm *JsonMaskerImpl) log(msg string, args ...any) {
+	if jm.logger == nil {
+		return
+	}
+
+	switch jm.logLevel {
+	case LevelDebug:
+		jm.logger.Debug(msg, args...)
+	case LevelInfo:
+		jm.logger.Info(msg, args...)
+	default:
+		jm.logger.Warn(msg, args...)
+	}
+}
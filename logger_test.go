@@ -0,0 +1,49 @@
+package jsonmask_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Debug(msg string, args ...any) { l.add("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...any)  { l.add("INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...any)  { l.add("WARN", msg, args) }
+
+func (l *testLogger) add(level, msg string, args []any) {
+	l.messages = append(l.messages, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func TestWithLogger(t *testing.T) {
+	l := &testLogger{}
+	jm := jsonmask.New(jsonmask.WithLogger(l, jsonmask.LevelWarn))
+
+	result, err := jm.Mask([]byte(`{"name":"john"}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "name", Action: "unknown"},
+			{Path: "email", Action: "email"},
+			{Path: "name", Action: "upper"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"JOHN"}`, string(result))
+	assert.Equal(t, []string{
+		"WARN jsonmask: unknown action, rule skipped [path name action unknown]",
+		"WARN jsonmask: path not found [path email action email]",
+	}, l.messages)
+
+	t.Run("Level", func(t *testing.T) {
+		l := &testLogger{}
+		jm := jsonmask.New(jsonmask.WithLogger(l, jsonmask.LevelDebug))
+
+		_, err := jm.Mask([]byte(`{}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a", Action: "-"}}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"DEBUG jsonmask: path not found [path a action -]"}, l.messages)
+	})
+}
@@ -0,0 +1,136 @@
+package jsonmask
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/tidwall/gjson"
+)
+
+// MaskLogLine finds JSON objects and arrays embedded in the plain-text log line,
+// e.g. bodies of curl traces or access log snippets, masks them by rules and
+// returns the reassembled line. Text around fragments is kept as is. Fragments
+// looking like JSON but not being valid, e.g. truncated bodies, can't be masked
+// by rules, so the text from their start, except valid fragments found later,
+// is masked by DefaultDetectors, see ScanAndMask. JSON escaped inside quoted
+// strings is not recognized.
+func (jm *JsonMaskerImpl) MaskLogLine(line []byte, smr StructMaskRules) ([]byte, error) {
+	if jm.disabled {
+		return line, nil
+	}
+
+	var res []byte
+	last := 0
+	fallback := -1 // start of the text masked by detectors, if any
+
+	appendText := func(end int) {
+		if fallback < 0 || fallback >= end {
+			res = append(res, line[last:end]...)
+			return
+		}
+		start := last
+		if fallback > start {
+			start = fallback
+		}
+		res = append(res, line[last:start]...)
+		res = append(res, detect(string(line[start:end]), DefaultDetectors)...)
+	}
+
+	for i := 0; i < len(line); i++ {
+		if line[i] != '{' && line[i] != '[' {
+			continue
+		}
+
+		end := jsonFragmentEnd(line[i:])
+		if end < 0 || !gjson.ValidBytes(line[i:i+end]) {
+			if fallback < 0 && looksLikeJSON(line[i:]) {
+				fallback = i
+			}
+			continue
+		}
+
+		masked, err := jm.Mask(line[i:i+end], smr)
+		if err != nil {
+			return nil, err
+		}
+		appendText(i)
+		res = append(res, masked...)
+		i += end - 1
+		last = i + 1
+	}
+
+	if res == nil && fallback < 0 {
+		return line, nil
+	}
+	appendText(len(line))
+	return res, nil
+}
+
+// MaskLogLines copies log lines from src to dst masking JSON fragments
+// embedded in every line, see MaskLogLine.
+func (jm *JsonMaskerImpl) MaskLogLines(dst io.Writer, src io.Reader, smr StructMaskRules) error {
+	r := bufio.NewReader(src)
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if len(line) > 0 {
+			body := bytes.TrimRight(line, "\r\n")
+			masked, err := jm.MaskLogLine(body, smr)
+			if err != nil {
+				return err
+			}
+			if _, err := dst.Write(append(masked, line[len(body):]...)); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// jsonFragmentEnd returns the length of the JSON object or array the data starts
+// with, matching brackets outside of strings, or -1 if it's not terminated.
+func jsonFragmentEnd(data []byte) int {
+	depth := 0
+	inString, escaped := false, false
+	for i, c := range data {
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// looksLikeJSON reports whether the data starting with '{' or '[' looks like
+// the start of a JSON object or array rather than text like "[INFO]".
+func looksLikeJSON(data []byte) bool {
+	rest := bytes.TrimLeft(data[1:], " \t\r\n")
+	if len(rest) == 0 {
+		return false
+	}
+	if data[0] == '{' {
+		return rest[0] == '"' || rest[0] == '}'
+	}
+	return bytes.IndexByte([]byte(`{["-0123456789tfn]`), rest[0]) >= 0
+}
@@ -0,0 +1,71 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskLogLine(t *testing.T) {
+	jm := jsonmask.New()
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}, {Path: "#.email", Action: "email"}}}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			"access log",
+			`2024-05-01 [INFO] POST /users 201 body={"email":"john@example.com","name":"J [x]"} took=3ms`,
+			`2024-05-01 [INFO] POST /users 201 body={"email":"j**n@e******.com","name":"J [x]"} took=3ms`,
+		},
+		{
+			"curl trace with two fragments",
+			`> {"email":"ann@example.com"} < [{"email":"john@example.com"}]`,
+			`> {"email":"a*n@e******.com"} < [{"email":"j**n@e******.com"}]`,
+		},
+		{
+			"truncated body",
+			`body={"email":"john@example.com","na...`,
+			`body={"email":"j**n@e******.com","na...`,
+		},
+		{
+			"truncated body after valid fragment",
+			`req={"email":"ann@example.com","id":7} card=4111111111111111 resp=[{"card":"4111111111111111","phone":"+420 777 123 456"`,
+			`req={"email":"a*n@e******.com","id":7} card=4111111111111111 resp=[{"card":"************1111","phone":"+*** *** *** *56"`,
+		},
+		{
+			"invalid fragment before valid one",
+			`{"email":john@example.com} {"email":"ann@example.com","note":"john@example.com"}`,
+			`{"email":j**n@e******.com} {"email":"a*n@e******.com","note":"john@example.com"}`,
+		},
+		{
+			"nested invalid fragment",
+			`{x {"email":"john@example.com"}`,
+			`{x {"email":"j**n@e******.com"}`,
+		},
+		{"plain text", `GET /health 200`, `GET /health 200`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jm.MaskLogLine([]byte(tt.line), smr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestJsonMaskerImpl_MaskLogLines(t *testing.T) {
+	jm := jsonmask.New()
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}}}
+
+	src := "start\r\nreq {\"email\":\"john@example.com\"}\nend"
+	var dst bytes.Buffer
+	assert.NoError(t, jm.MaskLogLines(&dst, strings.NewReader(src), smr))
+	assert.Equal(t, "start\r\nreq {\"email\":\"j**n@e******.com\"}\nend", dst.String())
+}
@@ -0,0 +1,63 @@
+package jsonmask
+
+import "sync"
+
+// Manager holds maskers of tenants, e.g. of a SaaS platform where each tenant
+// configures its own redaction. Tenant maskers share functions, factories and
+// the type cache of the base masker and keep their own named rule sets, falling
+// back to rule sets of the base masker.
+type Manager struct {
+	base    *JsonMaskerImpl
+	tenants sync.Map // tenant ID -> *JsonMaskerImpl
+}
+
+// NewManager creates a new instance of Manager with the base masker created
+// by New with the options. Tenant maskers inherit the options.
+func NewManager(opts ...Option) *Manager {
+	return &Manager{base: New(opts...)}
+}
+
+// Base returns the masker holding functions and rule sets shared by all tenants.
+func (m *Manager) Base() *JsonMaskerImpl {
+	return m.base
+}
+
+// Tenant returns the masker of the tenant, creating it on the first call.
+func (m *Manager) Tenant(id string) *JsonMaskerImpl {
+	if jm, ok := m.tenants.Load(id); ok {
+		return jm.(*JsonMaskerImpl)
+	}
+
+	jm, _ := m.tenants.LoadOrStore(id, m.newTenant())
+	return jm.(*JsonMaskerImpl)
+}
+
+// RemoveTenant removes the masker of the tenant.
+func (m *Manager) RemoveTenant(id string) {
+	m.tenants.Delete(id)
+}
+
+// newTenant creates a masker inheriting settings of the base masker.
+// Functions and factories added to the tenant masker are not visible to other tenants.
+func (m *Manager) newTenant() *JsonMaskerImpl {
+	jm := &JsonMaskerImpl{
+		settings:  m.base.settings,
+		funcs:     make(map[string]func(string) []byte),
+		factories: make(map[string]func(string) (func(string) []byte, error)),
+		rules:     make(map[string][]StructMaskRules),
+
+		unmaskFuncs: make(map[string]func(string) ([]byte, error)),
+		algs:        make(map[string]Algorithm),
+		stats:       new(maskStats),
+
+		parent: m.base,
+	}
+
+	// nested documents are masked with rule sets of the tenant and quoted
+	// values with its functions
+	jm.addFuncFactory("base64", jm.base64Factory)
+	jm.addFuncFactory("json", jm.jsonFactory)
+	jm.addFuncFactory("quoted", jm.quotedFactory)
+
+	return jm
+}
@@ -0,0 +1,75 @@
+package jsonmask_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	m := jsonmask.NewManager()
+	m.Base().AddFunc("redacted", func(string) []byte { return []byte(`"[redacted]"`) })
+	m.Base().AddRules("customer", jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "upper"}}})
+
+	acme := m.Tenant("acme")
+	assert.Same(t, acme, m.Tenant("acme"))
+	acme.AddRules("customer", jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "redacted"}}})
+
+	other := m.Tenant("other")
+	other.AddFunc("private", jsonmask.Null)
+
+	data := []byte(`{"name":"john"}`)
+
+	smr, ok := acme.Rules("customer")
+	assert.True(t, ok)
+	result, err := acme.Mask(data, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"[redacted]"}`, string(result))
+
+	smr, ok = other.Rules("customer")
+	assert.True(t, ok, "base rule set")
+	result, err = other.Mask(data, smr)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"JOHN"}`, string(result))
+
+	private := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "private"}}}
+	result, err = acme.Mask(data, private)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"john"}`, string(result), "function of another tenant")
+
+	quoted := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a", Action: "quoted(private)"}}}
+	result, err = other.Mask([]byte(`{"a":"12"}`), quoted)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":null}`, string(result), "quoted function of the tenant")
+
+	nested := []byte(`{"doc":"` + base64.StdEncoding.EncodeToString(data) + `"}`)
+	rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "doc", Action: "base64(customer)"}}}
+	result, err = acme.Mask(nested, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"doc":"`+base64.StdEncoding.EncodeToString([]byte(`{"name":"[redacted]"}`))+`"}`, string(result))
+	result, err = other.Mask(nested, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"doc":"`+base64.StdEncoding.EncodeToString([]byte(`{"name":"JOHN"}`))+`"}`, string(result))
+
+	type Customer struct {
+		Name string `json:"name" mask:"upper"`
+	}
+	assert.Equal(t, m.Base().ParseStruct(Customer{}), acme.ParseStruct(Customer{}))
+
+	m.RemoveTenant("acme")
+	assert.NotSame(t, acme, m.Tenant("acme"))
+}
+
+func TestManager_TenantOptions(t *testing.T) {
+	m := jsonmask.NewManager(jsonmask.WithCanonicalOutput(), jsonmask.WithUnknownFields("null"))
+	type Customer struct {
+		Name string `json:"name" mask:"upper"`
+	}
+	acme := m.Tenant("acme")
+
+	result, err := acme.Mask([]byte(`{"name":"john", "token":"secret"}`), acme.ParseStruct(Customer{}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"JOHN","token":null}`, string(result))
+}
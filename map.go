@@ -0,0 +1,240 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// MaskMap applies rules to the document already decoded by encoding/json,
+// modifying m in place, so applications holding parsed JSON avoid a
+// marshal-mask-unmarshal round trip. Objects must be map[string]any and
+// arrays []any, values of other types are treated as leaves. Masked values
+// are decoded by encoding/json, so numbers become float64.
+// Paths using gjson modifiers and exclusion rules are not supported.
+func (jm *JsonMaskerImpl) MaskMap(m map[string]any, smr StructMaskRules) error {
+	if jm.disabled || m == nil {
+		return nil
+	}
+
+	rules := smr.Rules
+	if jm.unknownFields != "" && len(smr.Fields) > 0 {
+		raw, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		rules = jm.unknownFieldRules(raw, smr.Fields, rules)
+	}
+
+	for _, rule := range rules {
+		if isExclusion(rule) {
+			jm.log("jsonmask: exclusion not supported, rule skipped", "path", rule.Path)
+			continue
+		}
+
+		maskFunc, ok, err := jm.ruleFunc(rule)
+		if !ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, found := cutModifierPath(rule.Path); found {
+			if jm.strict {
+				return fmt.Errorf("%w: %s", ErrModifier, rule.Path)
+			}
+			jm.log("jsonmask: modifier not allowed, rule skipped", "path", rule.Path, "action", rule.Action)
+			continue
+		}
+
+		rule := rule
+		op := func(v any) (any, bool) {
+			return maskTreeValue(v, rule, maskFunc)
+		}
+
+		if _, found := maskTree(m, splitPath(rule.Path), op); !found {
+			jm.log("jsonmask: path not found", "path", rule.Path, "action", rule.Action)
+		}
+	}
+
+	return nil
+}
+
+// maskTreeValue returns the value masked by the rule.
+// If keep is false, the value has to be deleted.
+func maskTreeValue(v any, rule Rule, maskFunc func(string) []byte) (res any, keep bool) {
+	if rule.Keys {
+		if _, ok := v.(map[string]any); !ok {
+			return v, true
+		}
+	}
+
+	if maskFunc == nil {
+		if rule.Keys {
+			return map[string]any{}, true
+		}
+		return nil, false
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, true
+	}
+
+	var masked []byte
+	if rule.Keys {
+		masked = maskKeys(gjson.ParseBytes(raw), maskFunc)
+	} else {
+		masked = maskFunc(string(raw))
+	}
+
+	if err := json.Unmarshal(masked, &res); err != nil {
+		return nil, true
+	}
+	return res, true
+}
+
+// pathSegment is an object key or a selector of a rule path.
+type pathSegment struct {
+	key      string // unescaped object key or selector
+	selector bool
+}
+
+// splitPath splits the rule path to unescaped object keys and selectors.
+func splitPath(path string) []pathSegment {
+	var segs []pathSegment
+
+	splitKeys := func(path string) {
+		var key strings.Builder
+		for i := 0; i < len(path); i++ {
+			switch {
+			case path[i] == '\\' && i+1 < len(path):
+				i++
+				key.WriteByte(path[i])
+			case path[i] == '.':
+				segs = append(segs, pathSegment{key: key.String()})
+				key.Reset()
+			default:
+				key.WriteByte(path[i])
+			}
+		}
+		segs = append(segs, pathSegment{key: key.String()})
+	}
+
+	for path != "" {
+		parentPath, selector, itemPath, found := cutSelectorPath(path)
+		if !found {
+			splitKeys(path)
+			break
+		}
+
+		if parentPath != "" {
+			splitKeys(parentPath)
+		}
+		segs = append(segs, pathSegment{key: selector, selector: true})
+		path = strings.TrimPrefix(itemPath, ".")
+	}
+
+	return segs
+}
+
+// maskTree applies op to values of the tree v selected by path segments.
+// It returns the new value of v, containers are modified in place
+// except arrays with deleted elements, and whether any value was selected.
+func maskTree(v any, segs []pathSegment, op func(any) (any, bool)) (any, bool) {
+	if len(segs) == 0 {
+		return v, false
+	}
+
+	seg, rest := segs[0], segs[1:]
+	deep := seg.selector && seg.key == "**"
+	found := false
+
+	if deep && len(rest) > 0 {
+		// "**" matches zero or more levels
+		v, found = maskTree(v, rest, op)
+	}
+
+	apply := func(child any) (any, bool) {
+		var f bool
+		switch {
+		case deep:
+			child, f = maskTree(child, segs, op)
+			found = found || f
+			if len(rest) > 0 {
+				return child, true
+			}
+		case len(rest) > 0:
+			child, f = maskTree(child, rest, op)
+			found = found || f
+			return child, true
+		}
+		found = true
+		return op(child)
+	}
+
+	switch c := v.(type) {
+	case map[string]any:
+		var keys []string
+		switch {
+		case !seg.selector || seg.key[0] == '-':
+			// negative indexes are valid object keys
+			if _, ok := c[seg.key]; ok {
+				keys = append(keys, seg.key)
+			}
+		case seg.key == "*" || deep:
+			for k := range c {
+				keys = append(keys, k)
+			}
+		}
+
+		for _, k := range keys {
+			if child, keep := apply(c[k]); keep {
+				c[k] = child
+			} else {
+				delete(c, k)
+			}
+		}
+
+	case []any:
+		var indexes []int
+		switch {
+		case !seg.selector:
+			if i, err := strconv.Atoi(seg.key); err == nil && i >= 0 && i < len(c) {
+				indexes = append(indexes, i)
+			}
+		case seg.key == "*" || deep:
+			indexes = selectElements(len(c), "#", nil)
+		default:
+			indexes = selectElements(len(c), seg.key, func(i int) string {
+				raw, _ := json.Marshal(c[i])
+				return string(raw)
+			})
+		}
+
+		deleted := make(map[int]bool)
+		for _, i := range indexes {
+			if child, keep := apply(c[i]); keep {
+				c[i] = child
+			} else {
+				deleted[i] = true
+			}
+		}
+
+		if len(deleted) > 0 {
+			res := make([]any, 0, len(c)-len(deleted))
+			for i := range c {
+				if !deleted[i] {
+					res = append(res, c[i])
+				}
+			}
+			v = res
+		}
+	}
+
+	return v, found
+}
@@ -0,0 +1,69 @@
+package jsonmask_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskMap(t *testing.T) {
+	data := `{
+		"name": "john",
+		"email": "john@example.com",
+		"a.b": 1,
+		"items": [
+			{"type": "card", "number": "4111111111111111", "cvv": "123"},
+			{"type": "cash", "number": "1"},
+			{"type": "card", "number": "5500000000000004"}
+		],
+		"history": [1, 2, 3],
+		"nested": {"deep": {"cardNumber": "1"}, "list": [{"cardNumber": "2"}]},
+		"balances": {"jane@example.com": 5}
+	}`
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(data), &m))
+
+	jm := jsonmask.New()
+	err := jm.MaskMap(m, jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "name", Action: "upper"},
+			{Path: "email", Action: "email"},
+			{Path: `a\.b`, Action: "zero"},
+			{Path: `items.#(type=="card")#.number`, Action: "first4"},
+			{Path: "items.#.cvv", Action: "-"},
+			{Path: "history.[1:]", Action: "-"},
+			{Path: "**.cardNumber", Action: "null"},
+			{Path: "balances", Action: "email", Keys: true},
+			{Path: "missing.#", Action: "null"},
+		},
+	})
+	assert.NoError(t, err)
+
+	result, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "JOHN",
+		"email": "j**n@e******.com",
+		"a.b": 0,
+		"items": [
+			{"type": "card", "number": "4111"},
+			{"type": "cash", "number": "1"},
+			{"type": "card", "number": "5500"}
+		],
+		"history": [1],
+		"nested": {"deep": {"cardNumber": null}, "list": [{"cardNumber": null}]},
+		"balances": {"j**e@e******.com": 5}
+	}`, string(result))
+
+	err = jm.MaskMap(m, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "unknown"}}})
+	assert.NoError(t, err)
+
+	strict := jsonmask.New(jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+	err = strict.MaskMap(m, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "name", Action: "unknown"}}})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+	err = strict.MaskMap(m, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "items.@reverse.0", Action: "null"}}})
+	assert.ErrorIs(t, err, jsonmask.ErrModifier)
+}
@@ -5,7 +5,24 @@ package jsonmask
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
 )
 
 // Upper returns the input string in uppercase.
@@ -26,8 +43,35 @@ func InitialChar(s string) []byte {
 	return []byte(s)
 }
 
+// MaskStyle defines characters used by maskers hiding parts of values,
+// e.g. for UI-facing outputs with specific display conventions.
+type MaskStyle struct {
+	Char     rune   // replaces hidden characters, '*' if zero; escaped in JSON as needed
+	Ellipsis string // marks truncated values
+}
+
+// maskChar returns the character replacing hidden characters,
+// '*' if it's not set or not a valid character.
+func (s MaskStyle) maskChar() rune {
+	if s.Char == 0 || !utf8.ValidRune(s.Char) {
+		return '*'
+	}
+	return s.Char
+}
+
+// DefaultMaskStyle is the style of maskers registered by default.
+var DefaultMaskStyle = MaskStyle{Char: '*', Ellipsis: "..."}
+
 // PrefixFn returns a function that prefixes the input string with the specified length.
 func PrefixFn(length int, addEllipsis bool) func(string) []byte {
+	return PrefixStyleFn(length, addEllipsis, DefaultMaskStyle)
+}
+
+// PrefixStyleFn is like PrefixFn but appends the ellipsis of the style.
+func PrefixStyleFn(length int, addEllipsis bool, style MaskStyle) func(string) []byte {
+	ellipsis := string(quote(style.Ellipsis))
+	ellipsis = ellipsis[1 : len(ellipsis)-1]
+
 	return func(s string) []byte {
 		if len(s) <= length+2 { // Include the opening and closing quotes
 			return []byte(s)
@@ -35,7 +79,7 @@ func PrefixFn(length int, addEllipsis bool) func(string) []byte {
 
 		res := s[:length+1] // Include the opening quote
 		if addEllipsis {
-			res += `..."`
+			res += ellipsis + `"`
 		} else {
 			res += `"`
 		}
@@ -58,6 +102,18 @@ func Null(s string) []byte {
 
 // Email masks the input string holding email address.
 func Email(email string) []byte {
+	return maskEmail(email, DefaultMaskStyle.maskChar())
+}
+
+// EmailFn returns a function like Email hiding characters with the character of the style.
+func EmailFn(style MaskStyle) func(string) []byte {
+	return func(email string) []byte {
+		return maskEmail(email, style.maskChar())
+	}
+}
+
+// maskEmail masks the email address replacing hidden characters with maskChar.
+func maskEmail(email string, maskChar rune) []byte {
 	var invalidEmail = []byte(`"invalid_email_format"`)
 
 	// Check for the presence of quotes
@@ -67,6 +123,7 @@ func Email(email string) []byte {
 
 	// Process the email while keeping quotes in place
 	emailBytes := []byte(email)
+	masked := make([]bool, len(emailBytes))
 
 	// Find the position of the '@' symbol
 	atIndex := -1
@@ -83,11 +140,11 @@ func Email(email string) []byte {
 	// Mask the local part
 	if atIndex-1 <= 2 {
 		for i := 2; i < atIndex; i++ { // Skip the opening quote
-			emailBytes[i] = '*'
+			masked[i] = true
 		}
 	} else {
 		for i := 2; i < atIndex-1; i++ {
-			emailBytes[i] = '*'
+			masked[i] = true
 		}
 	}
 
@@ -105,13 +162,986 @@ func Email(email string) []byte {
 
 	// Mask the domain part, leaving the last two levels visible
 	for i := atIndex + 2; i < lastDotIndex; i++ {
-		emailBytes[i] = '*'
+		masked[i] = true
 	}
 
-	return emailBytes
+	// the character is escaped if needed, like '"'
+	char := quote(string(maskChar))
+	char = char[1 : len(char)-1]
+
+	res := make([]byte, 0, len(emailBytes))
+	for i, b := range emailBytes {
+		if masked[i] {
+			res = append(res, char...)
+		} else {
+			res = append(res, b)
+		}
+	}
+	return res
 }
 
 // Zero masks the input string holding numeric value to 0 without quotes.
 func Zero(s string) []byte {
 	return []byte(`0`)
 }
+
+// Passport masks the input string holding a passport or travel document number.
+// The issuing-country prefix (up to 3 leading letters) and the last 2 characters
+// are kept, other letters and digits are replaced with '*'. Separators are kept.
+func Passport(s string) []byte {
+	return maskPassport(s, DefaultMaskStyle.maskChar())
+}
+
+// PassportFn returns a function like Passport hiding characters with the character of the style.
+func PassportFn(style MaskStyle) func(string) []byte {
+	return func(s string) []byte {
+		return maskPassport(s, style.maskChar())
+	}
+}
+
+// maskPassport masks the passport number replacing hidden characters with maskChar.
+func maskPassport(s string, maskChar rune) []byte {
+	str, ok := unquote(s)
+	if !ok {
+		return []byte(s)
+	}
+
+	prefix := 0
+	for _, r := range str {
+		if !unicode.IsLetter(r) || prefix == 3 {
+			break
+		}
+		prefix++
+	}
+
+	return maskAlnum(s, prefix, 2, maskChar)
+}
+
+// maskAlnum replaces letters and digits of the JSON string with maskChar,
+// keeping the first keepPrefix and the last keepSuffix of them. The string is
+// unescaped before masking, so escapes like \u0041 count as single characters.
+func maskAlnum(s string, keepPrefix, keepSuffix int, maskChar rune) []byte {
+	str, ok := unquote(s)
+	if !ok {
+		return []byte(s)
+	}
+	return quote(maskLettersDigits(str, keepPrefix, keepSuffix, maskChar))
+}
+
+// maskLettersDigits replaces letters and digits of s with maskChar, keeping the first
+// keepPrefix and the last keepSuffix of them. Separators are kept. If the value
+// is too short to keep anything hidden, all letters and digits are replaced.
+func maskLettersDigits(s string, keepPrefix, keepSuffix int, maskChar rune) string {
+	runes := []rune(s)
+
+	total := 0
+	for _, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			total++
+		}
+	}
+	if total <= keepPrefix+keepSuffix {
+		keepPrefix, keepSuffix = 0, 0
+	}
+
+	pos := 0
+	for i, r := range runes {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			continue
+		}
+		if pos >= keepPrefix && pos < total-keepSuffix {
+			runes[i] = maskChar
+		}
+		pos++
+	}
+
+	return string(runes)
+}
+
+// translitTable maps non-ASCII Latin letters to their ASCII transliteration.
+var translitTable = func() map[rune]string {
+	groups := map[string]string{
+		"A": "ÀÁÂÃÄÅĀĂĄǍ", "a": "àáâãäåāăąǎª",
+		"C": "ÇĆĈĊČ", "c": "çćĉċč",
+		"D": "ĎĐÐ", "d": "ďđð",
+		"E": "ÈÉÊËĒĔĖĘĚ", "e": "èéêëēĕėęě",
+		"G": "ĜĞĠĢ", "g": "ĝğġģ",
+		"H": "ĤĦ", "h": "ĥħ",
+		"I": "ÌÍÎÏĨĪĬĮİǏ", "i": "ìíîïĩīĭįıǐ",
+		"J": "Ĵ", "j": "ĵ",
+		"K": "Ķ", "k": "ķĸ",
+		"L": "ĹĻĽĿŁ", "l": "ĺļľŀł",
+		"N": "ÑŃŅŇŊ", "n": "ñńņňŉŋ",
+		"O": "ÒÓÔÕÖØŌŎŐǑ", "o": "òóôõöøōŏőǒº",
+		"R": "ŔŖŘ", "r": "ŕŗř",
+		"S": "ŚŜŞŠȘ", "s": "śŝşšș",
+		"T": "ŢŤŦȚ", "t": "ţťŧț",
+		"U": "ÙÚÛÜŨŪŬŮŰŲǓ", "u": "ùúûüũūŭůűųǔ",
+		"W": "Ŵ", "w": "ŵ",
+		"Y": "ÝŶŸ", "y": "ýÿŷ",
+		"Z": "ŹŻŽ", "z": "źżž",
+		"AE": "Æ", "ae": "æ", "OE": "Œ", "oe": "œ",
+		"TH": "Þ", "th": "þ", "ss": "ß",
+	}
+
+	table := make(map[rune]string)
+	for ascii, letters := range groups {
+		for _, r := range letters {
+			table[r] = ascii
+		}
+	}
+	return table
+}()
+
+// Translit removes diacritics and transliterates the input string to ASCII,
+// e.g. "Dvořák" becomes "Dvorak". Characters without transliteration are
+// replaced with '?'. Values other than strings are returned as is.
+func Translit(s string) []byte {
+	str, ok := unquote(s)
+	if !ok {
+		return []byte(s)
+	}
+	return quote(translit(str))
+}
+
+// translit returns s transliterated to ASCII.
+func translit(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range s {
+		switch ascii, ok := translitTable[r]; {
+		case r < utf8.RuneSelf:
+			sb.WriteRune(r)
+		case ok:
+			sb.WriteString(ascii)
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
+
+// Initials reduces a full name to its initials, e.g. "John Ronald Reuel Tolkien"
+// becomes "J.R.R.T.". Words are separated by spaces or hyphens.
+func Initials(s string) []byte {
+	name, ok := unquote(s)
+	if !ok {
+		return []byte(s)
+	}
+
+	var sb strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-'
+	}) {
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				sb.WriteRune(unicode.ToUpper(r))
+				sb.WriteByte('.')
+				break
+			}
+		}
+	}
+
+	return quote(sb.String())
+}
+
+// unquote returns the unescaped content of a JSON string.
+// The second result is false if the input is not a JSON string.
+func unquote(s string) (string, bool) {
+	res := gjson.Parse(s)
+	if res.Type != gjson.String {
+		return "", false
+	}
+	return res.Str, true
+}
+
+// StringFn returns a masking function applying f to the decoded value of
+// a JSON string and encoding the result back. Other values are returned as is.
+func StringFn(f func(string) string) func(string) []byte {
+	return func(s string) []byte {
+		str, ok := unquote(s)
+		if !ok {
+			return []byte(s)
+		}
+		return quote(f(str))
+	}
+}
+
+// quote returns the input string as JSON string with quotes.
+func quote(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding of a string never fails
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// Address generalizes the input string holding a postal address to its city and
+// country. The address is expected to be comma separated with the country last,
+// e.g. "221B Baker Street, London NW1 6XE, United Kingdom" becomes
+// "London, United Kingdom". Words containing digits (house numbers, postcodes)
+// are removed from the kept parts.
+func Address(s string) []byte {
+	addr, ok := unquote(s)
+	if !ok {
+		return []byte(s)
+	}
+
+	parts := strings.Split(addr, ",")
+	switch {
+	case len(parts) >= 3:
+		parts = parts[len(parts)-2:]
+	case len(parts) == 2:
+		parts = parts[1:]
+	default:
+		return []byte(`""`)
+	}
+
+	var kept []string
+	for _, part := range parts {
+		var words []string
+		for _, word := range strings.Fields(part) {
+			if strings.IndexFunc(word, unicode.IsDigit) < 0 {
+				words = append(words, word)
+			}
+		}
+		if len(words) > 0 {
+			kept = append(kept, strings.Join(words, " "))
+		}
+	}
+
+	return quote(strings.Join(kept, ", "))
+}
+
+// addressKeptAttrs holds lower-cased names of address object attributes kept by AddressObject.
+var addressKeptAttrs = map[string]bool{
+	"city":         true,
+	"town":         true,
+	"locality":     true,
+	"country":      true,
+	"countrycode":  true,
+	"country_code": true,
+}
+
+// AddressObject generalizes the input JSON object holding a postal address.
+// Attributes city and country are kept, string attributes like street, house
+// number or postcode are replaced with an empty string, others with null.
+func AddressObject(s string) []byte {
+	obj := gjson.Parse(s)
+	if !obj.IsObject() {
+		return []byte(s)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	obj.ForEach(func(key, value gjson.Result) bool {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(key.Raw)
+		buf.WriteByte(':')
+		switch {
+		case addressKeptAttrs[strings.ToLower(key.Str)]:
+			buf.WriteString(value.Raw)
+		case value.Type == gjson.String:
+			buf.WriteString(`""`)
+		default:
+			buf.WriteString(`null`)
+		}
+		return true
+	})
+	buf.WriteByte('}')
+
+	return buf.Bytes()
+}
+
+// timeNow returns current time. It's a variable to be replaced in tests.
+var timeNow = time.Now
+
+// AgeBucketFn returns a function that converts the input string holding a birth date
+// (formatted as "2006-01-02" or RFC 3339) to an age band of the given width,
+// e.g. "30-39" for width 10. It panics if the width is not positive.
+func AgeBucketFn(width int) func(string) []byte {
+	return ageBucketFn(width, timeNow)
+}
+
+// ageBucketFn works like AgeBucketFn, computing ages at the time returned by now.
+func ageBucketFn(width int, now func() time.Time) func(string) []byte {
+	if width <= 0 {
+		panic("jsonmask: non-positive age band width")
+	}
+
+	return func(s string) []byte {
+		str, ok := unquote(s)
+		if !ok {
+			return []byte(s)
+		}
+
+		birth, err := time.Parse("2006-01-02", str)
+		if err != nil {
+			if birth, err = time.Parse(time.RFC3339, str); err != nil {
+				return []byte(`"invalid_date_format"`)
+			}
+		}
+
+		at := now()
+		age := at.Year() - birth.Year()
+		if at.Month() < birth.Month() || (at.Month() == birth.Month() && at.Day() < birth.Day()) {
+			age--
+		}
+		if age < 0 {
+			age = 0
+		}
+
+		from := age - age%width
+		return []byte(`"` + strconv.Itoa(from) + "-" + strconv.Itoa(from+width-1) + `"`)
+	}
+}
+
+// AmountFn returns a function that rounds the input string holding numeric value
+// to the nearest multiple of precision, e.g. 1234.56 becomes 1200 for precision 100.
+// The result is a number without quotes. Non-numeric values are returned as is.
+// It panics if the precision is not a positive finite number.
+func AmountFn(precision float64) func(string) []byte {
+	if !(precision > 0) || math.IsInf(precision, 1) {
+		panic("jsonmask: non-positive precision")
+	}
+
+	decimals := 0
+	if precision < 1 {
+		decimals = int(math.Ceil(-math.Log10(precision)))
+	}
+
+	return func(s string) []byte {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return []byte(s)
+		}
+		v = math.Round(v/precision) * precision
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return []byte(s)
+		}
+		if v == 0 {
+			v = 0 // avoid negative zero
+		}
+		return []byte(strconv.FormatFloat(v, 'f', decimals, 64))
+	}
+}
+
+// LaplaceFn returns a function that adds noise drawn from the Laplace distribution
+// with the scale sensitivity/epsilon to numbers, the mechanism of differential
+// privacy: smaller epsilon gives stronger privacy and more noise, sensitivity is
+// the maximum change of the value caused by a single individual. Integers stay
+// integers. Noise is drawn from a cryptographically secure source on every call,
+// so repeated releases of the same value consume the privacy budget.
+// Non-numeric values are returned as is, noisy values out of the range of
+// float64 become null. It panics if epsilon or sensitivity is not a positive
+// finite number or their ratio overflows.
+func LaplaceFn(epsilon, sensitivity float64) func(string) []byte {
+	return laplaceFn(epsilon, sensitivity, func(string) io.Reader { return rand.Reader })
+}
+
+// seededLaplace returns a function like LaplaceFn drawing noise from a source
+// derived from the value and the salt, so the output is stable across runs.
+func seededLaplace(epsilon, sensitivity float64, salt string) func(string) []byte {
+	return laplaceFn(epsilon, sensitivity, func(s string) io.Reader {
+		return mathrand.New(mathrand.NewSource(valueSeed(s, []byte(salt))))
+	})
+}
+
+// laplaceFn works like LaplaceFn, reading noise from the source of the value.
+func laplaceFn(epsilon, sensitivity float64, source func(s string) io.Reader) func(string) []byte {
+	if !validLaplaceArgs(epsilon, sensitivity) {
+		panic("jsonmask: invalid epsilon or sensitivity")
+	}
+	scale := sensitivity / epsilon
+
+	return func(s string) []byte {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return []byte(s)
+		}
+
+		var buf [8]byte
+		if _, err := io.ReadFull(source(s), buf[:]); err != nil {
+			return []byte(`null`)
+		}
+		// uniform in (-0.5, 0.5), zero excluded to keep the logarithm finite
+		u := (float64(binary.BigEndian.Uint64(buf[:])>>11)+0.5)/(1<<53) - 0.5
+		v -= scale * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return []byte(`null`)
+		}
+
+		if !strings.ContainsAny(s, ".eE") {
+			return []byte(strconv.FormatFloat(math.Round(v)+0, 'f', 0, 64))
+		}
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+}
+
+// validLaplaceArgs reports whether epsilon and sensitivity are positive finite
+// numbers giving a finite scale of noise.
+func validLaplaceArgs(epsilon, sensitivity float64) bool {
+	return epsilon > 0 && sensitivity > 0 && !math.IsInf(epsilon, 1) && !math.IsInf(sensitivity/epsilon, 1)
+}
+
+// TombstoneFn returns a function that replaces the input value with a tombstone
+// recording that the value was erased, when and by which retention policy,
+// e.g. {"erased":true,"at":"2024-05-01T00:00:00Z","policy":"gdpr-30d"}.
+// The policy is omitted if empty. NULL is returned as is, nothing was erased.
+func TombstoneFn(policy string) func(string) []byte {
+	return tombstoneFn(policy, timeNow)
+}
+
+// tombstoneFn works like TombstoneFn, recording the time returned by now.
+func tombstoneFn(policy string, now func() time.Time) func(string) []byte {
+	return func(s string) []byte {
+		if s == "null" {
+			return []byte(s)
+		}
+
+		res := []byte(`{"erased":true,"at":"` + now().UTC().Format(time.RFC3339) + `"`)
+		if policy != "" {
+			res = append(res, `,"policy":`...)
+			res = append(res, quote(policy)...)
+		}
+		return append(res, '}')
+	}
+}
+
+// QuotedFn returns a function that applies the numeric masker f to a number
+// encoded as a JSON string, e.g. "123.45", keeping the string encoding of
+// a numeric result, since many APIs send money as strings. Numbers without
+// quotes are passed to f as is, other values are returned as is.
+func QuotedFn(f func(string) []byte) func(string) []byte {
+	return func(s string) []byte {
+		v := gjson.Parse(s)
+		switch {
+		case v.Type == gjson.Number:
+			return f(s)
+		case v.Type != gjson.String:
+			return []byte(s)
+		}
+
+		if n := gjson.Parse(v.Str); n.Type != gjson.Number || n.Raw != v.Str || !gjson.Valid(v.Str) {
+			return []byte(s)
+		}
+
+		res := f(v.Str)
+		if gjson.ParseBytes(res).Type == gjson.Number {
+			return quote(string(res))
+		}
+		return res
+	}
+}
+
+// nationalIDFormats holds count of leading and trailing letters and digits
+// kept visible by NationalIDFn per ISO 3166-1 alpha-2 country code.
+var nationalIDFormats = map[string][2]int{
+	"CZ": {0, 3}, // rodné číslo, 123456/7890
+	"SK": {0, 3}, // rodné číslo, 123456/7890
+	"PL": {0, 3}, // PESEL, 11 digits starting with birth date
+	"DE": {0, 3}, // Steuer-ID, 11 digits
+	"AT": {0, 3}, // Sozialversicherungsnummer, 10 digits
+	"FR": {1, 2}, // NIR, sex digit and control key are kept
+	"IT": {0, 1}, // codice fiscale, 16 characters, control character is kept
+	"ES": {0, 1}, // DNI/NIE, control letter is kept
+	"GB": {2, 1}, // National Insurance number, QQ123456C
+	"US": {0, 4}, // SSN, 123-45-6789
+}
+
+// NationalIDFn returns a function that masks the input string holding national
+// identification number of the given country (ISO 3166-1 alpha-2 code). Separators
+// are kept, letters and digits are replaced with '*' except ones visible by the
+// country format. Unknown countries keep the last 2 characters.
+func NationalIDFn(country string) func(string) []byte {
+	return NationalIDStyleFn(country, DefaultMaskStyle)
+}
+
+// NationalIDStyleFn is like NationalIDFn but hides characters with the character of the style.
+func NationalIDStyleFn(country string, style MaskStyle) func(string) []byte {
+	keep, ok := nationalIDFormats[strings.ToUpper(country)]
+	if !ok {
+		keep = [2]int{0, 2}
+	}
+
+	return func(s string) []byte {
+		return maskAlnum(s, keep[0], keep[1], style.maskChar())
+	}
+}
+
+// UUID replaces the input value with a randomly generated UUID (version 4).
+// NULL is returned as is.
+func UUID(s string) []byte {
+	if s == "null" {
+		return []byte(s)
+	}
+
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return []byte(`null`)
+	}
+	return formatUUID(u, 4)
+}
+
+// seededUUID returns a function replacing the input value with a UUID (version 4)
+// generated from the value and the salt instead of a random source. NULL is returned as is.
+func seededUUID(salt string) func(string) []byte {
+	return func(s string) []byte {
+		if s == "null" {
+			return []byte(s)
+		}
+
+		var u [16]byte
+		mathrand.New(mathrand.NewSource(valueSeed(s, []byte(salt)))).Read(u[:])
+		return formatUUID(u, 4)
+	}
+}
+
+// UUIDHashFn returns a function that replaces the input value with a UUID
+// (version 8) derived from the value by HMAC-SHA-256 with the key, so the same
+// input is always replaced with the same UUID and it can't be reversed by
+// a dictionary without the key. NULL is returned as is.
+func UUIDHashFn(key []byte) func(string) []byte {
+	key = append([]byte(nil), key...)
+	return func(s string) []byte {
+		if s == "null" {
+			return []byte(s)
+		}
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(s))
+
+		var u [16]byte
+		copy(u[:], mac.Sum(nil))
+		return formatUUID(u, 8)
+	}
+}
+
+// formatUUID sets version and variant bits of u and returns it as quoted JSON string.
+func formatUUID(u [16]byte, version byte) []byte {
+	u[6] = (u[6] & 0x0f) | version<<4
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	buf := make([]byte, 38)
+	buf[0], buf[37] = '"', '"'
+	hex.Encode(buf[1:9], u[0:4])
+	buf[9] = '-'
+	hex.Encode(buf[10:14], u[4:6])
+	buf[14] = '-'
+	hex.Encode(buf[15:19], u[6:8])
+	buf[19] = '-'
+	hex.Encode(buf[20:24], u[8:10])
+	buf[24] = '-'
+	hex.Encode(buf[25:37], u[10:16])
+	return buf
+}
+
+// StripHTML removes HTML tags from the input string and collapses whitespace.
+func StripHTML(s string) []byte {
+	return StripHTMLFn(0)(s)
+}
+
+// StripHTMLFn returns a function that removes HTML tags from the input string,
+// collapses whitespace and truncates the text to maxLen characters.
+// Zero maxLen means no truncation. Content of script and style elements is removed.
+func StripHTMLFn(maxLen int) func(string) []byte {
+	return func(s string) []byte {
+		str, ok := unquote(s)
+		if !ok {
+			return []byte(s)
+		}
+
+		var sb strings.Builder
+		for len(str) > 0 {
+			start := strings.IndexByte(str, '<')
+			if start < 0 {
+				sb.WriteString(str)
+				break
+			}
+			sb.WriteString(str[:start])
+
+			end := strings.IndexByte(str[start:], '>')
+			if end < 0 {
+				break
+			}
+			tag := strings.ToLower(str[start+1 : start+end])
+			str = str[start+end+1:]
+			sb.WriteByte(' ')
+
+			for _, skip := range []string{"script", "style"} {
+				if tag == skip || strings.HasPrefix(tag, skip+" ") {
+					closing := strings.Index(strings.ToLower(str), "</"+skip)
+					if closing < 0 {
+						str = ""
+					} else {
+						str = str[closing:]
+					}
+				}
+			}
+		}
+
+		text := strings.Join(strings.Fields(html.UnescapeString(sb.String())), " ")
+		if runes := []rune(text); maxLen > 0 && len(runes) > maxLen {
+			text = string(runes[:maxLen])
+		}
+
+		return quote(text)
+	}
+}
+
+// ArrayLimitFn returns a function that keeps only the first n elements of the input
+// JSON array. If addMarker is true, a string like "…and 7 more" is appended
+// in place of dropped elements. Non-array values are returned as is.
+func ArrayLimitFn(n int, addMarker bool) func(string) []byte {
+	return func(s string) []byte {
+		arr := gjson.Parse(s)
+		if !arr.IsArray() {
+			return []byte(s)
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		count := 0
+		arr.ForEach(func(_, value gjson.Result) bool {
+			if count < n {
+				if count > 0 {
+					buf.WriteByte(',')
+				}
+				buf.WriteString(value.Raw)
+			}
+			count++
+			return true
+		})
+
+		if count <= n {
+			return []byte(s)
+		}
+
+		if addMarker {
+			if n > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(quote("…and " + strconv.Itoa(count-n) + " more"))
+		}
+		buf.WriteByte(']')
+
+		return buf.Bytes()
+	}
+}
+
+// Count replaces the input JSON array with its element count.
+// Other values are replaced with null.
+func Count(s string) []byte {
+	arr := gjson.Parse(s)
+	if !arr.IsArray() {
+		return []byte(`null`)
+	}
+	return []byte(strconv.Itoa(len(arr.Array())))
+}
+
+// Summary replaces the input JSON object with a summary like {"masked":true,"fields":7}
+// holding the count of object attributes. Other values except NULL are replaced
+// with {"masked":true}.
+func Summary(s string) []byte {
+	v := gjson.Parse(s)
+	switch {
+	case v.Type == gjson.Null:
+		return []byte(`null`)
+	case v.IsObject():
+		return []byte(`{"masked":true,"fields":` + strconv.Itoa(len(v.Map())) + `}`)
+	}
+	return []byte(`{"masked":true}`)
+}
+
+// True masks the input string holding boolean value to true without quotes.
+func True(s string) []byte {
+	return []byte(`true`)
+}
+
+// False masks the input string holding boolean value to false without quotes.
+func False(s string) []byte {
+	return []byte(`false`)
+}
+
+// Sign masks the input string holding numeric value to -1, 0 or 1 keeping its sign.
+// Non-numeric values are returned as is.
+func Sign(s string) []byte {
+	v, err := strconv.ParseFloat(s, 64)
+	switch {
+	case err != nil:
+		return []byte(s)
+	case v > 0:
+		return []byte(`1`)
+	case v < 0:
+		return []byte(`-1`)
+	}
+	return []byte(`0`)
+}
+
+// Magnitude masks the input string holding numeric value to its order of magnitude
+// keeping the sign, e.g. 12345 becomes 10000 and -532 becomes -100.
+// Non-numeric values are returned as is.
+func Magnitude(s string) []byte {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return []byte(s)
+	}
+	if v == 0 {
+		return []byte(`0`)
+	}
+
+	m := math.Pow10(int(math.Floor(math.Log10(math.Abs(v)))))
+	return []byte(strconv.FormatFloat(math.Copysign(m, v), 'f', -1, 64))
+}
+
+// Length replaces the input string with its character count as a number.
+// Non-string values are returned as is.
+func Length(s string) []byte {
+	str, ok := unquote(s)
+	if !ok {
+		return []byte(s)
+	}
+	return []byte(strconv.Itoa(utf8.RuneCountInString(str)))
+}
+
+// LengthLabel replaces the input string with a label holding its character count,
+// e.g. "<len=42>". Non-string values are returned as is.
+func LengthLabel(s string) []byte {
+	str, ok := unquote(s)
+	if !ok {
+		return []byte(s)
+	}
+	return []byte(`"<len=` + strconv.Itoa(utf8.RuneCountInString(str)) + `>"`)
+}
+
+// RegexFn returns a function that replaces matches of the regular expression pattern
+// in the input string with replacement. Inside replacement, $ signs are interpreted
+// as in regexp.Regexp.ReplaceAllString. Non-string values are returned as is.
+// It panics if the pattern can't be compiled.
+func RegexFn(pattern, replacement string) func(string) []byte {
+	re := regexp.MustCompile(pattern)
+
+	return func(s string) []byte {
+		str, ok := unquote(s)
+		if !ok {
+			return []byte(s)
+		}
+		return quote(re.ReplaceAllString(str, replacement))
+	}
+}
+
+// SyntheticCard replaces the input string holding a card number with a synthetic one
+// sharing the BIN (the first 6 digits) and passing the Luhn check, so masked data
+// still passes validation. Separators are kept. Digits of values with less than
+// 12 digits are masked with '*'. Card numbers encoded as JSON numbers are replaced
+// with synthetic numbers, or null if too short. NULL is returned as is.
+func SyntheticCard(s string) []byte {
+	return syntheticCard(s, func([]byte) io.Reader { return rand.Reader })
+}
+
+// SyntheticCardHashFn returns a function working like SyntheticCard, but deriving
+// the synthetic number from the original one by a keyed hash, so the same input
+// is always replaced with the same number and it can't be brute-forced without the key.
+func SyntheticCardHashFn(key []byte) func(string) []byte {
+	key = append([]byte(nil), key...)
+	return func(s string) []byte {
+		return syntheticCard(s, func(digits []byte) io.Reader {
+			return mathrand.New(mathrand.NewSource(valueSeed(string(digits), key)))
+		})
+	}
+}
+
+// seededSyntheticCard returns a function like SyntheticCard generating digits
+// from the card number and the salt instead of a random source.
+func seededSyntheticCard(salt string) func(string) []byte {
+	return func(s string) []byte {
+		return syntheticCard(s, func(digits []byte) io.Reader {
+			return mathrand.New(mathrand.NewSource(valueSeed(string(digits), []byte(salt))))
+		})
+	}
+}
+
+// syntheticCard replaces digits of the card number after the BIN with digits
+// read from the source and recomputes the check digit.
+func syntheticCard(s string, source func(digits []byte) io.Reader) []byte {
+	str, ok := unquote(s)
+	if !ok {
+		if s == "null" {
+			return []byte(s)
+		}
+		str = s
+	}
+
+	digits := make([]byte, 0, len(str))
+	for i := 0; i < len(str); i++ {
+		if str[i] >= '0' && str[i] <= '9' {
+			digits = append(digits, str[i])
+		}
+	}
+	if len(digits) < 12 || (!ok && len(digits) != len(str)) {
+		if !ok {
+			return []byte(`null`)
+		}
+		res := []byte(str)
+		for i := range res {
+			if res[i] >= '0' && res[i] <= '9' {
+				res[i] = '*'
+			}
+		}
+		return quote(string(res))
+	}
+
+	random := make([]byte, len(digits))
+	if _, err := io.ReadFull(source(digits), random); err != nil {
+		return []byte(`null`)
+	}
+	for i := 6; i < len(digits)-1; i++ {
+		digits[i] = '0' + random[i]%10
+	}
+	digits[len(digits)-1] = luhnCheckDigit(digits[:len(digits)-1])
+
+	res := []byte(str)
+	pos := 0
+	for i := range res {
+		if res[i] >= '0' && res[i] <= '9' {
+			res[i] = digits[pos]
+			pos++
+		}
+	}
+
+	if !ok {
+		return res
+	}
+	return quote(string(res))
+}
+
+// luhnValid reports whether digits of s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// luhnCheckDigit returns the digit to be appended to digits to pass the Luhn check.
+func luhnCheckDigit(digits []byte) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return '0' + byte((10-sum%10)%10)
+}
+
+// ScrambleFn returns a function that deterministically shuffles characters of the
+// input string by a permutation derived from the keyed hash of the value, so it
+// can't be reversed without the key. Uppercase letters, lowercase letters and
+// digits are shuffled among positions of the same class, other characters stay
+// in place, so length and format of the value are preserved.
+func ScrambleFn(key []byte) func(string) []byte {
+	key = append([]byte(nil), key...)
+	return func(s string) []byte {
+		str, ok := unquote(s)
+		if !ok {
+			return []byte(s)
+		}
+
+		runes := []rune(str)
+		classes := map[int][]int{} // class -> positions
+		for i, r := range runes {
+			switch {
+			case unicode.IsUpper(r):
+				classes[0] = append(classes[0], i)
+			case unicode.IsLower(r):
+				classes[1] = append(classes[1], i)
+			case unicode.IsDigit(r):
+				classes[2] = append(classes[2], i)
+			}
+		}
+
+		rnd := mathrand.New(mathrand.NewSource(valueSeed(s, key)))
+		for class := 0; class < 3; class++ {
+			pos := classes[class]
+			rnd.Shuffle(len(pos), func(i, j int) {
+				runes[pos[i]], runes[pos[j]] = runes[pos[j]], runes[pos[i]]
+			})
+		}
+
+		return quote(string(runes))
+	}
+}
+
+// valueSeed returns a seed derived from the value by HMAC-SHA-256 with the key,
+// so replacements are stable across documents and runs.
+func valueSeed(value string, key []byte) int64 {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return int64(binary.BigEndian.Uint64(mac.Sum(nil)[:8]))
+}
+
+var (
+	fakeFirstNames = []string{
+		"Alex", "Anna", "Ben", "Clara", "David", "Emma", "Felix", "Grace",
+		"Henry", "Ivy", "Jack", "Julia", "Leo", "Lucy", "Max", "Mia",
+		"Noah", "Olivia", "Paul", "Sophie",
+	}
+	fakeLastNames = []string{
+		"Adams", "Baker", "Carter", "Davis", "Evans", "Fisher", "Green", "Harris",
+		"Jones", "King", "Lewis", "Miller", "Nelson", "Parker", "Reed", "Smith",
+		"Taylor", "Walker", "White", "Young",
+	}
+	fakeDomains = []string{"example.com", "example.org", "example.net"}
+)
+
+// FakeNameFn returns a function that replaces the input string with a fake full name.
+// The name is derived from the value by a keyed hash, so the same input always maps
+// to the same fake name and it can't be brute-forced without the key.
+// NULL is returned as is.
+func FakeNameFn(key []byte) func(string) []byte {
+	key = append([]byte(nil), key...)
+	return func(s string) []byte {
+		if s == "null" {
+			return []byte(s)
+		}
+		rnd := mathrand.New(mathrand.NewSource(valueSeed(s, key)))
+		return quote(fakeFirstNames[rnd.Intn(len(fakeFirstNames))] + " " + fakeLastNames[rnd.Intn(len(fakeLastNames))])
+	}
+}
+
+// FakeEmailFn returns a function that replaces the input string with a fake email
+// address at a reserved example domain. The address is derived from the value by
+// a keyed hash, so the same input always maps to the same fake address and it
+// can't be brute-forced without the key. NULL is returned as is.
+func FakeEmailFn(key []byte) func(string) []byte {
+	key = append([]byte(nil), key...)
+	return func(s string) []byte {
+		if s == "null" {
+			return []byte(s)
+		}
+		rnd := mathrand.New(mathrand.NewSource(valueSeed(s, key)))
+		first := strings.ToLower(fakeFirstNames[rnd.Intn(len(fakeFirstNames))])
+		last := strings.ToLower(fakeLastNames[rnd.Intn(len(fakeLastNames))])
+		return quote(first + "." + last + strconv.Itoa(rnd.Intn(100)) + "@" + fakeDomains[rnd.Intn(len(fakeDomains))])
+	}
+}
@@ -1,7 +1,15 @@
 package jsonmask
 
 import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode"
+
+	"github.com/tidwall/gjson"
 )
 
 func TestUpper(t *testing.T) {
@@ -79,6 +87,61 @@ func TestPrefixFn(t *testing.T) {
 	}
 }
 
+func TestMaskStyle(t *testing.T) {
+	style := MaskStyle{Char: '•', Ellipsis: "…"}
+
+	tests := []struct {
+		name     string
+		fn       func(string) []byte
+		input    string
+		expected string
+	}{
+		{"prefix", PrefixStyleFn(3, true, style), `"hello"`, `"hel…"`},
+		{"prefix quote", PrefixStyleFn(3, true, MaskStyle{Ellipsis: `"`}), `"hello"`, `"hel\""`},
+		{"email", EmailFn(style), `"john@example.com"`, `"j••n@e••••••.com"`},
+		{"email invalid", EmailFn(style), `"john"`, `"invalid_email_format"`},
+		{"passport", PassportFn(style), `"CZE12345678"`, `"CZE••••••78"`},
+		{"nationalID", NationalIDStyleFn("US", style), `"123-45-6789"`, `"•••-••-6789"`},
+		{"email zero char", EmailFn(MaskStyle{}), `"john@example.com"`, `"j**n@e******.com"`},
+		{"email quote char", EmailFn(MaskStyle{Char: '"'}), `"john@example.com"`, `"j\"\"n@e\"\"\"\"\"\".com"`},
+		{"passport backslash char", PassportFn(MaskStyle{Char: '\\'}), `"CZE12345678"`, `"CZE\\\\\\\\\\\\78"`},
+		{"nationalID zero char", NationalIDStyleFn("US", MaskStyle{}), `"123-45-6789"`, `"***-**-6789"`},
+	}
+
+	for _, tt := range tests {
+		result := string(tt.fn(tt.input))
+		if result != tt.expected {
+			t.Errorf("%s(%s) = %s; want %s", tt.name, tt.input, result, tt.expected)
+		}
+		if !gjson.Valid(result) {
+			t.Errorf("%s(%s) = %s; invalid JSON", tt.name, tt.input, result)
+		}
+	}
+}
+
+func TestTranslit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Dvořák"`, `"Dvorak"`},
+		{`"Łukasz Żółć"`, `"Lukasz Zolc"`},
+		{`"Straße, Ærøskøbing"`, `"Strasse, AEroskobing"`},
+		{`"Ünal Şahin"`, `"Unal Sahin"`},
+		{`"東京"`, `"??"`},
+		{`"plain"`, `"plain"`},
+		{`123`, `123`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(Translit(tt.input))
+		if result != tt.expected {
+			t.Errorf("Translit(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -152,3 +215,657 @@ func TestZero(t *testing.T) {
 		}
 	}
 }
+
+func TestPassport(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"CZE12345678"`, `"CZE******78"`},
+		{`"AB1234567"`, `"AB*****67"`},
+		{`"123456789"`, `"*******89"`},
+		{`"C01X00T47"`, `"C******47"`},
+		{`"AB 123-456"`, `"AB ***-*56"`},
+		{`"AB1"`, `"***"`},
+		{`"\u0043ZE12345678"`, `"CZE******78"`},
+		{`"AB\"1234567"`, `"AB\"*****67"`},
+		{`""`, `""`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(Passport(tt.input))
+		if result != tt.expected {
+			t.Errorf("Passport(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"John Ronald Reuel Tolkien"`, `"J.R.R.T."`},
+		{`"jean-luc  picard"`, `"J.L.P."`},
+		{`"Łukasz Żak"`, `"Ł.Ż."`},
+		{`"Šimon Dvořák"`, `"Š.D."`},
+		{`"\u0160imon Dvo\u0159\u00e1k"`, `"Š.D."`},
+		{`""`, `""`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(Initials(tt.input))
+		if result != tt.expected {
+			t.Errorf("Initials(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"221B Baker Street, London NW1 6XE, United Kingdom"`, `"London, United Kingdom"`},
+		{`"Unter den Linden 77, 10117 Berlin, Germany"`, `"Berlin, Germany"`},
+		{`"Main Street 1, Prague"`, `"Prague"`},
+		{`"Main Street 1"`, `""`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(Address(tt.input))
+		if result != tt.expected {
+			t.Errorf("Address(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestAddressObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`{"street":"Baker Street","houseNumber":221,"postcode":"NW1 6XE","city":"London","country":"UK"}`,
+			`{"street":"","houseNumber":null,"postcode":"","city":"London","country":"UK"}`,
+		},
+		{`{"City":"Prague","countryCode":"CZ"}`, `{"City":"Prague","countryCode":"CZ"}`},
+		{`{}`, `{}`},
+		{`"London"`, `"London"`},
+	}
+
+	for _, tt := range tests {
+		result := string(AddressObject(tt.input))
+		if result != tt.expected {
+			t.Errorf("AddressObject(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestAgeBucketFn(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	tests := []struct {
+		width    int
+		input    string
+		expected string
+	}{
+		{10, `"1990-04-30"`, `"30-39"`},
+		{10, `"1984-05-02"`, `"30-39"`},
+		{10, `"1984-05-01T10:00:00Z"`, `"40-49"`},
+		{5, `"2001-01-01"`, `"20-24"`},
+		{10, `"2030-01-01"`, `"0-9"`},
+		{10, `"01/02/1990"`, `"invalid_date_format"`},
+		{10, `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(AgeBucketFn(tt.width)(tt.input))
+		if result != tt.expected {
+			t.Errorf("AgeBucketFn(%d)(%s) = %s; want %s", tt.width, tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestAmountFn(t *testing.T) {
+	tests := []struct {
+		precision float64
+		input     string
+		expected  string
+	}{
+		{100, `1234.56`, `1200`},
+		{100, `1250`, `1300`},
+		{100, `-1234.56`, `-1200`},
+		{100, `-12`, `0`},
+		{1000, `1e6`, `1000000`},
+		{0.1, `12.345`, `12.3`},
+		{0.05, `12.345`, `12.35`},
+		{100, `"1234"`, `"1234"`},
+		{100, `null`, `null`},
+		{1e-300, `1e300`, `1e300`},
+	}
+
+	for _, tt := range tests {
+		result := string(AmountFn(tt.precision)(tt.input))
+		if result != tt.expected {
+			t.Errorf("AmountFn(%v)(%s) = %s; want %s", tt.precision, tt.input, result, tt.expected)
+		}
+	}
+	for _, precision := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("AmountFn(%v) didn't panic", precision)
+				}
+			}()
+			AmountFn(precision)
+		}()
+	}
+}
+
+func TestQuotedFn(t *testing.T) {
+	tests := []struct {
+		f        func(string) []byte
+		input    string
+		expected string
+	}{
+		{Zero, `"123.45"`, `"0"`},
+		{AmountFn(100), `"1234.56"`, `"1200"`},
+		{Magnitude, `"-532"`, `"-100"`},
+		{Magnitude, `532`, `100`},
+		{Null, `"1"`, `null`},
+		{Zero, `"12 apples"`, `"12 apples"`},
+		{Zero, `" 12"`, `" 12"`},
+		{Zero, `"NaN"`, `"NaN"`},
+		{Zero, `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(QuotedFn(tt.f)(tt.input))
+		if result != tt.expected {
+			t.Errorf("QuotedFn(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestStringFn(t *testing.T) {
+	reverse := StringFn(func(s string) string {
+		runes := []rune(s)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes)
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc"`, `"cba"`},
+		{`"a\"b"`, `"b\"a"`},
+		{`"\u00e9x"`, `"xé"`},
+		{`"<a\nb>"`, `">b\na<"`},
+		{`123`, `123`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(reverse(tt.input))
+		if result != tt.expected {
+			t.Errorf("StringFn(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestNationalIDFn(t *testing.T) {
+	tests := []struct {
+		country  string
+		input    string
+		expected string
+	}{
+		{"CZ", `"905512/1234"`, `"******/*234"`},
+		{"pl", `"44051401359"`, `"********359"`},
+		{"DE", `"65 929 970 489"`, `"** *** *** 489"`},
+		{"US", `"123-45-6789"`, `"***-**-6789"`},
+		{"GB", `"QQ 12 34 56 C"`, `"QQ ** ** ** C"`},
+		{"ES", `"12345678Z"`, `"********Z"`},
+		{"XX", `"AB123"`, `"***23"`},
+		{"US", `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(NationalIDFn(tt.country)(tt.input))
+		if result != tt.expected {
+			t.Errorf("NationalIDFn(%s)(%s) = %s; want %s", tt.country, tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestUUID(t *testing.T) {
+	re := regexp.MustCompile(`^"[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}"$`)
+
+	first := string(UUID(`"john"`))
+	if !re.MatchString(first) {
+		t.Errorf("UUID(%q) = %q; want UUID v4", `"john"`, first)
+	}
+	if second := string(UUID(`"john"`)); second == first {
+		t.Errorf("UUID(%q) returned the same value twice: %q", `"john"`, first)
+	}
+	if result := string(UUID(`null`)); result != `null` {
+		t.Errorf("UUID(null) = %q; want null", result)
+	}
+}
+
+func TestUUIDHashFn(t *testing.T) {
+	re := regexp.MustCompile(`^"[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}"$`)
+	uuidHash := UUIDHashFn([]byte("key"))
+
+	first := string(uuidHash(`"john"`))
+	if !re.MatchString(first) {
+		t.Errorf("UUIDHashFn(key)(%q) = %q; want UUID v8", `"john"`, first)
+	}
+	if second := string(uuidHash(`"john"`)); second != first {
+		t.Errorf("UUIDHashFn(key)(%q) = %q; want %q", `"john"`, second, first)
+	}
+	if other := string(uuidHash(`"jane"`)); other == first {
+		t.Errorf("UUIDHashFn(key)(%q) = %q; want different from %q", `"jane"`, other, first)
+	}
+	if other := string(UUIDHashFn([]byte("other"))(`"john"`)); other == first {
+		t.Errorf("UUIDHashFn(other)(%q) = %q; want different from %q", `"john"`, other, first)
+	}
+}
+
+func TestStripHTMLFn(t *testing.T) {
+	tests := []struct {
+		maxLen   int
+		input    string
+		expected string
+	}{
+		{0, `"<p>Hello <b>world</b></p>"`, `"Hello world"`},
+		{0, `"<div class=\"x\">Tom &amp; Jerry</div>\n<br/>bye"`, `"Tom & Jerry bye"`},
+		{0, `"before<script type=\"text/javascript\">alert(1)</script>after"`, `"before after"`},
+		{0, `"<STYLE>p{}</STYLE>text"`, `"text"`},
+		{0, `"broken <b"`, `"broken"`},
+		{5, `"<p>Hello world</p>"`, `"Hello"`},
+		{3, `"<i>Žluťoučký</i>"`, `"Žlu"`},
+		{0, `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(StripHTMLFn(tt.maxLen)(tt.input))
+		if result != tt.expected {
+			t.Errorf("StripHTMLFn(%d)(%s) = %s; want %s", tt.maxLen, tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestArrayLimitFn(t *testing.T) {
+	tests := []struct {
+		n         int
+		addMarker bool
+		input     string
+		expected  string
+	}{
+		{2, false, `[1,2,3,4]`, `[1,2]`},
+		{2, true, `[{"a":1},{"a":2},{"a":3},{"a":4}]`, `[{"a":1},{"a":2},"…and 2 more"]`},
+		{0, true, `[1,2]`, `["…and 2 more"]`},
+		{0, false, `[1,2]`, `[]`},
+		{5, true, `[1, 2]`, `[1, 2]`},
+		{2, true, `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(ArrayLimitFn(tt.n, tt.addMarker)(tt.input))
+		if result != tt.expected {
+			t.Errorf("ArrayLimitFn(%d, %t)(%s) = %s; want %s", tt.n, tt.addMarker, tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[1,2,3]`, `3`},
+		{`[{"id":1},{"id":2}]`, `2`},
+		{`[]`, `0`},
+		{`null`, `null`},
+		{`"abc"`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(Count(tt.input))
+		if result != tt.expected {
+			t.Errorf("Count(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a":1,"b":{"c":2}}`, `{"masked":true,"fields":2}`},
+		{`{}`, `{"masked":true,"fields":0}`},
+		{`[1,2]`, `{"masked":true}`},
+		{`"abc"`, `{"masked":true}`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(Summary(tt.input))
+		if result != tt.expected {
+			t.Errorf("Summary(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestTrueFalse(t *testing.T) {
+	tests := []struct {
+		input string
+	}{
+		{`true`},
+		{`false`},
+		{`null`},
+	}
+
+	for _, tt := range tests {
+		if result := string(True(tt.input)); result != `true` {
+			t.Errorf("True(%q) = %q; want true", tt.input, result)
+		}
+		if result := string(False(tt.input)); result != `false` {
+			t.Errorf("False(%q) = %q; want false", tt.input, result)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`123.45`, `1`},
+		{`-0.5`, `-1`},
+		{`0`, `0`},
+		{`null`, `null`},
+		{`"12"`, `"12"`},
+	}
+
+	for _, tt := range tests {
+		result := string(Sign(tt.input))
+		if result != tt.expected {
+			t.Errorf("Sign(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestLaplaceFn(t *testing.T) {
+	f := LaplaceFn(0.5, 1) // scale 2
+
+	const n = 5000
+	var sum, absSum float64
+	for i := 0; i < n; i++ {
+		result := string(f(`100`))
+		v, err := strconv.Atoi(result)
+		if err != nil {
+			t.Fatalf("LaplaceFn(%s) = %s; want an integer", `100`, result)
+		}
+		sum += float64(v)
+		absSum += math.Abs(float64(v) - 100)
+	}
+	if mean := sum / n; math.Abs(mean-100) > 0.3 {
+		t.Errorf("mean of noisy values = %v; want about 100", mean)
+	}
+	// mean absolute deviation of Laplace noise is the scale, rounding adds a bit
+	if mad := absSum / n; math.Abs(mad-2) > 0.3 {
+		t.Errorf("mean absolute deviation = %v; want about 2", mad)
+	}
+
+	if result := string(f(`12.5`)); result == `12.5` {
+		t.Errorf("LaplaceFn(12.5) = %s; want noise added", result)
+	} else if _, err := strconv.ParseFloat(result, 64); err != nil {
+		t.Errorf("LaplaceFn(12.5) = %s; want a number", result)
+	}
+	for _, input := range []string{`"100"`, `null`, `true`} {
+		if result := string(f(input)); result != input {
+			t.Errorf("LaplaceFn(%s) = %s; want %s", input, result, input)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if result := string(LaplaceFn(1, 1e308)(`1.7e308`)); !gjson.Valid(result) {
+			t.Fatalf("LaplaceFn(1.7e308) = %s; want valid JSON", result)
+		}
+	}
+
+	for _, args := range [][2]float64{{0, 1}, {-1, 1}, {math.NaN(), 1}, {math.Inf(1), 1}, {1, 0}, {1, math.NaN()}, {1, math.Inf(1)}, {1e-320, 1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("LaplaceFn(%v, %v) didn't panic", args[0], args[1])
+				}
+			}()
+			LaplaceFn(args[0], args[1])
+		}()
+	}
+}
+
+func TestTombstoneFn(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*3600)) }
+	defer func() { timeNow = time.Now }()
+
+	tests := []struct {
+		policy   string
+		input    string
+		expected string
+	}{
+		{"gdpr-30d", `"john@example.com"`, `{"erased":true,"at":"2024-05-01T10:30:00Z","policy":"gdpr-30d"}`},
+		{"", `{"a":1}`, `{"erased":true,"at":"2024-05-01T10:30:00Z"}`},
+		{`p"1`, `42`, `{"erased":true,"at":"2024-05-01T10:30:00Z","policy":"p\"1"}`},
+		{"gdpr-30d", `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(TombstoneFn(tt.policy)(tt.input))
+		if result != tt.expected {
+			t.Errorf("TombstoneFn(%q)(%s) = %s; want %s", tt.policy, tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestMagnitude(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`12345`, `10000`},
+		{`-532`, `-100`},
+		{`1000`, `1000`},
+		{`0.05`, `0.01`},
+		{`7`, `1`},
+		{`0`, `0`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(Magnitude(tt.input))
+		if result != tt.expected {
+			t.Errorf("Magnitude(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		label    string
+	}{
+		{`"hello"`, `5`, `"<len=5>"`},
+		{`"Dvořák"`, `6`, `"<len=6>"`},
+		{`"a\"b"`, `3`, `"<len=3>"`},
+		{`""`, `0`, `"<len=0>"`},
+		{`null`, `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		if result := string(Length(tt.input)); result != tt.expected {
+			t.Errorf("Length(%q) = %q; want %q", tt.input, result, tt.expected)
+		}
+		if result := string(LengthLabel(tt.input)); result != tt.label {
+			t.Errorf("LengthLabel(%q) = %q; want %q", tt.input, result, tt.label)
+		}
+	}
+}
+
+func TestRegexFn(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		replacement string
+		input       string
+		expected    string
+	}{
+		{`\d`, `*`, `"+420 123-456"`, `"+*** ***-***"`},
+		{`(\d{4})\d{8}(\d{4})`, `$1********$2`, `"4111111111111111"`, `"4111********1111"`},
+		{`token=\w+`, `token=***`, `"a=1&token=abc"`, `"a=1&token=***"`},
+		{`\d`, `*`, `123`, `123`},
+		{`\d`, `*`, `null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result := string(RegexFn(tt.pattern, tt.replacement)(tt.input))
+		if result != tt.expected {
+			t.Errorf("RegexFn(%q, %q)(%s) = %s; want %s", tt.pattern, tt.replacement, tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestSyntheticCard(t *testing.T) {
+	tests := []struct {
+		input   string
+		pattern string
+	}{
+		{`"4111111111111111"`, `^"411111\d{10}"$`},
+		{`"5500 0000 0000 0004"`, `^"5500 00\d\d \d{4} \d{4}"$`},
+		{`"3782-822463-10005"`, `^"3782-82\d{4}-\d{5}"$`},
+		{`4111111111111111`, `^411111\d{10}$`},
+	}
+
+	for _, tt := range tests {
+		result := string(SyntheticCard(tt.input))
+		if !regexp.MustCompile(tt.pattern).MatchString(result) {
+			t.Errorf("SyntheticCard(%s) = %s; want match %s", tt.input, result, tt.pattern)
+		}
+		if !luhnValid(result) {
+			t.Errorf("SyntheticCard(%s) = %s; want Luhn valid number", tt.input, result)
+		}
+	}
+
+	short := []struct {
+		input    string
+		expected string
+	}{
+		{`"12345"`, `"*****"`},
+		{`"4111-1111"`, `"****-****"`},
+		{`12345`, `null`},
+		{`4.111111111111111e15`, `null`},
+		{`null`, `null`},
+	}
+	for _, tt := range short {
+		if result := string(SyntheticCard(tt.input)); result != tt.expected {
+			t.Errorf("SyntheticCard(%s) = %s; want %s", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestScrambleFn(t *testing.T) {
+	tests := []string{
+		`"John Doe 1984"`,
+		`"AB-123-cd"`,
+		`"Žluťoučký kůň"`,
+	}
+
+	for _, input := range tests {
+		result := string(ScrambleFn([]byte("key"))(input))
+		if again := string(ScrambleFn([]byte("key"))(input)); again != result {
+			t.Errorf("ScrambleFn(key)(%s) = %s and %s; want deterministic result", input, result, again)
+		}
+
+		in, _ := unquote(input)
+		out, _ := unquote(result)
+		inRunes, outRunes := []rune(in), []rune(out)
+		if len(inRunes) != len(outRunes) {
+			t.Fatalf("ScrambleFn(key)(%s) = %s; want the same length", input, result)
+		}
+		for i := range inRunes {
+			if unicode.IsUpper(inRunes[i]) != unicode.IsUpper(outRunes[i]) ||
+				unicode.IsLower(inRunes[i]) != unicode.IsLower(outRunes[i]) ||
+				unicode.IsDigit(inRunes[i]) != unicode.IsDigit(outRunes[i]) {
+				t.Errorf("ScrambleFn(key)(%s) = %s; character class changed at %d", input, result, i)
+			}
+		}
+	}
+
+	if result := string(ScrambleFn([]byte("key"))(`"abcdefgh"`)); result == `"abcdefgh"` {
+		t.Errorf("ScrambleFn(key)(%s) = %s; want shuffled value", `"abcdefgh"`, result)
+	}
+	if a, b := string(ScrambleFn([]byte("key"))(`"abcdefgh"`)), string(ScrambleFn([]byte("other"))(`"abcdefgh"`)); a == b {
+		t.Errorf("ScrambleFn(key)(%s) = %s for different keys; want permutation depending on the key", `"abcdefgh"`, a)
+	}
+	if a, b := string(ScrambleFn([]byte("key"))(`"abcdefgh"`)), string(ScrambleFn([]byte("key"))(`"abcdefgi"`)); a[1:8] == b[1:8] {
+		t.Errorf("ScrambleFn(key) = %s and %s; want permutation depending on the value", a, b)
+	}
+	if result := string(ScrambleFn([]byte("key"))(`null`)); result != `null` {
+		t.Errorf("ScrambleFn(key)(null) = %s; want null", result)
+	}
+}
+
+func TestSyntheticCardHashFn(t *testing.T) {
+	first := string(SyntheticCardHashFn([]byte("key"))(`"4111 1111 1111 1111"`))
+	if !regexp.MustCompile(`^"4111 11\d\d \d{4} \d{4}"$`).MatchString(first) || !luhnValid(first) {
+		t.Errorf("SyntheticCardHashFn(key)(%s) = %s; want Luhn valid number with the same BIN", `"4111 1111 1111 1111"`, first)
+	}
+	if again := string(SyntheticCardHashFn([]byte("key"))(`"4111111111111111"`)); again != strings.ReplaceAll(first, " ", "") {
+		t.Errorf("SyntheticCardHashFn(key)(%s) = %s; want %s", `"4111111111111111"`, again, strings.ReplaceAll(first, " ", ""))
+	}
+}
+
+func TestFakeNameFn(t *testing.T) {
+	re := regexp.MustCompile(`^"[A-Z][a-z]+ [A-Z][a-z]+"$`)
+
+	first := string(FakeNameFn([]byte("key"))(`"John Doe"`))
+	if !re.MatchString(first) {
+		t.Errorf("FakeNameFn(salt)(%s) = %s; want fake name", `"John Doe"`, first)
+	}
+	if again := string(FakeNameFn([]byte("key"))(`"John Doe"`)); again != first {
+		t.Errorf("FakeNameFn(salt)(%s) = %s; want %s", `"John Doe"`, again, first)
+	}
+	if result := string(FakeNameFn([]byte("key"))(`null`)); result != `null` {
+		t.Errorf("FakeNameFn(salt)(null) = %s; want null", result)
+	}
+}
+
+func TestFakeEmailFn(t *testing.T) {
+	re := regexp.MustCompile(`^"[a-z]+\.[a-z]+\d{1,2}@example\.(com|org|net)"$`)
+
+	first := string(FakeEmailFn([]byte("key"))(`"john@doe.com"`))
+	if !re.MatchString(first) {
+		t.Errorf("FakeEmailFn()(%s) = %s; want fake email", `"john@doe.com"`, first)
+	}
+	if again := string(FakeEmailFn([]byte("key"))(`"john@doe.com"`)); again != first {
+		t.Errorf("FakeEmailFn()(%s) = %s; want %s", `"john@doe.com"`, again, first)
+	}
+
+	seen := map[string]bool{}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		seen[string(FakeEmailFn([]byte(key))(`"john@doe.com"`))] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("FakeEmailFn(key) ignores the key")
+	}
+}
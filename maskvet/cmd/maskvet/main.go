@@ -0,0 +1,11 @@
+// Command maskvet checks mask tags of struct fields, see package maskvet.
+package main
+
+import (
+	"github.com/axkit/jsonmask/maskvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(maskvet.Analyzer)
+}
@@ -0,0 +1,22 @@
+module github.com/axkit/jsonmask/maskvet
+
+go 1.22.0
+
+require (
+	github.com/axkit/jsonmask v0.0.0
+	golang.org/x/tools v0.26.0
+)
+
+require (
+	github.com/fxamacker/cbor/v2 v2.7.0 // indirect
+	github.com/tidwall/gjson v1.18.0 // indirect
+	github.com/tidwall/match v1.1.1 // indirect
+	github.com/tidwall/pretty v1.2.0 // indirect
+	github.com/tidwall/sjson v1.2.5 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	go.mongodb.org/mongo-driver/v2 v2.0.0 // indirect
+	golang.org/x/mod v0.21.0 // indirect
+	golang.org/x/sync v0.9.0 // indirect
+)
+
+replace github.com/axkit/jsonmask => ../
@@ -0,0 +1,123 @@
+// Package maskvet provides an analyzer statically checking mask tags of struct
+// fields, catching mistakes ParseStruct would silently skip at runtime:
+// actions unknown to a default masker, mask tags of unexported fields and
+// the deletion action "-" combined with other actions or options.
+//
+// It's a separate module keeping golang.org/x/tools out of dependencies of
+// jsonmask. It runs as a go vet tool built from the repository:
+//
+//	cd maskvet && go install ./cmd/maskvet
+//	go vet -vettool=$(which maskvet) -funcs=phone,iban ./...
+package maskvet
+
+import (
+	"go/ast"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/axkit/jsonmask"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer checks mask tags of struct fields.
+var Analyzer = &analysis.Analyzer{
+	Name: "maskvet",
+	Doc:  "check mask tags of struct fields",
+	Run:  run,
+}
+
+var (
+	tagName string // name of the struct field tag
+	funcs   string // comma separated names of custom actions
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&tagName, "tag", jsonmask.DefaultStructFieldTag, "name of the struct field tag")
+	Analyzer.Flags.StringVar(&funcs, "funcs", "", "comma separated names of custom actions registered by AddFunc or AddFuncFactory")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	jm := jsonmask.New()
+	for _, name := range strings.Split(funcs, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			jm.AddFunc(name, jsonmask.Null)
+			jm.AddFuncFactory(name, func(string) (func(string) []byte, error) { return jsonmask.Null, nil })
+		}
+	}
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if st, ok := n.(*ast.StructType); ok {
+				for _, field := range st.Fields.List {
+					checkField(pass, jm, field)
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// deletionOptions holds mask tag options meaningful with the deletion action.
+var deletionOptions = map[string]bool{
+	"keys": true, "sample": true, "when": true, "class": true,
+	"desc": true, "owner": true, "classification": true,
+}
+
+// checkField reports problems of the mask tag of the field.
+func checkField(pass *analysis.Pass, jm *jsonmask.JsonMaskerImpl, field *ast.Field) {
+	if field.Tag == nil {
+		return
+	}
+	tags, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return
+	}
+	tag, ok := reflect.StructTag(tags).Lookup(tagName)
+	if !ok {
+		return
+	}
+
+	for _, name := range field.Names {
+		if !name.IsExported() {
+			pass.Reportf(field.Tag.Pos(), "%s tag of unexported field %s is ignored", tagName, name.Name)
+		}
+	}
+
+	action, opts := jsonmask.ParseMaskTag(tag)
+	if action == "-" {
+		names := make([]string, 0, len(opts))
+		for name := range opts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if deletionOptions[name] {
+				continue
+			}
+			if value := opts[name]; value != "" {
+				name += "=" + value
+			}
+			pass.Reportf(field.Tag.Pos(), "%s tag %q combines deletion with %q", tagName, tag, name)
+		}
+		return
+	}
+
+	actions := []string{action}
+	if override := opts["override"]; override != "" && override != "none" {
+		actions = append(actions, override)
+	}
+	for _, a := range actions {
+		if a == "" || a == "-" {
+			continue
+		}
+		for _, f := range jm.Lint([]jsonmask.Rule{{Path: "field", Action: a}}) {
+			if f.Code == jsonmask.LintUnknownAction {
+				pass.Reportf(field.Tag.Pos(), "%s tag %q has unknown action %q", tagName, tag, a)
+			}
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package maskvet_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask/maskvet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	if err := maskvet.Analyzer.Flags.Set("funcs", "custom"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), maskvet.Analyzer, "a")
+}
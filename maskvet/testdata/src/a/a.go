@@ -0,0 +1,21 @@
+package a
+
+type Customer struct {
+	Email    string            `json:"email" mask:"email"`
+	Phone    string            `json:"phone" mask:"phone"` // want `mask tag "phone" has unknown action "phone"`
+	Card     string            `json:"card" mask:"limit(4,...)"`
+	Secret   string            `json:"secret" mask:"-"`
+	Meta     map[string]string `json:"meta" mask:"-,keys"`
+	Token    string            `json:"token" mask:"-,email"`           // want `mask tag "-,email" combines deletion with "email"`
+	Balance  int               `json:"balance,string" mask:"-,quoted"` // want `mask tag "-,quoted" combines deletion with "quoted"`
+	Address  Address           `json:"address" mask:"override=hide"`   // want `mask tag "override=hide" has unknown action "hide"`
+	Billing  Address           `json:"billing" mask:"override=none"`
+	password string            `mask:"truncate"` // want `mask tag of unexported field password is ignored`
+	Name     string            `json:"name"`
+	Custom   string            `mask:"custom"`
+	Phone2   string            `mask:"-,when=!consents.marketing==true,class=contact"`
+}
+
+type Address struct {
+	Street string `mask:"address"`
+}
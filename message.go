@@ -0,0 +1,152 @@
+package jsonmask
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// MessageRules holds rules of SQS and SNS message scrubbing by MaskMessages.
+type MessageRules struct {
+	// Sources holds rules of JSON message bodies by queue or topic name, e.g. "orders".
+	Sources map[string]StructMaskRules
+
+	// Default holds rules of bodies of queues and topics not listed.
+	Default StructMaskRules
+
+	// Attributes holds names of message attributes whose string values are
+	// masked by the Action.
+	Attributes []string
+
+	// Action masks values of message attributes, "truncate" by default.
+	// Results other than strings are converted to strings.
+	Action string
+}
+
+// messageBodies holds attributes of message bodies in SQS and SNS documents.
+var messageBodies = []string{"body", "Body", "MessageBody", "Message"}
+
+// messageSources holds attributes naming the queue or the topic of messages.
+var messageSources = []string{"eventSourceARN", "TopicArn", "QueueUrl"}
+
+// MaskMessages masks SQS and SNS messages of the document, so DLQ dumps and
+// consumer logs stay clean: JSON bodies by rules of their queue or topic and
+// string values of configured message attributes. Documents of receive paths
+// (Lambda SQS and SNS events, ReceiveMessage responses, SNS notifications) and
+// publish paths (SendMessage, SendMessageBatch, Publish and PublishBatch
+// inputs) are recognized. SNS notifications delivered to SQS queues are masked
+// by rules of the topic. Bodies not being JSON are kept as is.
+func (jm *JsonMaskerImpl) MaskMessages(doc []byte, rules MessageRules) ([]byte, error) {
+	if jm.disabled {
+		return doc, nil
+	}
+	if !gjson.ValidBytes(doc) {
+		return nil, ErrInvalidJSON
+	}
+
+	maskValue, err := jm.stringMasker(rules.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	root := gjson.ParseBytes(doc)
+	paths := []string{""}
+	for _, list := range []string{"Records", "Messages", "Entries", "PublishBatchRequestEntries"} {
+		for i := range root.Get(list).Array() {
+			item := list + "." + strconv.Itoa(i)
+			paths = append(paths, item, item+".Sns")
+		}
+	}
+
+	defaultSource := messageSource(root)
+	for _, path := range paths {
+		msg := root
+		if path != "" {
+			msg = root.Get(path)
+		}
+		if !msg.IsObject() {
+			continue
+		}
+
+		source := messageSource(msg)
+		if source == "" {
+			source = defaultSource
+		}
+		if doc, err = jm.maskMessage(doc, path, msg, source, rules, maskValue); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// maskMessage masks the body and attributes of the message found by the path.
+func (jm *JsonMaskerImpl) maskMessage(doc []byte, path string, msg gjson.Result, source string, rules MessageRules, maskValue func(string) string) ([]byte, error) {
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+
+	var err error
+	for _, attr := range messageBodies {
+		body := msg.Get(attr)
+		if body.Type != gjson.String || !gjson.Valid(body.Str) {
+			continue
+		}
+
+		var masked []byte
+		if notification := gjson.Parse(body.Str); notification.Get("TopicArn").Exists() && notification.Get("Message").Exists() {
+			masked, err = jm.MaskMessages([]byte(body.Str), rules)
+		} else {
+			smr, ok := rules.Sources[source]
+			if !ok {
+				smr = rules.Default
+			}
+			masked, err = jm.Mask([]byte(body.Str), smr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = sjson.SetBytes(doc, prefix+attr, string(masked)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rules.Attributes) == 0 {
+		return doc, nil
+	}
+	for _, attrs := range []string{"messageAttributes", "MessageAttributes"} {
+		msg.Get(attrs).ForEach(func(name, attr gjson.Result) bool {
+			if !hasName(rules.Attributes, name.Str, false) {
+				return true
+			}
+			for _, key := range []string{"stringValue", "StringValue", "Value"} {
+				value := attr.Get(key)
+				if value.Type != gjson.String {
+					continue
+				}
+				valuePath := prefix + attrs + "." + pathEscaper.Replace(name.Str) + "." + key
+				if doc, err = sjson.SetBytes(doc, valuePath, maskValue(value.Str)); err != nil {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// messageSource returns the name of the queue or the topic of the message,
+// the last part of its ARN or URL, or an empty string if it's not known.
+func messageSource(msg gjson.Result) string {
+	for _, attr := range messageSources {
+		if s := msg.Get(attr).Str; s != "" {
+			return s[strings.LastIndexAny(s, ":/")+1:]
+		}
+	}
+	return ""
+}
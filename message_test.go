@@ -0,0 +1,67 @@
+package jsonmask_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskMessages(t *testing.T) {
+	jm := jsonmask.New()
+	rules := jsonmask.MessageRules{
+		Sources: map[string]jsonmask.StructMaskRules{
+			"orders": {Rules: []jsonmask.Rule{{Path: "card", Action: "-"}}},
+			"users":  {Rules: []jsonmask.Rule{{Path: "email", Action: "email"}}},
+		},
+		Default:    jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "ssn", Action: "-"}}},
+		Attributes: []string{"customerEmail"},
+	}
+
+	notification := `{"Type":"Notification","TopicArn":"arn:aws:sns:eu-west-1:123456789012:users","Message":"{\"email\":\"john@example.com\"}"}`
+
+	tests := []struct {
+		name, doc, expected string
+	}{
+		{
+			"lambda sqs event",
+			`{"Records":[
+				{"messageId":"1","body":"{\"id\":1,\"card\":\"4111\"}","eventSourceARN":"arn:aws:sqs:eu-west-1:123456789012:orders",
+					"messageAttributes":{"customerEmail":{"stringValue":"john@example.com","dataType":"String"}}},
+				{"messageId":"2","body":` + strconv.Quote(notification) + `,"eventSourceARN":"arn:aws:sqs:eu-west-1:123456789012:fanout"},
+				{"messageId":"3","body":"not json","eventSourceARN":"arn:aws:sqs:eu-west-1:123456789012:orders"}
+			]}`,
+			`{"Records":[
+				{"messageId":"1","body":"{\"id\":1}","eventSourceARN":"arn:aws:sqs:eu-west-1:123456789012:orders",
+					"messageAttributes":{"customerEmail":{"stringValue":"","dataType":"String"}}},
+				{"messageId":"2","body":` + strconv.Quote(`{"Type":"Notification","TopicArn":"arn:aws:sns:eu-west-1:123456789012:users","Message":"{\"email\":\"j**n@e******.com\"}"}`) + `,"eventSourceARN":"arn:aws:sqs:eu-west-1:123456789012:fanout"},
+				{"messageId":"3","body":"not json","eventSourceARN":"arn:aws:sqs:eu-west-1:123456789012:orders"}
+			]}`,
+		},
+		{
+			"lambda sns event",
+			`{"Records":[{"EventSource":"aws:sns","Sns":{"TopicArn":"arn:aws:sns:eu-west-1:123456789012:users","Message":"{\"email\":\"john@example.com\"}",
+				"MessageAttributes":{"customerEmail":{"Type":"String","Value":"john@example.com"}}}}]}`,
+			`{"Records":[{"EventSource":"aws:sns","Sns":{"TopicArn":"arn:aws:sns:eu-west-1:123456789012:users","Message":"{\"email\":\"j**n@e******.com\"}",
+				"MessageAttributes":{"customerEmail":{"Type":"String","Value":""}}}}]}`,
+		},
+		{
+			"send message batch",
+			`{"QueueUrl":"https://sqs.eu-west-1.amazonaws.com/123456789012/orders","Entries":[{"Id":"a","MessageBody":"{\"card\":\"4111\"}"}]}`,
+			`{"QueueUrl":"https://sqs.eu-west-1.amazonaws.com/123456789012/orders","Entries":[{"Id":"a","MessageBody":"{}"}]}`,
+		},
+		{
+			"receive message of unknown queue",
+			`{"Messages":[{"MessageId":"1","Body":"{\"ssn\":\"123-45-6789\",\"name\":\"John\"}"}]}`,
+			`{"Messages":[{"MessageId":"1","Body":"{\"name\":\"John\"}"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := jm.MaskMessages([]byte(tt.doc), rules)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(res))
+		})
+	}
+}
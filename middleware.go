@@ -0,0 +1,54 @@
+package jsonmask
+
+import "github.com/tidwall/gjson"
+
+// Middleware wraps the masking function of the action, e.g. to measure
+// its duration or to validate its output. It's called for every rule
+// applied, so it should be cheap to build.
+type Middleware func(action string, next func(string) []byte) func(string) []byte
+
+// WrapFunc adds middleware layered around every masking function, registered
+// and resolved by factories. Middleware added later wraps earlier ones.
+// Middleware of the parent instance wraps middleware of its tenants.
+func (jm *JsonMaskerImpl) WrapFunc(mw ...Middleware) {
+	jm.middleware = append(jm.middleware, mw...)
+}
+
+// wrapFunc returns the masking function of the action wrapped by middleware.
+func (jm *JsonMaskerImpl) wrapFunc(action string, f func(string) []byte) func(string) []byte {
+	for p := jm; p != nil; p = p.parent {
+		for _, mw := range p.middleware {
+			f = mw(action, f)
+		}
+	}
+	return f
+}
+
+// RecoverMiddleware returns middleware replacing the result of a panicking
+// masking function with the fallback raw JSON value, e.g. `null`.
+func RecoverMiddleware(fallback string) Middleware {
+	return func(_ string, next func(string) []byte) func(string) []byte {
+		return func(s string) (res []byte) {
+			defer func() {
+				if recover() != nil {
+					res = []byte(fallback)
+				}
+			}()
+			return next(s)
+		}
+	}
+}
+
+// ValidateMiddleware returns middleware replacing the result of a masking
+// function not being a valid raw JSON value with the fallback, e.g. `null`.
+func ValidateMiddleware(fallback string) Middleware {
+	return func(_ string, next func(string) []byte) func(string) []byte {
+		return func(s string) []byte {
+			res := next(s)
+			if !gjson.ValidBytes(res) {
+				return []byte(fallback)
+			}
+			return res
+		}
+	}
+}
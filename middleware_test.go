@@ -0,0 +1,48 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_WrapFunc(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddFunc("panic", func(string) []byte { panic("boom") })
+	jm.AddFunc("broken", func(string) []byte { return []byte(`{`) })
+
+	var calls []string
+	jm.WrapFunc(
+		jsonmask.RecoverMiddleware(`null`),
+		jsonmask.ValidateMiddleware(`"invalid"`),
+		func(action string, next func(string) []byte) func(string) []byte {
+			return func(s string) []byte {
+				calls = append(calls, action)
+				return next(s)
+			}
+		},
+	)
+
+	data := []byte(`{"a":"x","b":"y","c":"z","d":1}`)
+	result, err := jm.Mask(data, jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "a", Action: "panic"},
+			{Path: "b", Action: "broken"},
+			{Path: "c", Action: "upper"},
+			{Path: "d", Action: "round(10)"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":null,"b":"invalid","c":"Z","d":0}`, string(result))
+	assert.Equal(t, []string{"panic", "broken", "upper", "round(10)"}, calls)
+
+	// middleware of the base masker wraps functions of tenants
+	m := jsonmask.NewManager()
+	m.Base().WrapFunc(jsonmask.RecoverMiddleware(`"recovered"`))
+	tenant := m.Tenant("acme")
+	tenant.AddFunc("panic", func(string) []byte { panic("boom") })
+	result, err = tenant.Mask([]byte(`{"a":"x"}`), jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "a", Action: "panic"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":"recovered"}`, string(result))
+}
@@ -0,0 +1,157 @@
+package jsonmask
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// DefaultModifiers holds names of gjson modifiers allowed in rule paths by default.
+// Modifiers only select values, they never change the masked document.
+var DefaultModifiers = []string{"this", "reverse", "flatten", "valid"}
+
+// modifierRe matches modifier segments of a path, e.g. "items|@reverse".
+var modifierRe = regexp.MustCompile(`(?:^|[.|])@([A-Za-z0-9_]+)`)
+
+// WithModifiers allows custom gjson modifiers, registered by gjson.AddModifier,
+// in rule paths in addition to DefaultModifiers.
+func WithModifiers(names ...string) Option {
+	return func(jm *JsonMaskerImpl) {
+		for _, name := range names {
+			jm.modifiers[name] = true
+		}
+	}
+}
+
+// checkModifiers returns an error if the path uses a modifier that is not allowed.
+func (jm *JsonMaskerImpl) checkModifiers(path string) error {
+	if _, found := cutModifierPath(path); !found {
+		return nil
+	}
+
+	for _, m := range modifierRe.FindAllStringSubmatch(path, -1) {
+		if !jm.modifiers[m[1]] {
+			return fmt.Errorf("%w: @%s", ErrModifier, m[1])
+		}
+	}
+	return nil
+}
+
+// cutModifierPath returns the plain part of the path in front of the first modifier.
+func cutModifierPath(path string) (scope string, found bool) {
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\':
+			i++ // skip escaped character
+		case path[i] == '@' && i == 0:
+			return "", true
+		case path[i] == '@' && (path[i-1] == '.' || path[i-1] == '|'):
+			return path[:i-1], true
+		}
+	}
+	return path, false
+}
+
+// expandModifierPath returns concrete paths of values selected by the path using
+// modifiers. Modifiers don't keep positions of selected values, so candidates are
+// located by content within the plain part of the path in front of the first
+// modifier. If the same value is found several times, the selected occurrences
+// are told apart by replacing candidates one by one with a sentinel and
+// evaluating the path again. Values that can't be told apart are not returned.
+func expandModifierPath(data []byte, path string) []string {
+	res := gjson.GetBytes(data, path)
+	if !res.Exists() {
+		return nil
+	}
+
+	scope, _ := cutModifierPath(path)
+	scopes := []string{""}
+	if scope != "" {
+		scopes = expandPath(data, scope)
+	}
+
+	if candidates := locateValues(data, scopes, res.Raw); len(candidates) > 0 {
+		return selectedPaths(data, path, candidates, func(r gjson.Result) gjson.Result { return r })
+	}
+
+	var paths []string
+	if res.IsArray() {
+		// the array is built by a modifier, e.g. @flatten, locate its elements instead.
+		for i, item := range res.Array() {
+			i := i
+			candidates := locateValues(data, scopes, item.Raw)
+			paths = append(paths, selectedPaths(data, path, candidates, func(r gjson.Result) gjson.Result {
+				if arr := r.Array(); i < len(arr) {
+					return arr[i]
+				}
+				return gjson.Result{}
+			})...)
+		}
+	}
+	return uniquePaths(paths)
+}
+
+// selectedPaths returns candidates selected by the path. If there are several,
+// each is replaced with a sentinel and kept if the value picked by pick from
+// the result of the path becomes the sentinel.
+func selectedPaths(data []byte, path string, candidates []string, pick func(gjson.Result) gjson.Result) []string {
+	if len(candidates) < 2 {
+		return candidates
+	}
+
+	var paths []string
+	for _, c := range candidates {
+		sentinel := locateSentinel(gjson.GetBytes(data, c))
+		probe, err := sjson.SetRawBytes(append([]byte(nil), data...), c, []byte(sentinel))
+		if err != nil {
+			continue
+		}
+		if pick(gjson.GetBytes(probe, path)).Raw == sentinel {
+			paths = append(paths, c)
+		}
+	}
+	return paths
+}
+
+// locateSentinel returns a value of the same kind as v, so modifiers
+// treat it like v, not found in documents.
+func locateSentinel(v gjson.Result) string {
+	switch {
+	case v.IsObject():
+		return `{"\u0000jsonmask":0}`
+	case v.IsArray():
+		return `["\u0000jsonmask"]`
+	case v.Type == gjson.Number:
+		return `-1.2345678901234567e-300`
+	}
+	return `"\u0000jsonmask"`
+}
+
+// locateValues returns concrete paths of values equal to raw found within the scopes.
+func locateValues(data []byte, scopes []string, raw string) []string {
+	var paths []string
+
+	for _, scope := range scopes {
+		v := gjson.ParseBytes(data)
+		if scope != "" {
+			v = gjson.GetBytes(data, scope)
+		}
+
+		if v.Raw == raw {
+			paths = append(paths, scope)
+			continue
+		}
+
+		walk(v, nil, func(keys []string, value gjson.Result) bool {
+			if value.Raw != raw {
+				return true
+			}
+			paths = append(paths, joinPath(scope, joinKeys(keys)))
+			return false
+		})
+	}
+
+	return paths
+}
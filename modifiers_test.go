@@ -0,0 +1,86 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestJsonMaskerImpl_Modifiers(t *testing.T) {
+	data := []byte(`{"name":"john","history":[{"secret":"a1"},{"secret":"b2"},{"secret":"c3"}],"matrix":[["x"],["y","z"]]}`)
+
+	tests := []struct {
+		name     string
+		rule     jsonmask.Rule
+		expected string
+	}{
+		{
+			"Reverse",
+			jsonmask.Rule{Path: "history|@reverse|0.secret", Action: "null"},
+			`{"name":"john","history":[{"secret":"a1"},{"secret":"b2"},{"secret":null}],"matrix":[["x"],["y","z"]]}`,
+		},
+		{
+			"This",
+			jsonmask.Rule{Path: "@this.name", Action: "upper"},
+			`{"name":"JOHN","history":[{"secret":"a1"},{"secret":"b2"},{"secret":"c3"}],"matrix":[["x"],["y","z"]]}`,
+		},
+		{
+			"Flatten",
+			jsonmask.Rule{Path: "matrix|@flatten", Action: "upper"},
+			`{"name":"john","history":[{"secret":"a1"},{"secret":"b2"},{"secret":"c3"}],"matrix":[["X"],["Y","Z"]]}`,
+		},
+		{
+			"Root",
+			jsonmask.Rule{Path: "@this", Action: "summary"},
+			`{"masked":true,"fields":3}`,
+		},
+	}
+
+	jm := jsonmask.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := jm.Mask(data, jsonmask.StructMaskRules{Rules: []jsonmask.Rule{tt.rule}})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+
+	t.Run("EqualValues", func(t *testing.T) {
+		data := []byte(`{"tags":["x","y","x"],"nick":"x","matrix":[["x"],["x","y"]],"codes":[["a"],["a","b"]]}`)
+		rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+			{Path: "tags|@reverse|0", Action: "-"},
+			{Path: "matrix|@reverse|0", Action: "-"},
+			{Path: "codes|@flatten|1", Action: "upper"},
+		}}
+
+		// only selected values are masked, not other values equal to them.
+		result, err := jm.Mask(data, rules)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"tags":["x","y"],"nick":"x","matrix":[["x"]],"codes":[["a"],["A","b"]]}`, string(result))
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		gjson.AddModifier("last", func(json, arg string) string {
+			arr := gjson.Parse(json).Array()
+			if len(arr) == 0 {
+				return ""
+			}
+			return arr[len(arr)-1].Raw
+		})
+		rules := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "history|@last.secret", Action: "null"}}}
+
+		// not allowed modifiers are skipped.
+		result, err := jsonmask.New().Mask(data, rules)
+		assert.NoError(t, err)
+		assert.Equal(t, string(data), string(result))
+
+		_, err = jsonmask.New(jsonmask.WithConfig(jsonmask.Config{Strict: true})).Mask(data, rules)
+		assert.ErrorIs(t, err, jsonmask.ErrModifier)
+
+		result, err = jsonmask.New(jsonmask.WithModifiers("last")).Mask(data, rules)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name":"john","history":[{"secret":"a1"},{"secret":"b2"},{"secret":null}],"matrix":[["x"],["y","z"]]}`, string(result))
+	})
+}
@@ -0,0 +1,199 @@
+package jsonmask
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// MultipartRules selects parts of multipart/form-data bodies masked by MaskMultipart.
+type MultipartRules struct {
+	// Parts holds rules of JSON parts by form field name.
+	Parts map[string]StructMaskRules
+
+	// Default holds rules of JSON parts not listed in Parts.
+	Default StructMaskRules
+
+	// TextFields holds actions of text fields by form field name,
+	// e.g. {"password": "-", "email": "email"}. The action "-" empties the field.
+	TextFields map[string]string
+}
+
+// MaskMultipart copies the multipart body, e.g. multipart/form-data of an HTTP
+// request, from src to dst masking JSON parts, having a JSON content type, and
+// configured text fields. Other parts, e.g. files, are streamed through untouched.
+// Parts and text fields configured by name are masked whatever their content
+// type or file name, parts in Parts holding invalid JSON fail with ErrInvalidJSON.
+// Bodies of masked parts with base64 or quoted-printable transfer encoding are
+// decoded and encoded back, other encodings fail with ErrUnsupportedEncoding.
+// The boundary and part headers are kept, headers are written in canonical form.
+// Parts are copied as is if masking is bypassed for ctx by Skip.
+func (jm *JsonMaskerImpl) MaskMultipart(ctx context.Context, dst io.Writer, src io.Reader, contentType string, rules MultipartRules) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return ErrNotMultipart
+	}
+
+	skip := IsSkipped(ctx)
+	if skip {
+		jm.log("jsonmask: masking skipped by context", "content-type", mediaType)
+	}
+
+	r := multipart.NewReader(src, params["boundary"])
+	w := multipart.NewWriter(dst)
+	if err := w.SetBoundary(params["boundary"]); err != nil {
+		return err
+	}
+
+	for {
+		// raw parts keep the content transfer encoding
+		part, err := r.NextRawPart()
+		if err == io.EOF {
+			return w.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		pw, err := w.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+
+		mask := jm.multipartMask(part, rules)
+		if skip || mask == nil {
+			if _, err := io.Copy(pw, part); err != nil {
+				return err
+			}
+			continue
+		}
+
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		if body, err = mask(body); err != nil {
+			return err
+		}
+		if _, err := pw.Write(body); err != nil {
+			return err
+		}
+	}
+}
+
+// multipartMask returns the function masking the body of the part, decoding
+// it first if needed, or nil if the part is streamed as is.
+func (jm *JsonMaskerImpl) multipartMask(part *multipart.Part, rules MultipartRules) func([]byte) ([]byte, error) {
+	mask := jm.multipartBodyMask(part, rules)
+	if mask == nil {
+		return nil
+	}
+
+	switch enc := strings.ToLower(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding"))); enc {
+	case "", "7bit", "8bit", "binary":
+		return mask // identity encodings
+	case "base64":
+		return func(body []byte) ([]byte, error) {
+			decoded, err := base64.StdEncoding.DecodeString(strings.Map(func(r rune) rune {
+				if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+					return -1
+				}
+				return r
+			}, string(body)))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrUnsupportedEncoding, err)
+			}
+			if decoded, err = mask(decoded); err != nil {
+				return nil, err
+			}
+			return wrapLines(base64.StdEncoding.EncodeToString(decoded), 76), nil
+		}
+	case "quoted-printable":
+		return func(body []byte) ([]byte, error) {
+			decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrUnsupportedEncoding, err)
+			}
+			if decoded, err = mask(decoded); err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			qw := quotedprintable.NewWriter(&buf)
+			_, _ = qw.Write(decoded)
+			_ = qw.Close()
+			return buf.Bytes(), nil
+		}
+	default:
+		return func([]byte) ([]byte, error) {
+			return nil, fmt.Errorf("%w: %s of part %q", ErrUnsupportedEncoding, enc, part.FormName())
+		}
+	}
+}
+
+// wrapLines splits s to lines of n characters separated by CRLF.
+func wrapLines(s string, n int) []byte {
+	res := make([]byte, 0, len(s)+len(s)/n*2)
+	for len(s) > n {
+		res = append(res, s[:n]...)
+		res = append(res, "\r\n"...)
+		s = s[n:]
+	}
+	return append(res, s...)
+}
+
+// multipartBodyMask returns the function masking the decoded body of the part,
+// or nil if the part is streamed as is.
+func (jm *JsonMaskerImpl) multipartBodyMask(part *multipart.Part, rules MultipartRules) func([]byte) ([]byte, error) {
+	name := part.FormName()
+
+	// configured fields are masked whatever the client declares for them
+	smr, ok := rules.Parts[name]
+	if !ok {
+		if action, ok := rules.TextFields[name]; ok {
+			return jm.multipartTextMask(name, action)
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			return nil
+		}
+		smr = rules.Default
+	}
+	return func(body []byte) ([]byte, error) {
+		return jm.Mask(body, smr)
+	}
+}
+
+// multipartTextMask returns the function masking the text field body by the action.
+func (jm *JsonMaskerImpl) multipartTextMask(name, action string) func([]byte) ([]byte, error) {
+	return func(body []byte) ([]byte, error) {
+		maskFunc, ok, err := jm.ruleFunc(Rule{Path: name, Action: action})
+		if !ok {
+			return body, err
+		}
+		if maskFunc == nil {
+			return nil, nil
+		}
+
+		masked := maskFunc(string(quote(string(body))))
+		if str, ok := unquote(string(masked)); ok {
+			return []byte(str), nil
+		}
+		if string(masked) == "null" {
+			return nil, nil
+		}
+		return masked, nil // other values as JSON text, e.g. numbers
+	}
+}
+
+// Error definitions
+var (
+	ErrNotMultipart        = errors.New("not a multipart body")
+	ErrUnsupportedEncoding = errors.New("unsupported content transfer encoding")
+)
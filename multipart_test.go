@@ -0,0 +1,148 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskMultipart(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("email", "john@example.com"))
+	require.NoError(t, mw.WriteField("password", "secret"))
+	require.NoError(t, mw.WriteField("comment", "hello"))
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="profile"`)
+	h.Set("Content-Type", "application/json")
+	pw, err := mw.CreatePart(h)
+	require.NoError(t, err)
+	_, _ = pw.Write([]byte(`{"name":"John Smith","card":"4111111111111111"}`))
+
+	fw, err := mw.CreateFormFile("avatar", "me.png")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte("\x89PNG binary"))
+	require.NoError(t, mw.Close())
+
+	jm := jsonmask.New()
+	rules := jsonmask.MultipartRules{
+		Parts: map[string]jsonmask.StructMaskRules{
+			"profile": {Rules: []jsonmask.Rule{{Path: "card", Action: "-"}, {Path: "name", Action: "initials"}}},
+		},
+		TextFields: map[string]string{"email": "email", "password": "-", "avatar": "truncate"},
+	}
+
+	var masked bytes.Buffer
+	err = jm.MaskMultipart(context.Background(), &masked, bytes.NewReader(body.Bytes()), mw.FormDataContentType(), rules)
+	require.NoError(t, err)
+
+	parts := readParts(t, masked.Bytes(), mw.Boundary())
+	assert.Equal(t, map[string]string{
+		"email":    "j**n@e******.com",
+		"password": "",
+		"comment":  "hello",
+		"profile":  `{"name":"J.S."}`,
+		"avatar":   "",
+	}, parts)
+
+	masked.Reset()
+	err = jm.MaskMultipart(jsonmask.Skip(context.Background()), &masked, bytes.NewReader(body.Bytes()), mw.FormDataContentType(), rules)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", readParts(t, masked.Bytes(), mw.Boundary())["password"])
+
+	err = jm.MaskMultipart(context.Background(), io.Discard, bytes.NewReader(nil), "application/json", rules)
+	assert.ErrorIs(t, err, jsonmask.ErrNotMultipart)
+}
+
+func TestMaskMultipart_PartHeaders(t *testing.T) {
+	jm := jsonmask.New()
+	rules := jsonmask.MultipartRules{
+		Parts:      map[string]jsonmask.StructMaskRules{"profile": {Rules: []jsonmask.Rule{{Path: "card", Action: "-"}}}},
+		Default:    jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "card", Action: "-"}}},
+		TextFields: map[string]string{"password": "-", "email": "email"},
+	}
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		body     string
+		expected string
+	}{
+		{"8bit json", map[string]string{"Content-Disposition": `form-data; name="doc"`, "Content-Type": "application/json", "Content-Transfer-Encoding": "8bit"},
+			`{"card":"4111"}`, `{}`},
+		{"binary text field", map[string]string{"Content-Transfer-Encoding": "binary"},
+			"secret", ""},
+		{"octet-stream text field", map[string]string{"Content-Type": "application/octet-stream"},
+			"secret", ""},
+		{"file text field", map[string]string{"Content-Disposition": `form-data; name="password"; filename="p.txt"`},
+			"secret", ""},
+		{"text/plain json part", map[string]string{"Content-Disposition": `form-data; name="profile"`, "Content-Type": "text/plain"},
+			`{"card":"4111"}`, `{}`},
+		{"base64 text field", map[string]string{"Content-Disposition": `form-data; name="email"`, "Content-Transfer-Encoding": "base64"},
+			"am9obkBleGFtcGxlLmNvbQ==", "aioqbkBlKioqKioqLmNvbQ=="},
+		{"quoted-printable text field", map[string]string{"Content-Disposition": `form-data; name="email"`, "Content-Transfer-Encoding": "quoted-printable"},
+			"john=40example.com", "j**n@e******.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			h := textproto.MIMEHeader{}
+			h.Set("Content-Disposition", `form-data; name="password"`)
+			for k, v := range tt.header {
+				h.Set(k, v)
+			}
+			pw, err := mw.CreatePart(h)
+			require.NoError(t, err)
+			_, _ = pw.Write([]byte(tt.body))
+			require.NoError(t, mw.Close())
+
+			var masked bytes.Buffer
+			require.NoError(t, jm.MaskMultipart(context.Background(), &masked, &body, mw.FormDataContentType(), rules))
+
+			r := multipart.NewReader(&masked, mw.Boundary())
+			p, err := r.NextRawPart()
+			require.NoError(t, err)
+			data, err := io.ReadAll(p)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="password"`)
+	h.Set("Content-Transfer-Encoding", "x-gzip")
+	pw, err := mw.CreatePart(h)
+	require.NoError(t, err)
+	_, _ = pw.Write([]byte("secret"))
+	require.NoError(t, mw.Close())
+	err = jm.MaskMultipart(context.Background(), io.Discard, &body, mw.FormDataContentType(), rules)
+	assert.ErrorIs(t, err, jsonmask.ErrUnsupportedEncoding)
+}
+
+// readParts returns bodies of parts of the multipart body by form field name.
+func readParts(t *testing.T, body []byte, boundary string) map[string]string {
+	res := make(map[string]string)
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return res
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(p)
+		require.NoError(t, err)
+		res[p.FormName()] = string(data)
+	}
+}
@@ -0,0 +1,57 @@
+package jsonmask
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Normalize returns a copy of the rule set with duplicate rules removed and
+// rules shadowed by deletion of a parent path dropped, e.g. "a.b" when "a" is
+// always deleted. Deletions with conditions or sampling don't shadow rules.
+// Rules aren't sorted by path: overlapping rules apply in order, e.g. "a.b"
+// masked before "a" is counted, so sorting would change masking results.
+func (smr StructMaskRules) Normalize() StructMaskRules {
+	res := smr
+	res.Rules = nil
+
+	for i, rule := range smr.Rules {
+		if containsRule(res.Rules, rule) {
+			continue
+		}
+		if _, shadowed := shadowingRule(smr.Rules, i); shadowed {
+			continue
+		}
+		res.Rules = append(res.Rules, rule)
+	}
+
+	return res
+}
+
+// containsRule reports whether rules contain the rule.
+func containsRule(rules []Rule, rule Rule) bool {
+	for _, r := range rules {
+		if reflect.DeepEqual(r, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowingRule returns index of a rule always deleting a parent of the path
+// of rules[i], not having exclusions in its subtree.
+func shadowingRule(rules []Rule, i int) (int, bool) {
+	var exclusions []string
+	for _, rule := range rules {
+		if isExclusion(rule) {
+			exclusions = append(exclusions, rule.Path[1:])
+		}
+	}
+
+	for j, rule := range rules {
+		if j != i && isDeletion(rule) && strings.HasPrefix(rules[i].Path, rule.Path+".") &&
+			!hasPathPrefix(rule.Path, exclusions, true) {
+			return j, true
+		}
+	}
+	return 0, false
+}
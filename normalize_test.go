@@ -0,0 +1,67 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructMaskRules_Normalize(t *testing.T) {
+	rules := jsonmask.StructMaskRules{
+		Version: "v1",
+		Rules: []jsonmask.Rule{
+			{Path: "name", Action: "lower"},
+			{Path: "card.number", Action: "first4"},
+			{Path: "card", Action: "-"},
+			{Path: "items.#.secret", Action: "null"},
+			{Path: "name", Action: "lower"},
+			{Path: "email", Action: "email"},
+			{Path: "name", Action: "initialChar"},
+			{Path: "items.#", Action: "-"},
+			{Path: "cardholder", Action: "upper"},
+		},
+	}
+
+	normalized := rules.Normalize()
+	assert.Equal(t, "v1", normalized.Version)
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "name", Action: "lower"},
+		{Path: "card", Action: "-"},
+		{Path: "email", Action: "email"},
+		{Path: "name", Action: "initialChar"},
+		{Path: "items.#", Action: "-"},
+		{Path: "cardholder", Action: "upper"},
+	}, normalized.Rules)
+
+	// original rules are kept intact.
+	assert.Len(t, rules.Rules, 9)
+}
+
+func TestStructMaskRules_NormalizeConditionalDeletion(t *testing.T) {
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "contact", Action: "-", When: "!consents.marketing==true"},
+			{Path: "contact.email", Action: "email"},
+			{Path: "card", Action: "-", Sample: 0.5},
+			{Path: "card.number", Action: "truncate"},
+			{Path: "order", Action: "-"},
+			{Path: "!order.id", Action: ""},
+			{Path: "order.id", Action: "truncate"},
+		},
+		Fields:  []string{"contact", "contact.email"},
+		Classes: map[string][]string{"contact.email": {"PII"}},
+		Headers: []jsonmask.HeaderRule{{Name: "Authorization", Action: "-"}},
+	}
+
+	normalized := rules.Normalize()
+	assert.Equal(t, rules.Rules, normalized.Rules)
+	assert.Equal(t, rules.Fields, normalized.Fields)
+	assert.Equal(t, rules.Classes, normalized.Classes)
+	assert.Equal(t, rules.Headers, normalized.Headers)
+
+	jm := jsonmask.New()
+	res, err := jm.Mask([]byte(`{"contact":{"email":"john@example.com"},"consents":{"marketing":true}}`), normalized)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"contact":{"email":"j**n@e******.com"},"consents":{"marketing":true}}`, string(res))
+}
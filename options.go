@@ -0,0 +1,216 @@
+package jsonmask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"strconv"
+	"time"
+)
+
+// Option configures JsonMaskerImpl created by New or NewWithMaskTag.
+type Option func(*JsonMaskerImpl)
+
+// WithLogger sets the logger receiving warnings about skipped rules (unknown action,
+// path not found). Messages are logged at the given level. By default nothing is logged.
+func WithLogger(l Logger, level LogLevel) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.logger = l
+		jm.logLevel = level
+	}
+}
+
+// MaskOption adjusts rules for a single call of Mask.
+type MaskOption func(*maskOptions)
+
+type maskOptions struct {
+	overrides []Rule          // replacing actions of rules with the same path
+	disabled  map[string]bool // paths of rules to skip
+}
+
+// WithOverride replaces the action of rules with the path for a single call.
+// If there is no such rule, the rule is added.
+func WithOverride(path, action string) MaskOption {
+	return func(o *maskOptions) {
+		o.overrides = append(o.overrides, Rule{Path: path, Action: action})
+	}
+}
+
+// WithDisable skips rules with the path for a single call.
+func WithDisable(path string) MaskOption {
+	return func(o *maskOptions) {
+		if o.disabled == nil {
+			o.disabled = make(map[string]bool)
+		}
+		o.disabled[path] = true
+	}
+}
+
+// applyMaskOptions returns rules adjusted by the options.
+// The rules are returned as is if there are no options.
+func applyMaskOptions(rules []Rule, opts []MaskOption) []Rule {
+	if len(opts) == 0 {
+		return rules
+	}
+
+	var o maskOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	res := make([]Rule, 0, len(rules)+len(o.overrides))
+	applied := make(map[string]bool, len(o.overrides))
+	for _, rule := range rules {
+		if o.disabled[rule.Path] {
+			continue
+		}
+		for _, override := range o.overrides {
+			if override.Path == rule.Path {
+				rule.Action = override.Action
+				applied[override.Path] = true
+			}
+		}
+		res = append(res, rule)
+	}
+
+	for _, override := range o.overrides {
+		if !applied[override.Path] && !o.disabled[override.Path] {
+			res = append(res, override)
+			applied[override.Path] = true
+		}
+	}
+
+	return res
+}
+
+// OverflowPolicy defines handling of array elements beyond the limit set by WithArrayLimit.
+type OverflowPolicy int
+
+// Overflow policies.
+const (
+	// OverflowSkip leaves elements beyond the limit unmasked.
+	OverflowSkip OverflowPolicy = iota
+
+	// OverflowDelete removes elements beyond the limit from the array.
+	OverflowDelete
+
+	// OverflowError fails masking with ErrArrayLimit.
+	OverflowError
+)
+
+// WithArrayLimit limits the number of elements of every array processed by a rule,
+// protecting latency-sensitive paths from huge arrays. Elements beyond the limit
+// are not selected by the rule and handled according to the policy.
+func WithArrayLimit(limit int, policy OverflowPolicy) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.arrayLimit = limit
+		jm.overflowPolicy = policy
+	}
+}
+
+// InvalidOutputPolicy defines handling of values of masking functions not being
+// valid raw JSON, see WithValidOutput.
+type InvalidOutputPolicy int
+
+// Invalid output policies.
+const (
+	// InvalidOutputError fails masking with ErrInvalidOutput.
+	InvalidOutputError InvalidOutputPolicy = iota
+
+	// InvalidOutputDelete deletes the value, the whole document selected
+	// by a rule is replaced with null.
+	InvalidOutputDelete
+)
+
+// WithValidOutput guarantees masked documents are valid JSON: values returned
+// by masking functions, e.g. custom ones, are validated and handled according
+// to the policy if invalid. Documents corrupted by post-mask hooks fail with
+// ErrInvalidOutput. Attribute names masked by rules with Keys are not checked,
+// they are always encoded as strings.
+func WithValidOutput(policy InvalidOutputPolicy) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.validOutput = true
+		jm.invalidPolicy = policy
+	}
+}
+
+// WithMaskStyle replaces built-in maskers "email", "first4" and "passport" with
+// ones hiding characters and marking truncation as defined by the style.
+// The ellipsis is used by "first4" only if it's not empty.
+func WithMaskStyle(style MaskStyle) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.AddFunc("email", EmailFn(style))
+		jm.AddFunc("first4", PrefixStyleFn(4, style.Ellipsis != "", style))
+		jm.AddFunc("passport", PassportFn(style))
+	}
+}
+
+// WithSeed replaces built-in maskers producing random values ("uuid",
+// "syntheticCard", "laplace(E,S)") with versions derived from values and the
+// seed, and ones depending on the current time ("tombstone", "ageBucket")
+// with versions using SeedTime, so the output is stable across runs, e.g. for
+// golden-file snapshot tests. The seed isn't a secret, don't use it in
+// production. Functions added by the application, like EncryptFn or
+// TokenizeFn, are not affected.
+func WithSeed(seed int64) Option {
+	salt := strconv.FormatInt(seed, 10)
+	now := func() time.Time { return SeedTime }
+	return func(jm *JsonMaskerImpl) {
+		jm.AddFunc("uuid", seededUUID(salt))
+		jm.AddFunc("syntheticCard", seededSyntheticCard(salt))
+		jm.AddFunc("tombstone", tombstoneFn("", now))
+		jm.AddFunc("ageBucket", ageBucketFn(10, now))
+		jm.AddFuncFactory("laplace", func(arg string) (func(string) []byte, error) {
+			epsilon, sensitivity, err := laplaceArgs(arg)
+			if err != nil {
+				return nil, err
+			}
+			return seededLaplace(epsilon, sensitivity, salt), nil
+		})
+		jm.AddFuncFactory("tombstone", func(policy string) (func(string) []byte, error) {
+			return tombstoneFn(policy, now), nil
+		})
+		jm.AddFuncFactory("ageBucket", func(arg string) (func(string) []byte, error) {
+			width, err := ageBucketWidth(arg)
+			if err != nil {
+				return nil, err
+			}
+			return ageBucketFn(width, now), nil
+		})
+	}
+}
+
+// SeedTime is the current time seen by maskers configured by WithSeed.
+var SeedTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WithHashKey registers maskers deriving replacements from keyed hashes of
+// values, so they can't be reversed or brute-forced without the key:
+// "scramble" and "scramble(seed)", the latter deriving a distinct permutation
+// per seed, "syntheticCardHash", "fakeName", "fakeEmail" and "uuidHash".
+// The key should come from a secret store. It panics if the key is empty.
+func WithHashKey(key []byte) Option {
+	if len(key) == 0 {
+		panic("jsonmask: hash key is empty")
+	}
+	key = append([]byte(nil), key...)
+	return func(jm *JsonMaskerImpl) {
+		jm.registerErr(jm.AddCryptoFunc("scramble", AlgHMACSHA256, ScrambleFn(key), nil))
+		jm.registerErr(jm.AddCryptoFactory("scramble", AlgHMACSHA256, func(seed string) (func(string) []byte, error) {
+			return ScrambleFn(subKey(key, "scramble:"+seed)), nil
+		}))
+		for name, f := range map[string]func([]byte) func(string) []byte{
+			"syntheticCardHash": SyntheticCardHashFn,
+			"fakeName":          FakeNameFn,
+			"fakeEmail":         FakeEmailFn,
+			"uuidHash":          UUIDHashFn,
+		} {
+			jm.registerErr(jm.AddCryptoFunc(name, AlgHMACSHA256, f(subKey(key, name)), nil))
+		}
+	}
+}
+
+// subKey derives a key for the purpose from the key.
+func subKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
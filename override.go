@@ -0,0 +1,143 @@
+package jsonmask
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RuleOverride is a temporary override of actions of rules with the path,
+// registered by AddRuleOverride.
+type RuleOverride struct {
+	Path    string    `json:"path"`
+	Action  string    `json:"action"` // empty keeps values unmasked
+	Reason  string    `json:"reason,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// ruleOverrides holds active overrides of the masker by path. Changes are
+// published to the snapshot read by masking without locking, nil if there are
+// no overrides.
+type ruleOverrides struct {
+	mu       sync.Mutex
+	byPath   map[string]RuleOverride
+	timers   map[string]*time.Timer
+	snapshot atomic.Pointer[map[string]RuleOverride]
+}
+
+// publish stores a copy of overrides to the snapshot. It's called with mu locked.
+func (ro *ruleOverrides) publish() {
+	if len(ro.byPath) == 0 {
+		ro.snapshot.Store(nil)
+		return
+	}
+	m := make(map[string]RuleOverride, len(ro.byPath))
+	for path, o := range ro.byPath {
+		m[path] = o
+	}
+	ro.snapshot.Store(&m)
+}
+
+// AddRuleOverride replaces the action of rules with the path in all rule sets
+// for the ttl, e.g. to unmask a field for an incident investigation by the
+// empty action, after which the base policy applies again and the expiration
+// is logged. Registering the path again replaces its override. Rules missing
+// the path are not added. Tenant maskers of a Manager honor overrides of the
+// manager's base masker unless they override the path themselves.
+func (jm *JsonMaskerImpl) AddRuleOverride(path, action string, ttl time.Duration, reason string) {
+	o := RuleOverride{Path: path, Action: action, Reason: reason, Expires: timeNow().Add(ttl)}
+
+	jm.overrides.mu.Lock()
+	defer jm.overrides.mu.Unlock()
+	if jm.overrides.byPath == nil {
+		jm.overrides.byPath = make(map[string]RuleOverride)
+		jm.overrides.timers = make(map[string]*time.Timer)
+	}
+	if t, ok := jm.overrides.timers[path]; ok {
+		t.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		jm.overrides.mu.Lock()
+		expired := jm.overrides.timers[path] == timer
+		if expired {
+			delete(jm.overrides.byPath, path)
+			delete(jm.overrides.timers, path)
+			jm.overrides.publish()
+		}
+		jm.overrides.mu.Unlock()
+
+		if expired {
+			jm.log("jsonmask: rule override expired", "path", path, "action", action, "reason", reason)
+		}
+	})
+	jm.overrides.byPath[path] = o
+	jm.overrides.timers[path] = timer
+	jm.overrides.publish()
+	jm.log("jsonmask: rule override added", "path", path, "action", action, "reason", reason, "expires", o.Expires)
+}
+
+// RemoveRuleOverride removes the override of the path before it expires.
+func (jm *JsonMaskerImpl) RemoveRuleOverride(path string) {
+	jm.overrides.mu.Lock()
+	defer jm.overrides.mu.Unlock()
+	if t, ok := jm.overrides.timers[path]; ok {
+		t.Stop()
+		delete(jm.overrides.byPath, path)
+		delete(jm.overrides.timers, path)
+		jm.overrides.publish()
+	}
+}
+
+// RuleOverrides returns active overrides of the masker sorted by path.
+func (jm *JsonMaskerImpl) RuleOverrides() []RuleOverride {
+	now := timeNow()
+	res := []RuleOverride{}
+
+	if m := jm.overrides.snapshot.Load(); m != nil {
+		for _, o := range *m {
+			if now.Before(o.Expires) {
+				res = append(res, o)
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res
+}
+
+// ruleOverride returns the active override of the path, looking into parents.
+func (jm *JsonMaskerImpl) ruleOverride(path string) (RuleOverride, bool) {
+	for p := jm; p != nil; p = p.parent {
+		m := p.overrides.snapshot.Load()
+		if m == nil {
+			continue
+		}
+		if o, ok := (*m)[path]; ok && timeNow().Before(o.Expires) {
+			return o, true
+		}
+	}
+	return RuleOverride{}, false
+}
+
+// overriddenRules returns the rules with actions replaced by active overrides,
+// the empty action marks rules skipped. The rules are returned as is if no
+// override applies.
+func (jm *JsonMaskerImpl) overriddenRules(rules []Rule) []Rule {
+	var res []Rule
+	for i, rule := range rules {
+		o, found := jm.ruleOverride(rule.Path)
+		if !found {
+			continue
+		}
+		if res == nil {
+			res = append([]Rule(nil), rules...)
+		}
+		res[i].Action = o.Action
+	}
+	if res == nil {
+		return rules
+	}
+	return res
+}
@@ -0,0 +1,70 @@
+package jsonmask_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// chanLogger sends messages to the channel, so they can be awaited.
+type chanLogger chan string
+
+func (l chanLogger) Debug(msg string, args ...any) { l <- msg }
+func (l chanLogger) Info(msg string, args ...any)  { l <- msg }
+func (l chanLogger) Warn(msg string, args ...any)  { l <- msg }
+
+func TestAddRuleOverride(t *testing.T) {
+	l := make(chanLogger, 10)
+	m := jsonmask.NewManager(jsonmask.WithLogger(l, jsonmask.LevelInfo))
+	jm := m.Base()
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "email", Action: "email"},
+		{Path: "name", Action: "initials"},
+	}}
+	data := []byte(`{"email":"john@example.com","name":"John Smith"}`)
+
+	jm.AddRuleOverride("email", "", time.Hour, "INC-42")
+	jm.AddRuleOverride("name", "null", 50*time.Millisecond, "INC-42")
+	jm.AddRuleOverride("phone", "-", time.Hour, "")
+	assert.Equal(t, "jsonmask: rule override added", <-l)
+
+	res, err := m.Tenant("acme").Mask(data, smr)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"email":"john@example.com","name":null}`, string(res))
+
+	overrides := jm.RuleOverrides()
+	require.Len(t, overrides, 3)
+	assert.Equal(t, "email", overrides[0].Path)
+	assert.Equal(t, "INC-42", overrides[0].Reason)
+
+	jm.RemoveRuleOverride("phone")
+	for msg := range l {
+		if msg == "jsonmask: rule override expired" {
+			break
+		}
+	}
+	assert.Len(t, jm.RuleOverrides(), 1)
+
+	res, err = jm.Mask(data, smr)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"email":"john@example.com","name":"J.S."}`, string(res))
+}
+
+func TestAddRuleOverride_IdempotentMarker(t *testing.T) {
+	jm := jsonmask.New(jsonmask.WithIdempotentMarker("_masked", []byte("key")))
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}}}
+
+	jm.AddRuleOverride("email", "", time.Hour, "INC-42")
+	res, err := jm.Mask([]byte(`{"email":"john@example.com"}`), smr)
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.com", gjson.GetBytes(res, "email").Str)
+
+	jm.RemoveRuleOverride("email")
+	res, err = jm.Mask(res, smr)
+	require.NoError(t, err)
+	assert.NotEqual(t, "john@example.com", gjson.GetBytes(res, "email").Str)
+}
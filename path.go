@@ -0,0 +1,332 @@
+package jsonmask
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// expandPath returns concrete paths of values matching the path, where every
+// array element selector is replaced with indexes of matching elements.
+// Supported selectors:
+//
+//	items.#.currency                  all elements
+//	items.#(type=="card")#.number     elements matching the gjson query
+//	items.#(type=="card").number      the first element matching the gjson query
+//	items.-1.secret                   the element counted from the end, -1 is the last one
+//	items.[1:].comment                elements in the range [from:to), bounds may be negative or omitted
+//	customer.*.email                  all object attributes or array elements
+//	**.cardNumber                     values at any depth
+func expandPath(data []byte, path string) []string {
+	paths, _ := expandPathLimit(data, path, 0)
+	return paths
+}
+
+// expandPathLimit is like expandPath but selects only first limit elements
+// of every array, 0 means no limit. Concrete paths of arrays having more
+// elements are returned as overflows, outer arrays first.
+func expandPathLimit(data []byte, path string, limit int) (paths, overflows []string) {
+	if _, found := cutModifierPath(path); found {
+		return expandModifierPath(data, path), nil
+	}
+
+	e := pathExpander{data: data, limit: limit}
+	return e.expand("", path), e.overflows
+}
+
+// pathExpander expands rule paths to concrete paths of values of the document.
+type pathExpander struct {
+	data      []byte
+	limit     int      // max number of selected elements of an array, 0 - no limit
+	overflows []string // paths of arrays exceeding the limit
+}
+
+// expand expands the path relative to the already resolved concrete prefix.
+func (e *pathExpander) expand(prefix, path string) []string {
+	parentPath, selector, itemPath, found := cutSelectorPath(path)
+	if !found {
+		full := joinPath(prefix, path)
+		if gjson.GetBytes(e.data, full).Exists() {
+			return []string{full}
+		}
+		return nil
+	}
+
+	base := prefix
+	if parentPath != "" {
+		base = joinPath(prefix, parentPath)
+	}
+	itemPath = strings.TrimPrefix(itemPath, ".")
+
+	parent := gjson.ParseBytes(e.data)
+	if base != "" {
+		parent = gjson.GetBytes(e.data, base)
+	}
+
+	var paths []string
+	expand := func(child string) {
+		if itemPath == "" {
+			paths = append(paths, child)
+			return
+		}
+		paths = append(paths, e.expand(child, itemPath)...)
+	}
+
+	switch {
+	case selector == "*" || selector == "**":
+		if selector == "**" {
+			// "**" matches zero or more levels
+			switch {
+			case itemPath != "":
+				paths = append(paths, e.expand(base, itemPath)...)
+				itemPath = "**." + itemPath
+			case base != "":
+				paths = append(paths, base)
+				itemPath = "**"
+			default:
+				itemPath = "**"
+			}
+		}
+		for _, child := range e.childKeys(base, parent) {
+			expand(joinPath(base, child))
+		}
+		if selector == "**" {
+			paths = uniquePaths(paths)
+		}
+	case parent.IsArray():
+		elems := e.elements(base, parent)
+		for _, i := range selectElements(len(elems), selector, func(i int) string { return elems[i].Raw }) {
+			expand(joinPath(base, strconv.Itoa(i)))
+		}
+	case parent.IsObject() && selector[0] == '-':
+		// negative indexes are valid object keys
+		return e.expand(joinPath(base, selector), itemPath)
+	}
+	return paths
+}
+
+// elements returns elements of the array found by the path, up to the limit.
+func (e *pathExpander) elements(path string, arr gjson.Result) []gjson.Result {
+	var elems []gjson.Result
+	arr.ForEach(func(_, value gjson.Result) bool {
+		if e.limit > 0 && len(elems) == e.limit {
+			e.overflows = append(e.overflows, path)
+			return false
+		}
+		elems = append(elems, value)
+		return true
+	})
+	return elems
+}
+
+// childKeys returns escaped object keys or array indexes, up to the limit,
+// of the value found by the path.
+func (e *pathExpander) childKeys(path string, v gjson.Result) []string {
+	if v.IsArray() {
+		keys := make([]string, len(e.elements(path, v)))
+		for i := range keys {
+			keys[i] = strconv.Itoa(i)
+		}
+		return keys
+	}
+
+	if !v.IsObject() {
+		return nil // ForEach visits scalars as a single value
+	}
+
+	var keys []string
+	v.ForEach(func(key, _ gjson.Result) bool {
+		keys = append(keys, pathEscaper.Replace(key.Str))
+		return true
+	})
+	return keys
+}
+
+// uniquePaths removes repeated paths keeping the order of first occurrences.
+func uniquePaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	res := paths[:0]
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// selectElements returns indexes of array elements matching the selector.
+// The element function returns raw JSON of the i-th element, it's called for
+// query selectors only.
+func selectElements(count int, selector string, element func(i int) string) []int {
+	var res []int
+
+	switch selector[0] {
+	case '-':
+		n, _ := strconv.Atoi(selector)
+		if count+n >= 0 {
+			res = append(res, count+n)
+		}
+		return res
+	case '[':
+		from, to, _ := parseRange(selector, count)
+		for i := from; i < to; i++ {
+			res = append(res, i)
+		}
+		return res
+	}
+
+	if selector == "#" {
+		for i := 0; i < count; i++ {
+			res = append(res, i)
+		}
+		return res
+	}
+
+	query := strings.TrimSuffix(selector, "#")
+	first := !strings.HasSuffix(selector, ")#")
+	for i := 0; i < count; i++ {
+		if gjson.Get("["+element(i)+"]", query+"#|#").Int() > 0 {
+			res = append(res, i)
+			if first {
+				break
+			}
+		}
+	}
+	return res
+}
+
+// cutSelectorPath slices path around the first selector segment.
+// The item path is empty or starts with the path separator.
+func cutSelectorPath(path string) (parentPath, selector, itemPath string, found bool) {
+	segment := 0 // start of the current path segment
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++ // skip escaped character
+			continue
+		case '.':
+			segment = i + 1
+			continue
+		}
+
+		if i != segment {
+			continue
+		}
+
+		end := selectorEnd(path, i)
+		if end < 0 || (end < len(path) && path[end] != '.') {
+			continue
+		}
+
+		if i > 0 {
+			parentPath = path[:i-1]
+		}
+		return parentPath, path[i:end], path[end:], true
+	}
+	return path, "", "", false
+}
+
+// selectorEnd returns the end position of the selector starting at the position
+// start, or -1 if there is no valid selector.
+func selectorEnd(path string, start int) int {
+	switch path[start] {
+	case '*':
+		if start+1 < len(path) && path[start+1] == '*' {
+			return start + 2
+		}
+		return start + 1
+	case '#':
+		return queryEnd(path, start)
+	case '-':
+		pos := start + 1
+		for pos < len(path) && path[pos] >= '0' && path[pos] <= '9' {
+			pos++
+		}
+		if pos == start+1 {
+			return -1
+		}
+		return pos
+	case '[':
+		pos := strings.IndexByte(path[start:], ']')
+		if pos < 0 {
+			return -1
+		}
+		if _, _, ok := parseRange(path[start:start+pos+1], 0); !ok {
+			return -1
+		}
+		return start + pos + 1
+	}
+	return -1
+}
+
+// parseRange parses the "[from:to]" selector and returns its bounds normalized
+// to the array length: negative bounds count from the end, omitted bounds
+// default to the start and the end of the array.
+func parseRange(selector string, count int) (from, to int, ok bool) {
+	fromStr, toStr, ok := strings.Cut(selector[1:len(selector)-1], ":")
+	if !ok {
+		return 0, 0, false
+	}
+
+	bound := func(s string, def int) (int, bool) {
+		if s == "" {
+			return def, true
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, false
+		}
+		if n < 0 {
+			n += count
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n > count {
+			n = count
+		}
+		return n, true
+	}
+
+	if from, ok = bound(fromStr, 0); !ok {
+		return 0, 0, false
+	}
+	if to, ok = bound(toStr, count); !ok {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// queryEnd returns the end position of the "#", "#(cond)" or "#(cond)#" selector
+// starting at the position start, or -1 if the query is not terminated.
+func queryEnd(path string, start int) int {
+	pos := start + 1
+	if pos == len(path) || path[pos] != '(' {
+		return pos
+	}
+
+	depth := 0
+	inString := false
+	for ; pos < len(path); pos++ {
+		switch c := path[pos]; {
+		case inString && c == '\\':
+			pos++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				if pos+1 < len(path) && path[pos+1] == '#' {
+					return pos + 2
+				}
+				return pos + 1
+			}
+		}
+	}
+	return -1
+}
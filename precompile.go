@@ -0,0 +1,56 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Precompile extracts and caches rules of the types at startup, so the first
+// request per type in latency-sensitive services doesn't pay the reflection
+// cost of ParseStruct or MaskStruct, and resolves their actions, so
+// parametrized actions are built by factories in advance. Types are given by values or nil pointers,
+// e.g. Customer{} or (*Customer)(nil). Values not being structs, invalid paths
+// and actions that can't be resolved are reported in the joined error.
+func (jm *JsonMaskerImpl) Precompile(types ...any) error {
+	var errs []error
+	for _, src := range types {
+		t, err := structType(src)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		jm.precompileStructTags(t, map[reflect.Type]bool{})
+		for _, rule := range jm.ParseStruct(src).Rules {
+			if err := jm.checkRule(rule); err != nil {
+				errs = append(errs, fmt.Errorf("%v, rule %s: %w", t, rule.Path, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// structType returns the struct type of src given by a value or a pointer,
+// e.g. Customer{} or (*Customer)(nil), or ErrInvalidInput for other values.
+func structType(src any) (reflect.Type, error) {
+	t := reflect.TypeOf(src)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, t)
+	}
+	return t, nil
+}
+
+// Register extracts and caches rules of the type T like Precompile, so
+// MaskValue, masking values of T by rules of their type, and MaskStruct
+// don't pay the reflection cost on the first call, e.g.
+//
+//	if err := jsonmask.Register[Customer](jm); err != nil {
+//		log.Fatal(err)
+//	}
+func Register[T any](jm *JsonMaskerImpl) error {
+	return jm.Precompile((*T)(nil))
+}
@@ -0,0 +1,55 @@
+package jsonmask_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestPrecompile(t *testing.T) {
+	type Account struct {
+		IBAN  string `json:"iban" mask:"limit(4)"`
+		Email string `json:"email" mask:"email"`
+	}
+	type Broken struct {
+		Phone string `json:"phone" mask:"phone"`
+	}
+
+	jm := jsonmask.New()
+	assert.NoError(t, jm.Precompile(Account{}, (*Account)(nil)))
+
+	rec := httptest.NewRecorder()
+	jm.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/jsonmask/cache", nil))
+	cache := gjson.Parse(rec.Body.String())
+	assert.Equal(t, "limit(4)", cache.Get("types.jsonmask_test\\.Account.0.action").String())
+	assert.Equal(t, []any{"limit(4)"}, cache.Get("actions").Value())
+
+	err := jm.Precompile(Broken{}, "text")
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidInput)
+	assert.Contains(t, err.Error(), "jsonmask_test.Broken, rule phone: unknown action: phone")
+}
+
+func TestRegister(t *testing.T) {
+	type Card struct {
+		Number string `json:"number" mask:"first4"`
+	}
+
+	jm := jsonmask.New()
+	assert.NoError(t, jsonmask.Register[Card](jm))
+	assert.Equal(t, "first4", jm.Inventory()[0].Action)
+
+	masked, err := jm.MaskValue(Card{Number: "4111111111111111"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"number":"4111"}`, string(masked))
+
+	card := Card{Number: "4111111111111111"}
+	assert.NoError(t, jm.MaskStruct(&card))
+	assert.Equal(t, "4111", card.Number)
+
+	assert.ErrorIs(t, jsonmask.Register[int](jm), jsonmask.ErrInvalidInput)
+}
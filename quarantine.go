@@ -0,0 +1,75 @@
+package jsonmask
+
+import "encoding/json"
+
+// MaskQuarantine is like Mask but in addition returns the quarantine: original
+// values of masked and deleted paths encrypted with the current key of the
+// provider, supporting "redact but retain for compliance" workflows. The
+// quarantine is a JSON string like "enc:k1:Zm9v...", use RestoreQuarantine
+// to read original values.
+func (jm *JsonMaskerImpl) MaskQuarantine(data []byte, smr StructMaskRules, kp KeyProvider, opts ...MaskOption) (masked, quarantine []byte, err error) {
+	if jm.disabled {
+		return data, nil, nil
+	}
+
+	masked, originals, err := jm.MaskSplit(data, smr, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := json.Marshal(originals)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encrypted, err := encrypt(kp, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return masked, quote(encrypted), nil
+}
+
+// MaskSplit is like Mask but in addition returns original values of masked and
+// deleted paths, derived in the same pass, so callers can store sensitive values
+// in a vault and the masked document in regular storage. Values are keyed by
+// their paths in data, also when deleted array elements shift the masked document.
+func (jm *JsonMaskerImpl) MaskSplit(data []byte, smr StructMaskRules, opts ...MaskOption) (masked []byte, sensitive map[string]json.RawMessage, err error) {
+	if jm.disabled {
+		return data, map[string]json.RawMessage{}, nil
+	}
+
+	rules := jm.unknownFieldRules(data, smr.Fields, applyMaskOptions(smr.Rules, opts))
+	run := &maskRun{originals: make(map[string]string)}
+	masked, err = jm.maskRun(data, rules, run)
+	if err == nil {
+		masked, err = jm.postMask(masked)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return masked, rawValues(run.originals), nil
+}
+
+// RestoreQuarantine decrypts the quarantine returned by MaskQuarantine and
+// returns original values by their paths in the input document.
+func RestoreQuarantine(quarantine []byte, kp KeyProvider) (map[string]json.RawMessage, error) {
+	doc, err := DecryptFn(kp)(string(quarantine))
+	if err != nil {
+		return nil, err
+	}
+
+	var res map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// rawValues converts raw JSON values by paths to json.RawMessage values.
+func rawValues(values map[string]string) map[string]json.RawMessage {
+	res := make(map[string]json.RawMessage, len(values))
+	for path, raw := range values {
+		res[path] = json.RawMessage(raw)
+	}
+	return res
+}
@@ -0,0 +1,79 @@
+package jsonmask_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskQuarantine(t *testing.T) {
+	jm := jsonmask.New()
+	kp := jsonmask.NewStaticKeyProvider("k1", []byte("0123456789abcdef"))
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "email"},
+			{Path: "email", Action: "upper"},
+			{Path: "card", Action: "-"},
+			{Path: "missing", Action: "-"},
+		},
+	}
+
+	masked, quarantine, err := jm.MaskQuarantine([]byte(`{"name":"john","email":"john@example.com","card":{"number":"4111"}}`), rules, kp)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"john","email":"J**N@E******.COM"}`, string(masked))
+	assert.Contains(t, string(quarantine), `"enc:k1:`)
+	assert.NotContains(t, string(quarantine), "john")
+
+	originals, err := jsonmask.RestoreQuarantine(quarantine, kp)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{
+		"email": json.RawMessage(`"john@example.com"`),
+		"card":  json.RawMessage(`{"number":"4111"}`),
+	}, originals)
+
+	_, err = jsonmask.RestoreQuarantine(quarantine, jsonmask.NewStaticKeyProvider("k2", []byte("0123456789abcdef")))
+	assert.ErrorIs(t, err, jsonmask.ErrKeyNotFound)
+}
+
+func TestJsonMaskerImpl_MaskSplit(t *testing.T) {
+	jm := jsonmask.New()
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{{Path: "items.#.card", Action: "-"}, {Path: "name", Action: "initialChar"}},
+	}
+
+	masked, sensitive, err := jm.MaskSplit([]byte(`{"name":"john","items":[{"card":"4111"},{"id":1},{"card":"5500"}]}`), rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"J","items":[{},{"id":1},{}]}`, string(masked))
+	assert.Equal(t, map[string]json.RawMessage{
+		"name":         json.RawMessage(`"john"`),
+		"items.0.card": json.RawMessage(`"4111"`),
+		"items.2.card": json.RawMessage(`"5500"`),
+	}, sensitive)
+}
+
+func TestJsonMaskerImpl_MaskSplitShiftedElements(t *testing.T) {
+	jm := jsonmask.New()
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: `items.#(type=="test")#`, Action: "-"},
+			{Path: "items.#.card", Action: "truncate"},
+			{Path: "matrix.0.0", Action: "-"},
+			{Path: "matrix.0.0", Action: "upper"},
+		},
+	}
+
+	data := []byte(`{"items":[{"type":"test","card":"1111"},{"card":"4111"},{"type":"test"},{"card":"5500"}],"matrix":[["a","b"]]}`)
+	masked, sensitive, err := jm.MaskSplit(data, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":[{"card":""},{"card":""}],"matrix":[["B"]]}`, string(masked))
+	assert.Equal(t, map[string]json.RawMessage{
+		"items.0":      json.RawMessage(`{"type":"test","card":"1111"}`),
+		"items.2":      json.RawMessage(`{"type":"test"}`),
+		"items.1.card": json.RawMessage(`"4111"`),
+		"items.3.card": json.RawMessage(`"5500"`),
+		"matrix.0.0":   json.RawMessage(`"a"`),
+		"matrix.0.1":   json.RawMessage(`"b"`),
+	}, sensitive)
+}
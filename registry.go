@@ -0,0 +1,237 @@
+package jsonmask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// DefaultSchemaAnnotation is a default name of the schema attribute holding
+// the action of a field.
+const DefaultSchemaAnnotation = "x-mask"
+
+// Schema types of a schema registry.
+const (
+	SchemaAvro = "AVRO"
+	SchemaJSON = "JSON"
+)
+
+// SchemaRegistry loads rules derived from schemas of a Confluent-compatible
+// schema registry, keeping masking policy of event streams next to schemas
+// rather than in Go code. Fields are annotated with actions by the attribute
+// named Annotation, e.g. {"name":"email","type":"string","x-mask":"email"}
+// in Avro or {"type":"string","x-mask":"email"} in JSON Schema.
+type SchemaRegistry struct {
+	URL        string // e.g. "http://schema-registry:8081"
+	Username   string // basic authentication, if set
+	Password   string
+	Annotation string       // DefaultSchemaAnnotation by default
+	Client     *http.Client // http.DefaultClient by default
+}
+
+// Rules fetches the version of the subject's schema, "latest" if empty, and
+// returns rules derived from it. The rule set version is the schema version.
+func (r SchemaRegistry) Rules(ctx context.Context, subject, version string) (StructMaskRules, error) {
+	if version == "" {
+		version = "latest"
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u := strings.TrimSuffix(r.URL, "/") + "/subjects/" + url.PathEscape(subject) + "/versions/" + url.PathEscape(version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return StructMaskRules{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return StructMaskRules{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return StructMaskRules{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return StructMaskRules{}, fmt.Errorf("%w: %s, registry status %d", ErrRulesNotFound, subject, resp.StatusCode)
+	}
+
+	res := gjson.ParseBytes(body)
+	schemaType := res.Get("schemaType").Str
+	if schemaType == "" {
+		schemaType = SchemaAvro // the registry omits the default type
+	}
+
+	smr, err := SchemaRules(schemaType, []byte(res.Get("schema").Str), r.Annotation)
+	if err != nil {
+		return StructMaskRules{}, err
+	}
+	smr.Version = strconv.FormatInt(res.Get("version").Int(), 10)
+	return smr, nil
+}
+
+// LoadSchemaRules registers rule sets derived from the latest schemas of
+// subjects by subject name, e.g. to be referenced by "json(subject)".
+func (jm *JsonMaskerImpl) LoadSchemaRules(ctx context.Context, r SchemaRegistry, subjects ...string) error {
+	for _, subject := range subjects {
+		smr, err := r.Rules(ctx, subject, "")
+		if err != nil {
+			return err
+		}
+		if err := jm.AddRules(subject, smr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaRules derives rules from annotations of the Avro or JSON Schema schema.
+// Arrays are denoted by "#" and Avro maps by "*". Values of Avro unions are
+// expected without type wrappers. Only local references are resolved in JSON
+// Schema, e.g. "#/$defs/address".
+func SchemaRules(schemaType string, schema []byte, annotation string) (StructMaskRules, error) {
+	if !gjson.ValidBytes(schema) {
+		return StructMaskRules{}, ErrInvalidJSON
+	}
+	if annotation == "" {
+		annotation = DefaultSchemaAnnotation
+	}
+
+	d := schemaDeriver{root: gjson.ParseBytes(schema), annotation: annotation, visiting: make(map[string]bool)}
+	switch schemaType {
+	case SchemaAvro:
+		d.named = make(map[string]gjson.Result)
+		d.avro(d.root, "")
+	case SchemaJSON:
+		d.jsonSchema(d.root, "")
+	default:
+		return StructMaskRules{}, fmt.Errorf("%w: %s", ErrUnsupportedSchema, schemaType)
+	}
+	return StructMaskRules{Rules: d.rules}, nil
+}
+
+// schemaDeriver collects rules from schema annotations.
+type schemaDeriver struct {
+	root       gjson.Result
+	annotation string
+	named      map[string]gjson.Result // Avro named types by full name
+	namespace  string                  // Avro namespace of the enclosing named type
+	visiting   map[string]bool         // named types and references being inspected, to stop recursion
+	rules      []Rule
+}
+
+// avro collects rules of the Avro type found at the path.
+func (d *schemaDeriver) avro(t gjson.Result, path string) {
+	switch {
+	case t.IsArray(): // union
+		for _, branch := range t.Array() {
+			d.avro(branch, path)
+		}
+	case t.Type == gjson.String: // primitive or named type
+		name := t.Str
+		if _, ok := d.named[name]; !ok && d.namespace != "" {
+			name = d.namespace + "." + name
+		}
+		if named, ok := d.named[name]; ok && !d.visiting[name] {
+			d.visiting[name] = true
+			d.avro(named, path)
+			delete(d.visiting, name)
+		}
+	case t.IsObject():
+		if name := t.Get("name").Str; name != "" {
+			namespace := d.namespace
+			if ns := t.Get("namespace"); ns.Exists() {
+				namespace = ns.Str
+			}
+			if i := strings.LastIndexByte(name, '.'); i >= 0 {
+				namespace = name[:i]
+			} else if namespace != "" {
+				name = namespace + "." + name
+			}
+			d.named[name] = t
+
+			defer func(enclosing string) { d.namespace = enclosing }(d.namespace)
+			d.namespace = namespace
+		}
+
+		switch t.Get("type").Str {
+		case "record", "error":
+			for _, f := range t.Get("fields").Array() {
+				fieldPath := joinPath(path, pathEscaper.Replace(f.Get("name").Str))
+				if action := f.Get(d.annotation).Str; action != "" {
+					d.rules = append(d.rules, Rule{Path: fieldPath, Action: action})
+					continue
+				}
+				d.avro(f.Get("type"), fieldPath)
+			}
+		case "array":
+			d.avro(t.Get("items"), joinPath(path, "#"))
+		case "map":
+			d.avro(t.Get("values"), joinPath(path, "*"))
+		}
+	}
+}
+
+// jsonSchema collects rules of the JSON Schema found at the path.
+func (d *schemaDeriver) jsonSchema(s gjson.Result, path string) {
+	if !s.IsObject() {
+		return
+	}
+	if action := s.Get(d.annotation).Str; action != "" && path != "" {
+		d.rules = append(d.rules, Rule{Path: path, Action: action})
+		return
+	}
+
+	if ref := s.Get(`\$ref`).Str; strings.HasPrefix(ref, "#/") && !d.visiting[ref] {
+		d.visiting[ref] = true
+		d.jsonSchema(d.root.Get(refPath(ref)), path)
+		delete(d.visiting, ref)
+	}
+
+	s.Get("properties").ForEach(func(name, prop gjson.Result) bool {
+		d.jsonSchema(prop, joinPath(path, pathEscaper.Replace(name.Str)))
+		return true
+	})
+	if items := s.Get("items"); items.IsObject() {
+		d.jsonSchema(items, joinPath(path, "#"))
+	}
+	if values := s.Get("additionalProperties"); values.IsObject() {
+		d.jsonSchema(values, joinPath(path, "*"))
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		for _, sub := range s.Get(keyword).Array() {
+			d.jsonSchema(sub, path)
+		}
+	}
+}
+
+// refPath converts the local JSON pointer reference, e.g. "#/$defs/address",
+// to a gjson path.
+func refPath(ref string) string {
+	segments := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	for i, s := range segments {
+		s = strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+		segments[i] = pathEscaper.Replace(s)
+	}
+	return strings.Join(segments, ".")
+}
+
+// Error definitions
+var (
+	ErrUnsupportedSchema = errors.New("unsupported schema type")
+)
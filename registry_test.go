@@ -0,0 +1,97 @@
+package jsonmask_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+const avroOrder = `{
+	"type": "record", "name": "Order", "namespace": "shop",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "customer", "type": {
+			"type": "record", "name": "Customer",
+			"fields": [
+				{"name": "email", "type": "string", "x-mask": "email"},
+				{"name": "phones", "type": {"type": "array", "items": "string"}, "x-mask": "last4"}
+			]
+		}},
+		{"name": "payer", "type": ["null", "shop.Customer"]},
+		{"name": "cards", "type": {"type": "map", "values": {
+			"type": "record", "name": "Card",
+			"fields": [{"name": "pan", "type": "string", "x-mask": "pan"}]
+		}}}
+	]
+}`
+
+const jsonSchemaOrder = `{
+	"type": "object",
+	"properties": {
+		"id": {"type": "integer"},
+		"customer": {"$ref": "#/$defs/customer"},
+		"items": {"type": "array", "items": {
+			"type": "object",
+			"properties": {"note": {"type": "string", "x-mask": "-"}}
+		}}
+	},
+	"$defs": {
+		"customer": {
+			"type": "object",
+			"properties": {"email": {"type": "string", "x-mask": "email"}}
+		}
+	}
+}`
+
+func TestSchemaRules(t *testing.T) {
+	smr, err := jsonmask.SchemaRules(jsonmask.SchemaAvro, []byte(avroOrder), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "customer.email", Action: "email"},
+		{Path: "customer.phones", Action: "last4"},
+		{Path: "payer.email", Action: "email"},
+		{Path: "payer.phones", Action: "last4"},
+		{Path: "cards.*.pan", Action: "pan"},
+	}, smr.Rules)
+
+	smr, err = jsonmask.SchemaRules(jsonmask.SchemaJSON, []byte(jsonSchemaOrder), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []jsonmask.Rule{
+		{Path: "customer.email", Action: "email"},
+		{Path: "items.#.note", Action: "-"},
+	}, smr.Rules)
+
+	_, err = jsonmask.SchemaRules("PROTOBUF", []byte(`{}`), "")
+	assert.ErrorIs(t, err, jsonmask.ErrUnsupportedSchema)
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/subjects/orders-value/versions/latest" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"subject":"orders-value","version":7,"id":42,"schemaType":"JSON","schema":` + strconv.Quote(jsonSchemaOrder) + `}`))
+	}))
+	defer srv.Close()
+
+	reg := jsonmask.SchemaRegistry{URL: srv.URL}
+	jm := jsonmask.New()
+	assert.NoError(t, jm.LoadSchemaRules(context.Background(), reg, "orders-value"))
+
+	smr, ok := jm.Rules("orders-value")
+	assert.True(t, ok)
+	assert.Equal(t, "7", smr.Version)
+
+	res, err := jm.Mask([]byte(`{"id":1,"customer":{"email":"john@example.com"},"items":[{"note":"call me"}]}`), smr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"customer":{"email":"j**n@e******.com"},"items":[{}]}`, string(res))
+
+	_, err = reg.Rules(context.Background(), "missing", "")
+	assert.ErrorIs(t, err, jsonmask.ErrRulesNotFound)
+}
@@ -0,0 +1,64 @@
+package jsonmask
+
+import "fmt"
+
+// AddRules registers a named rule set. Named rule sets are referenced by
+// actions that mask nested documents, e.g. "base64(customer)".
+//
+// Several versions of a rule set can be registered side by side under the same
+// name, distinguished by StructMaskRules.Version. Registering a version again
+// replaces it. The version registered last is the current one.
+//
+// Rule sets exceeding limits set by WithRuleLimits are not registered,
+// ErrRuleLimit is returned.
+func (jm *JsonMaskerImpl) AddRules(name string, smr StructMaskRules) error {
+	if err := jm.checkLimits(smr); err != nil {
+		return fmt.Errorf("rule set %s: %w", name, err)
+	}
+
+	versions := jm.rules[name]
+	for i := range versions {
+		if versions[i].Version == smr.Version {
+			versions = append(versions[:i], versions[i+1:]...)
+			break
+		}
+	}
+	jm.rules[name] = append(versions, smr)
+	return nil
+}
+
+// Rules returns the current version of a registered rule set by name.
+// Tenant maskers of a Manager fall back to rule sets of the manager's base masker.
+func (jm *JsonMaskerImpl) Rules(name string) (StructMaskRules, bool) {
+	versions := jm.rules[name]
+	if len(versions) == 0 {
+		if jm.parent != nil {
+			return jm.parent.Rules(name)
+		}
+		return StructMaskRules{}, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// RulesVersion returns the given version of a registered rule set by name.
+func (jm *JsonMaskerImpl) RulesVersion(name, version string) (StructMaskRules, bool) {
+	for _, smr := range jm.rules[name] {
+		if smr.Version == version {
+			return smr, true
+		}
+	}
+	if jm.parent != nil {
+		return jm.parent.RulesVersion(name, version)
+	}
+	return StructMaskRules{}, false
+}
+
+// MaskVersion applies masking to JSON based on the given version of a registered
+// rule set, e.g. to re-mask archived payloads with the policy active at write time.
+func (jm *JsonMaskerImpl) MaskVersion(data []byte, name, version string) ([]byte, error) {
+	smr, ok := jm.RulesVersion(name, version)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s@%s", ErrRulesNotFound, name, version)
+	}
+	return jm.Mask(data, smr)
+}
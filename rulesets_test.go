@@ -0,0 +1,52 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskVersion(t *testing.T) {
+	jm := jsonmask.New()
+	jm.AddRules("customer", jsonmask.StructMaskRules{
+		Version: "2023-01",
+		Rules:   []jsonmask.Rule{{Path: "name", Action: "upper"}},
+	})
+	jm.AddRules("customer", jsonmask.StructMaskRules{
+		Version: "2024-01",
+		Rules:   []jsonmask.Rule{{Path: "name", Action: "initialChar"}},
+	})
+
+	data := []byte(`{"name":"john"}`)
+
+	result, err := jm.MaskVersion(data, "customer", "2023-01")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"JOHN"}`, string(result))
+
+	result, err = jm.MaskVersion(data, "customer", "2024-01")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"J"}`, string(result))
+
+	current, ok := jm.Rules("customer")
+	assert.True(t, ok)
+	assert.Equal(t, "2024-01", current.Version)
+
+	_, err = jm.MaskVersion(data, "customer", "2022-01")
+	assert.ErrorIs(t, err, jsonmask.ErrRulesNotFound)
+
+	t.Run("Replace", func(t *testing.T) {
+		jm.AddRules("customer", jsonmask.StructMaskRules{
+			Version: "2023-01",
+			Rules:   []jsonmask.Rule{{Path: "name", Action: "-"}},
+		})
+
+		result, err := jm.MaskVersion(data, "customer", "2023-01")
+		assert.NoError(t, err)
+		assert.Equal(t, `{}`, string(result))
+
+		// replaced version becomes the current one.
+		current, _ := jm.Rules("customer")
+		assert.Equal(t, "2023-01", current.Version)
+	})
+}
@@ -0,0 +1,120 @@
+package jsonmask
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Detector finds sensitive data in string values of a JSON document.
+type Detector struct {
+	// Name identifies the detector, e.g. "email".
+	Name string
+
+	// Pattern finds candidate matches.
+	Pattern *regexp.Regexp
+
+	// Validate optionally confirms a candidate match, e.g. by a checksum.
+	Validate func(match string) bool
+
+	// Mask returns a replacement of the match.
+	Mask func(match string) string
+}
+
+// DefaultDetectors holds built-in detectors of email addresses, IBANs,
+// card numbers passing the Luhn check and phone numbers, in order of application.
+// Phone numbers have 8 to 15 digits and start with "+", e.g. "+420 777 123 456",
+// or an area code in parentheses, e.g. "(555) 123-4567", or are grouped by
+// 3 digits, e.g. "555-123-4567", so dates, timestamps and IDs aren't matched.
+var DefaultDetectors = []Detector{
+	{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		Mask: func(match string) string {
+			return strings.Trim(string(Email(`"`+match+`"`)), `"`)
+		},
+	},
+	{
+		Name:    "iban",
+		Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		Mask: func(match string) string {
+			return maskLettersDigits(match, 4, 4, '*')
+		},
+	},
+	{
+		Name:     "card",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: luhnValid,
+		Mask: func(match string) string {
+			return maskLettersDigits(match, 0, 4, '*')
+		},
+	},
+	{
+		Name: "phone",
+		Pattern: regexp.MustCompile(`\+\d{1,3}(?:[ -]?\(?\d{1,4}\)?){2,5}\b|` +
+			`\(\d{2,4}\)[ -]?\d{3,4}[ -]?\d{3,4}\b|\b\d{3}[ -]\d{3}[ -]\d{3,4}\b`),
+		Validate: phoneValid,
+		Mask: func(match string) string {
+			return maskLettersDigits(match, 0, 2, '*')
+		},
+	},
+}
+
+// phoneValid reports whether the match has 8 to 15 digits, the length of
+// E.164 phone numbers.
+func phoneValid(match string) bool {
+	n := 0
+	for _, c := range match {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n >= 8 && n <= 15
+}
+
+// ScanAndMask runs detectors over every string value of the JSON document and masks
+// found matches, catching sensitive data in attributes not covered by any rule.
+// DefaultDetectors are used if no detectors are given.
+func (jm *JsonMaskerImpl) ScanAndMask(data []byte, detectors ...Detector) ([]byte, error) {
+	if !gjson.ValidBytes(data) {
+		return nil, ErrInvalidJSON
+	}
+
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors
+	}
+
+	var edits []edit
+	scan := func(value gjson.Result) {
+		if value.Type != gjson.String {
+			return
+		}
+
+		if str := detect(value.Str, detectors); str != value.Str {
+			edits = append(edits, edit{start: value.Index, end: value.Index + len(value.Raw), raw: quote(str)})
+		}
+	}
+
+	root := gjson.ParseBytes(data)
+	scan(root)
+	walk(root, nil, func(_ []string, value gjson.Result) bool {
+		scan(value)
+		return true
+	})
+
+	return applyEdits(data, edits), nil
+}
+
+// detect returns the text with matches of detectors masked, in order of detectors.
+func detect(text string, detectors []Detector) string {
+	for _, d := range detectors {
+		text = d.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if d.Validate != nil && !d.Validate(match) {
+				return match
+			}
+			return d.Mask(match)
+		})
+	}
+	return text
+}
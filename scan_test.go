@@ -0,0 +1,67 @@
+package jsonmask_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_ScanAndMask(t *testing.T) {
+	jm := jsonmask.New()
+
+	data := []byte(`{
+		"note": "contact john.doe@example.com or +420 777 123 456",
+		"items": [
+			{"comment": "paid by 4111 1111 1111 1111"},
+			{"comment": "order 123456"}
+		],
+		"account": "CZ65 0800 0000 1920 0014 5399",
+		"count": 5,
+		"plain": "nothing here"
+	}`)
+
+	result, err := jm.ScanAndMask(data)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"note": "contact j******e@e******.com or +*** *** *** *56",
+		"items": [
+			{"comment": "paid by **** **** **** 1111"},
+			{"comment": "order 123456"}
+		],
+		"account": "CZ65 **** **** **** **** 5399",
+		"count": 5,
+		"plain": "nothing here"
+	}`, string(result))
+
+	t.Run("Phones", func(t *testing.T) {
+		result, err := jm.ScanAndMask([]byte(`["call (555) 123-4567","555-123-4567","+1 555 123 4567"]`))
+		assert.NoError(t, err)
+		assert.Equal(t, `["call (***) ***-**67","***-***-**67","+* *** *** **67"]`, string(result))
+	})
+
+	t.Run("NotPhones", func(t *testing.T) {
+		data := []byte(`["2024-05-01","2024-05-01T10:20:30.123Z","2024-05-01 10:20:30",` +
+			`"123e4567-e89b-12d3-a456-426614174000","550e8400-e29b-41d4-a716-446655440000",` +
+			`"192.168.100.1","v1.20.3","order 12345678","1700000000"]`)
+		result, err := jm.ScanAndMask(data)
+		assert.NoError(t, err)
+		assert.Equal(t, string(data), string(result))
+	})
+
+	t.Run("CustomDetector", func(t *testing.T) {
+		result, err := jm.ScanAndMask([]byte(`["token=abc","x"]`), jsonmask.Detector{
+			Name:    "token",
+			Pattern: regexp.MustCompile(`token=\w+`),
+			Mask:    func(string) string { return "token=***" },
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `["token=***","x"]`, string(result))
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		_, err := jm.ScanAndMask([]byte(`{"a":`))
+		assert.ErrorIs(t, err, jsonmask.ErrInvalidJSON)
+	})
+}
@@ -0,0 +1,198 @@
+package jsonmask
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SecretProvider provides keys and salts by name from a secret store, so they
+// are never hard-coded in rule definitions.
+type SecretProvider interface {
+	Secret(ctx context.Context, name string) ([]byte, error)
+}
+
+// EnvSecretProvider reads secrets from environment variables named by the prefix
+// and the secret name in upper case with non-alphanumeric characters replaced
+// with '_', e.g. "JSONMASK_SECRET_HMAC_V1" for the name "hmac-v1". Values
+// prefixed with "base64:" are decoded.
+type EnvSecretProvider struct {
+	Prefix string
+}
+
+// Secret implements SecretProvider.
+func (p EnvSecretProvider) Secret(_ context.Context, name string) ([]byte, error) {
+	env := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+
+	value, ok := os.LookupEnv(p.Prefix + env)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	if encoded, ok := strings.CutPrefix(value, "base64:"); ok {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+	return []byte(value), nil
+}
+
+// VaultSecretProvider reads secrets from the KV version 2 secrets engine of
+// HashiCorp Vault over its HTTP API. The secret name is "path#field", e.g.
+// "jsonmask/keys#hmac-v1", the field value is decoded from base64 if prefixed
+// with "base64:".
+type VaultSecretProvider struct {
+	Addr   string // e.g. "https://vault.example.com:8200"
+	Token  string
+	Mount  string       // mount path of the engine, "secret" by default
+	Client *http.Client // http.DefaultClient by default
+}
+
+// Secret implements SecretProvider.
+func (p VaultSecretProvider) Secret(ctx context.Context, name string) ([]byte, error) {
+	path, field, ok := strings.Cut(name, "#")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, field missing", ErrKeyNotFound, name)
+	}
+
+	mount := p.Mount
+	if mount == "" {
+		mount = "secret"
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u := strings.TrimSuffix(p.Addr, "/") + "/v1/" + url.PathEscape(mount) + "/data/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s, vault status %d", ErrKeyNotFound, name, resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	value, ok := body.Data.Data[field]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	if encoded, ok := strings.CutPrefix(value, "base64:"); ok {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+	return []byte(value), nil
+}
+
+// KMSSecretProvider unwraps secrets stored encrypted by a cloud KMS (AWS KMS,
+// Google Cloud KMS, Azure Key Vault). Wrapped secrets are read from the source
+// and decrypted by the Decrypt function, usually a thin wrapper of the KMS client.
+type KMSSecretProvider struct {
+	Source  SecretProvider
+	Decrypt func(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// Secret implements SecretProvider.
+func (p KMSSecretProvider) Secret(ctx context.Context, name string) ([]byte, error) {
+	wrapped, err := p.Source.Secret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return p.Decrypt(ctx, wrapped)
+}
+
+// SecretKeyProvider is a KeyProvider using secrets as keys, key identifiers are
+// secret names. Secrets are read on first use and cached.
+type SecretKeyProvider struct {
+	sp      SecretProvider
+	current string
+	keys    sync.Map // name -> []byte
+}
+
+// NewSecretKeyProvider creates a new instance of SecretKeyProvider using
+// the secret with the name as the current key.
+func NewSecretKeyProvider(sp SecretProvider, current string) *SecretKeyProvider {
+	return &SecretKeyProvider{sp: sp, current: current}
+}
+
+// CurrentKey implements KeyProvider.
+func (kp *SecretKeyProvider) CurrentKey() (string, []byte, error) {
+	key, err := kp.Key(kp.current)
+	return kp.current, key, err
+}
+
+// Key implements KeyProvider.
+func (kp *SecretKeyProvider) Key(id string) ([]byte, error) {
+	if key, ok := kp.keys.Load(id); ok {
+		return key.([]byte), nil
+	}
+
+	key, err := kp.sp.Secret(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	kp.keys.Store(id, key)
+	return key, nil
+}
+
+// WithSecretProvider registers parametrized actions "hmac(name)" and
+// "encrypt(name)" using the secret with the name as the key, see HMACFn
+// and EncryptFn. Values encrypted by any of the secrets are restored by Unmask.
+func WithSecretProvider(sp SecretProvider) Option {
+	return func(jm *JsonMaskerImpl) {
+		keyProvider := func(name string) (KeyProvider, error) {
+			kp := NewSecretKeyProvider(sp, name)
+			if _, _, err := kp.CurrentKey(); err != nil {
+				return nil, err
+			}
+			return kp, nil
+		}
+
+		jm.registerErr(jm.AddCryptoFactory("hmac", AlgHMACSHA256, func(name string) (func(string) []byte, error) {
+			kp, err := keyProvider(name)
+			if err != nil {
+				return nil, err
+			}
+			return HMACFn(kp), nil
+		}))
+		err := jm.AddCryptoFactory("encrypt", AlgAESGCM, func(name string) (func(string) []byte, error) {
+			kp, err := keyProvider(name)
+			if err != nil {
+				return nil, err
+			}
+			return EncryptFn(kp), nil
+		})
+		jm.registerErr(err)
+		if err == nil {
+			// the key identifier embedded in the value is the secret name
+			jm.unmaskFuncs["encrypt"] = DecryptFn(NewSecretKeyProvider(sp, ""))
+		}
+	}
+}
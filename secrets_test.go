@@ -0,0 +1,90 @@
+package jsonmask_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("JSONMASK_HMAC_V1", "plain")
+	t.Setenv("JSONMASK_ENC_V1", "base64:AAEC")
+
+	sp := jsonmask.EnvSecretProvider{Prefix: "JSONMASK_"}
+	secret, err := sp.Secret(context.Background(), "hmac-v1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("plain"), secret)
+
+	secret, err = sp.Secret(context.Background(), "enc.v1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, secret)
+
+	_, err = sp.Secret(context.Background(), "missing")
+	assert.ErrorIs(t, err, jsonmask.ErrKeyNotFound)
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "t0ken" || r.URL.Path != "/v1/kv/data/jsonmask/keys" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"hmac":"base64:AAEC","salt":"pepper"},"metadata":{"version":3}}}`))
+	}))
+	defer srv.Close()
+
+	sp := jsonmask.VaultSecretProvider{Addr: srv.URL, Token: "t0ken", Mount: "kv"}
+	secret, err := sp.Secret(context.Background(), "jsonmask/keys#hmac")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2}, secret)
+
+	secret, err = sp.Secret(context.Background(), "jsonmask/keys#salt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pepper"), secret)
+
+	_, err = sp.Secret(context.Background(), "jsonmask/keys#missing")
+	assert.ErrorIs(t, err, jsonmask.ErrKeyNotFound)
+
+	_, err = jsonmask.VaultSecretProvider{Addr: srv.URL, Token: "bad"}.Secret(context.Background(), "jsonmask/keys#hmac")
+	assert.ErrorIs(t, err, jsonmask.ErrKeyNotFound)
+}
+
+func TestWithSecretProvider(t *testing.T) {
+	t.Setenv("WRAPPED_K1", strings.Repeat("x", 32))
+
+	// the fake KMS "decrypts" wrapped keys by upper-casing them
+	sp := jsonmask.KMSSecretProvider{
+		Source: jsonmask.EnvSecretProvider{Prefix: "WRAPPED_"},
+		Decrypt: func(_ context.Context, ciphertext []byte) ([]byte, error) {
+			return []byte(strings.ToUpper(string(ciphertext))), nil
+		},
+	}
+
+	jm := jsonmask.New(jsonmask.WithSecretProvider(sp), jsonmask.WithConfig(jsonmask.Config{Strict: true}))
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "encrypt(k1)"},
+			{Path: "phone", Action: "hmac(k1)"},
+		},
+	}
+
+	masked, err := jm.Mask([]byte(`{"email":"john@example.com","phone":"123"}`), rules)
+	assert.NoError(t, err)
+	assert.Contains(t, string(masked), `"email":"enc:k1:`)
+	assert.Contains(t, string(masked), `"phone":"hmac:k1:`)
+
+	restored, report, err := jm.Unmask(masked, rules)
+	assert.NoError(t, err)
+	assert.Contains(t, string(restored), `"email":"john@example.com"`)
+	assert.Equal(t, []string{"phone"}, report.Unrestored)
+
+	_, err = jm.Mask([]byte(`{"email":"john@example.com"}`), jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{{Path: "email", Action: "encrypt(k2)"}},
+	})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+}
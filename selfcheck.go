@@ -0,0 +1,78 @@
+package jsonmask
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SelfCheck verifies the masker is ready to mask, intended to be called in main()
+// to fail fast instead of leaking or dropping data at runtime: every rule of every
+// version of registered rule sets, parents' included, has a valid path and an
+// action resolving to a masking function, the action of WithUnknownFields is
+// known and registrations made by options succeeded, e.g. of crypto maskers in
+// FIPS mode. Resolving parametrized actions reaches key providers of crypto
+// maskers registered by WithSecretProvider. Key providers of functions added
+// by AddFunc, e.g. HMACFn, are checked if passed. All problems are joined in
+// the returned error.
+func (jm *JsonMaskerImpl) SelfCheck(kps ...KeyProvider) error {
+	var errs []error
+	for _, err := range jm.errs {
+		errs = append(errs, fmt.Errorf("registration: %w", err))
+	}
+
+	sets := jm.debugRules()
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, smr := range sets[name] {
+			set := name
+			if smr.Version != "" {
+				set += "@" + smr.Version
+			}
+			for i, rule := range smr.Rules {
+				if err := jm.checkRule(rule); err != nil {
+					errs = append(errs, fmt.Errorf("rule set %s, rule %d (%s): %w", set, i, rule.Path, err))
+				}
+			}
+		}
+	}
+
+	if jm.unknownFields != "" && jm.unknownFields != "-" {
+		if _, err := jm.resolveFunc(jm.unknownFields); err != nil {
+			errs = append(errs, fmt.Errorf("unknown fields: %w", err))
+		}
+	}
+
+	for i, kp := range kps {
+		if _, _, err := kp.CurrentKey(); err != nil {
+			errs = append(errs, fmt.Errorf("key provider %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkRule returns an error if the rule path is invalid or its action can't be resolved.
+func (jm *JsonMaskerImpl) checkRule(rule Rule) error {
+	path := rule.Path
+	if isExclusion(rule) {
+		path = path[1:]
+	}
+	if msg := pathSyntaxError(path); msg != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidPath, msg)
+	}
+	if err := jm.checkModifiers(path); err != nil {
+		return err
+	}
+
+	if isExclusion(rule) || rule.Action == "-" {
+		return nil
+	}
+	_, err := jm.resolveFunc(rule.Action)
+	return err
+}
@@ -0,0 +1,41 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingKeyProvider struct{}
+
+func (failingKeyProvider) CurrentKey() (string, []byte, error) { return "", nil, jsonmask.ErrKeyNotFound }
+func (failingKeyProvider) Key(string) ([]byte, error)          { return nil, jsonmask.ErrKeyNotFound }
+
+func TestSelfCheck(t *testing.T) {
+	t.Setenv("JSONMASK_K1", "secret")
+
+	jm := jsonmask.New(jsonmask.WithSecretProvider(jsonmask.EnvSecretProvider{Prefix: "JSONMASK_"}))
+	jm.AddRules("customer", jsonmask.StructMaskRules{Rules: []jsonmask.Rule{
+		{Path: "email", Action: "hmac(k1)"},
+		{Path: "!email.verified"},
+		{Path: "notes", Action: "-"},
+	}})
+	assert.NoError(t, jm.SelfCheck(jsonmask.NewStaticKeyProvider("k1", []byte("key"))))
+
+	jm.AddRules("order", jsonmask.StructMaskRules{Version: "v2", Rules: []jsonmask.Rule{
+		{Path: "card", Action: "pan"},
+		{Path: "items..sku", Action: "upper"},
+		{Path: "phone", Action: "hmac(k2)"},
+	}})
+	err := jm.SelfCheck(failingKeyProvider{})
+	assert.ErrorIs(t, err, jsonmask.ErrUnknownAction)
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidPath)
+	assert.ErrorIs(t, err, jsonmask.ErrKeyNotFound)
+	assert.Contains(t, err.Error(), "rule set order@v2, rule 0 (card): unknown action: pan")
+	assert.Contains(t, err.Error(), "rule set order@v2, rule 2 (phone): action hmac(k2): key not found")
+	assert.Contains(t, err.Error(), "key provider 0: key not found")
+
+	tenant := jsonmask.NewManager(jsonmask.WithUnknownFields("fake")).Tenant("acme")
+	assert.ErrorIs(t, tenant.SelfCheck(), jsonmask.ErrUnknownAction)
+}
@@ -0,0 +1,183 @@
+package jsonmask
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+)
+
+// SSEWriter masks JSON payloads of Server-Sent Events written to it and passes
+// them to the underlying writer, e.g. when proxying event streams to less-trusted
+// consumers. Events are buffered until complete. Payloads of event types without
+// rules, payloads not being JSON, comments and other fields are passed as is.
+type SSEWriter struct {
+	jm    *JsonMaskerImpl
+	w     io.Writer
+	rules map[string]StructMaskRules // event type -> rules
+
+	buf   []byte   // incomplete line
+	lines [][]byte // lines of the current event with line terminators
+}
+
+// NewSSEWriter creates a new instance of SSEWriter masking data of events
+// with rules by event type. Events without the event field are of type "message".
+func (jm *JsonMaskerImpl) NewSSEWriter(w io.Writer, rules map[string]StructMaskRules) *SSEWriter {
+	return &SSEWriter{jm: jm, w: w, rules: rules}
+}
+
+// MaskSSE copies the event stream from src to dst masking payloads of events.
+func (jm *JsonMaskerImpl) MaskSSE(dst io.Writer, src io.Reader, rules map[string]StructMaskRules) error {
+	sw := jm.NewSSEWriter(dst, rules)
+	if _, err := io.Copy(sw, src); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// Write implements io.Writer.
+func (sw *SSEWriter) Write(p []byte) (int, error) {
+	sw.buf = append(sw.buf, p...)
+
+	for {
+		end := bytes.IndexAny(sw.buf, "\r\n")
+		if end < 0 {
+			break
+		}
+
+		// a line is terminated by CRLF, LF or CR, CR can be the last byte seen so far
+		next := end + 1
+		if sw.buf[end] == '\r' {
+			if next == len(sw.buf) {
+				break
+			}
+			if sw.buf[next] == '\n' {
+				next++
+			}
+		}
+
+		line := append([]byte(nil), sw.buf[:next]...)
+		sw.buf = sw.buf[next:]
+
+		sw.lines = append(sw.lines, line)
+		if end == 0 {
+			// an empty line dispatches the event
+			if err := sw.writeEvent(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush implements http.Flusher passing complete events to the client,
+// if the underlying writer supports flushing.
+func (sw *SSEWriter) Flush() {
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes the incomplete event left at the end of the stream.
+// It doesn't close the underlying writer.
+func (sw *SSEWriter) Close() error {
+	if len(sw.buf) > 0 {
+		sw.lines = append(sw.lines, sw.buf)
+		sw.buf = nil
+	}
+	return sw.writeEvent()
+}
+
+// writeEvent writes lines of the current event masking its data.
+func (sw *SSEWriter) writeEvent() error {
+	lines, err := sw.maskEvent(sw.lines)
+	sw.lines = sw.lines[:0]
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if _, err := sw.w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maskEvent returns lines of the event with data lines replaced by masked data.
+func (sw *SSEWriter) maskEvent(lines [][]byte) ([][]byte, error) {
+	event := "message"
+	first := -1 // index of the first data line
+	var data [][]byte
+
+	for i, line := range lines {
+		name, value := sseField(line)
+		switch name {
+		case "event":
+			event = string(value)
+		case "data":
+			if first < 0 {
+				first = i
+			}
+			data = append(data, value)
+		}
+	}
+
+	smr, ok := sw.rules[event]
+	if !ok || first < 0 {
+		return lines, nil
+	}
+
+	payload := bytes.Join(data, []byte{'\n'})
+	if !gjson.ValidBytes(payload) {
+		return lines, nil
+	}
+
+	masked, err := sw.jm.Mask(payload, smr)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep style of the first data line, "data:" or "data: ", and its terminator
+	line := lines[first]
+	terminator := line[len(bytes.TrimRight(line, "\r\n")):]
+	prefix := []byte("data:")
+	if bytes.HasPrefix(line, []byte("data: ")) {
+		prefix = []byte("data: ")
+	}
+
+	var res [][]byte
+	for i, line := range lines {
+		name, _ := sseField(line)
+		switch {
+		case i == first:
+			parts := bytes.Split(masked, []byte{'\n'})
+			for j, part := range parts {
+				l := append(append([]byte(nil), prefix...), part...)
+				if j < len(parts)-1 && len(terminator) == 0 {
+					l = append(l, '\n') // the last line of the stream has no terminator
+				}
+				res = append(res, append(l, terminator...))
+			}
+		case name != "data":
+			res = append(res, line)
+		}
+	}
+	return res, nil
+}
+
+// sseField parses the line of an event to the field name and value.
+func sseField(line []byte) (string, []byte) {
+	line = bytes.TrimRight(line, "\r\n")
+	if len(line) == 0 || line[0] == ':' {
+		return "", nil // empty line or comment
+	}
+
+	name, value, found := bytes.Cut(line, []byte{':'})
+	if !found {
+		return string(line), nil
+	}
+	return string(name), bytes.TrimPrefix(value, []byte{' '})
+}
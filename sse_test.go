@@ -0,0 +1,53 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskSSE(t *testing.T) {
+	jm := jsonmask.New()
+	rules := map[string]jsonmask.StructMaskRules{
+		"message":  {Rules: []jsonmask.Rule{{Path: "text", Action: "upper"}}},
+		"customer": {Rules: []jsonmask.Rule{{Path: "email", Action: "email"}}},
+	}
+
+	src := ": keep-alive\n" +
+		"data: {\"text\":\"hi\"}\n\n" +
+		"event: customer\r\n" +
+		"id: 7\r\n" +
+		"data: {\"email\":\r\n" +
+		"data: \"john@example.com\"}\r\n\r\n" +
+		"event: other\n" +
+		"data: {\"email\":\"john@example.com\"}\n\n" +
+		"data:not json\n\n" +
+		"data:{\"text\":\"last\"}"
+
+	var dst bytes.Buffer
+	assert.NoError(t, jm.MaskSSE(&dst, strings.NewReader(src), rules))
+	assert.Equal(t, ": keep-alive\n"+
+		"data: {\"text\":\"HI\"}\n\n"+
+		"event: customer\r\n"+
+		"id: 7\r\n"+
+		"data: {\"email\":\r\n"+
+		"data: \"j**n@e******.com\"}\r\n\r\n"+
+		"event: other\n"+
+		"data: {\"email\":\"john@example.com\"}\n\n"+
+		"data:not json\n\n"+
+		"data:{\"text\":\"LAST\"}", dst.String())
+
+	// written byte by byte
+	dst.Reset()
+	sw := jm.NewSSEWriter(&dst, rules)
+	for i := 0; i < len(src); i++ {
+		_, err := sw.Write([]byte{src[i]})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, sw.Close())
+	assert.Contains(t, dst.String(), "data: \"j**n@e******.com\"}\r\n\r\n")
+	assert.True(t, strings.HasSuffix(dst.String(), "data:{\"text\":\"LAST\"}"))
+}
@@ -0,0 +1,533 @@
+package jsonmask
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// MaskStream copies JSON documents from src to dst masking them by rules as
+// tokens are parsed, so large payloads, e.g. bodies passing an HTTP proxy,
+// aren't buffered as a whole: only values selected by rules are, as well as
+// arrays selected by negative indexes. Whitespace-separated documents, e.g.
+// NDJSON, are masked one by one. Values not selected are copied as is without
+// validation; invalid JSON found while parsing fails with ErrInvalidJSON,
+// dst may hold a partial output then.
+//
+// Rules or options needing the whole document make every document be
+// buffered as a whole and masked by Mask, one by one: conditions, modifiers,
+// "**", exclusions, keys rules, sampling, array limits, deletions of array
+// elements, post-mask hooks, canonical output, idempotent markers, drift
+// handlers and unknown fields. Memory use grows with the largest document then.
+func (jm *JsonMaskerImpl) MaskStream(dst io.Writer, src io.Reader, smr StructMaskRules, opts ...MaskOption) error {
+	if jm.disabled {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	rules := applyMaskOptions(smr.Rules, opts)
+	e, matchers, ok, err := jm.streamPass(smr, rules)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s := &jsonStream{r: bufio.NewReader(src), w: bufio.NewWriter(dst)}
+		err := s.documents(func() error {
+			data, err := s.readValue()
+			if err != nil {
+				return err
+			}
+			if data, err = jm.Mask(data, smr, opts...); err != nil {
+				return err
+			}
+			_, err = s.w.Write(data)
+			return err
+		})
+		if err == nil {
+			err = s.w.Flush()
+		}
+		return err
+	}
+
+	cr := &countingReader{r: src}
+	s := &jsonStream{r: bufio.NewReader(cr), w: bufio.NewWriter(dst), e: e}
+	err = s.documents(func() error { return s.value(matchers, "") })
+	if err == nil {
+		err = s.w.Flush()
+	}
+	if err == nil {
+		e.finish()
+	}
+	jm.stats.masked(int(cr.n), err)
+	return err
+}
+
+// MaskReader returns a reader of JSON documents read from src masked by rules,
+// see MaskStream. Masking runs in a goroutine as the result is read; the
+// reader should be read to the end or closed.
+func (jm *JsonMaskerImpl) MaskReader(src io.Reader, smr StructMaskRules, opts ...MaskOption) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(jm.MaskStream(pw, src, smr, opts...))
+	}()
+	return pr
+}
+
+// MaskWriter returns a writer masking JSON documents written to it by rules
+// and writing the result to dst, see MaskStream. Close flushes the rest of
+// the output and returns the masking error, if any.
+func (jm *JsonMaskerImpl) MaskWriter(dst io.Writer, smr StructMaskRules, opts ...MaskOption) io.WriteCloser {
+	pr, pw := io.Pipe()
+	w := &maskWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := jm.MaskStream(dst, pr, smr, opts...)
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w
+}
+
+// maskWriter feeds MaskStream running in a goroutine.
+type maskWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *maskWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *maskWriter) Close() error {
+	_ = w.pw.Close()
+	return <-w.done
+}
+
+// streamPass returns the single pass of rules for streaming. It's not ok if
+// rules or options need the whole document.
+func (jm *JsonMaskerImpl) streamPass(smr StructMaskRules, rules []Rule) (*singlePass, []passMatcher, bool, error) {
+	if jm.marker != "" || jm.canonical || len(jm.postMaskHooks) > 0 || jm.driftHandler != nil ||
+		(jm.unknownFields != "" && len(smr.Fields) > 0) {
+		return nil, nil, false, nil
+	}
+	for _, rule := range rules {
+		if rule.When != "" {
+			return nil, nil, false, nil
+		}
+	}
+	return jm.compileSinglePass(rules)
+}
+
+// countingReader counts bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// jsonStream masks JSON tokens read from r, writing them to w.
+type jsonStream struct {
+	r *bufio.Reader
+	w *bufio.Writer
+	e *singlePass
+}
+
+// documents calls mask for every document up to the end of the input,
+// whitespace between documents is copied as is.
+func (s *jsonStream) documents(mask func() error) error {
+	for {
+		space, err := s.space(nil)
+		if _, werr := s.w.Write(space); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := mask(); err != nil {
+			return err
+		}
+	}
+}
+
+// value masks the value at the current position by rules of matchers, all
+// having segments left, so the value is kept.
+func (s *jsonStream) value(matchers []passMatcher, path string) error {
+	c, err := s.peek()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(matchers) == 0 || (c != '{' && c != '['):
+		return s.scanValue(s.w)
+	case c == '[' && !streamableArray(matchers):
+		raw, err := s.readValue()
+		if err != nil {
+			return err
+		}
+		res := s.e.descend(nil, string(raw), path, matchers)
+		if s.e.err != nil {
+			return s.e.err
+		}
+		_, err = s.w.Write(res)
+		return err
+	}
+	return s.container(matchers, path)
+}
+
+// container masks the object or array at the current position attribute by
+// attribute, buffering only values masked by rules. Text between members is
+// kept, separators of deleted members are dropped like by the single pass.
+func (s *jsonStream) container(matchers []passMatcher, path string) error {
+	open, _ := s.r.ReadByte()
+	isObject := open == '{'
+	closing := byte(']')
+	if isObject {
+		closing = '}'
+	}
+	if err := s.w.WriteByte(open); err != nil {
+		return err
+	}
+
+	matched := make([]bool, len(matchers)) // first attributes with keys or elements matching queries
+	var pending []byte                     // text preceding the value of the member
+	var key bytes.Buffer
+	lastKept, skipSep := false, false
+
+	for i := 0; ; i++ {
+		var err error
+		if pending, err = s.space(pending[:0]); err != nil {
+			return unexpected(err)
+		}
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		if c == closing {
+			if _, err := s.w.Write(pending); err != nil {
+				return err
+			}
+			return s.w.WriteByte(c)
+		}
+		if i > 0 {
+			if c != ',' {
+				return fmt.Errorf("%w: unexpected %q", ErrInvalidJSON, c)
+			}
+			pending = append(pending, c)
+			if pending, err = s.space(pending); err != nil {
+				return unexpected(err)
+			}
+		} else {
+			_ = s.r.UnreadByte()
+		}
+
+		keyOffset := len(pending)
+		var name string
+		if isObject {
+			if c, err := s.peek(); err != nil || c != '"' {
+				return fmt.Errorf("%w: object key expected", ErrInvalidJSON)
+			}
+			key.Reset()
+			if err := s.scanValue(&key); err != nil {
+				return err
+			}
+			name = gjson.ParseBytes(key.Bytes()).Str
+			pending = append(pending, key.Bytes()...)
+			if pending, err = s.space(pending); err != nil {
+				return unexpected(err)
+			}
+			if c, err := s.r.ReadByte(); err != nil || c != ':' {
+				return fmt.Errorf("%w: colon expected", ErrInvalidJSON)
+			}
+			pending = append(pending, ':')
+			if pending, err = s.space(pending); err != nil {
+				return unexpected(err)
+			}
+		}
+
+		children, raw, err := s.selectMember(matchers, matched, i, name, isObject)
+		if err != nil {
+			return err
+		}
+
+		keep := func() error {
+			out := pending
+			if skipSep {
+				out = pending[keyOffset:]
+			}
+			lastKept, skipSep = true, false
+			_, err := s.w.Write(out)
+			return err
+		}
+
+		childPath := ""
+		if s.e.jm.validOutput {
+			elem := strconv.Itoa(i)
+			if isObject {
+				elem = pathEscaper.Replace(name)
+			}
+			childPath = joinPath(path, elem)
+		}
+
+		switch {
+		case len(children) == 0:
+			if err := keep(); err != nil {
+				return err
+			}
+			if raw != nil {
+				_, err = s.w.Write(raw)
+			} else {
+				err = s.scanValue(s.w)
+			}
+		case raw != nil || hasTerminal(children):
+			if raw == nil {
+				if raw, err = s.readValue(); err != nil {
+					return err
+				}
+			}
+			res, kept := s.e.apply(nil, string(raw), childPath, children)
+			if s.e.err != nil {
+				return s.e.err
+			}
+			if kept {
+				if err = keep(); err == nil {
+					_, err = s.w.Write(res)
+				}
+			} else if !lastKept && !skipSep {
+				// drop the separator following the member
+				skipSep = true
+				_, err = s.w.Write(pending[:keyOffset])
+			}
+		default:
+			if err = keep(); err == nil {
+				err = s.value(children, childPath)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// selectMember returns matchers of the i-th member of the container selected
+// by matchers of the container, see selectMembers. Array elements selected by
+// queries are read and returned.
+func (s *jsonStream) selectMember(matchers []passMatcher, matched []bool, i int, name string, isObject bool) (children []passMatcher, raw []byte, err error) {
+	for j, m := range matchers {
+		seg := m.segs[0]
+		selected := false
+		switch {
+		case isObject && seg.selector && seg.key == "*":
+			selected = true
+		case isObject:
+			if (!seg.selector || seg.key[0] == '-') && !matched[j] && name == seg.key {
+				selected, matched[j] = true, true
+			}
+		case !seg.selector:
+			n, err := strconv.Atoi(seg.key)
+			selected = err == nil && n == i
+		case seg.key == "*" || seg.key == "#":
+			selected = true
+		case seg.key[0] == '[':
+			from, to := streamRange(seg.key)
+			selected = i >= from && (to < 0 || i < to)
+		default: // query
+			first := !strings.HasSuffix(seg.key, ")#")
+			if first && matched[j] {
+				continue
+			}
+			if raw == nil {
+				if raw, err = s.readValue(); err != nil {
+					return nil, nil, err
+				}
+			}
+			selected = len(selectElements(1, seg.key, func(int) string { return string(raw) })) > 0
+			matched[j] = selected
+		}
+		if selected {
+			children = append(children, passMatcher{rule: m.rule, segs: m.segs[1:]})
+		}
+	}
+	return children, raw, nil
+}
+
+// streamableArray reports whether elements of arrays can be selected by
+// matchers without knowing the number of elements.
+func streamableArray(matchers []passMatcher) bool {
+	for _, m := range matchers {
+		seg := m.segs[0]
+		if !seg.selector {
+			continue
+		}
+		switch seg.key[0] {
+		case '-':
+			return false
+		case '[':
+			from, to, _ := strings.Cut(seg.key[1:len(seg.key)-1], ":")
+			if strings.HasPrefix(from, "-") || strings.HasPrefix(to, "-") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// streamRange returns bounds of the "[from:to]" selector having no negative
+// bounds, to is -1 if omitted.
+func streamRange(selector string) (from, to int) {
+	fromStr, toStr, _ := strings.Cut(selector[1:len(selector)-1], ":")
+	from, _ = strconv.Atoi(fromStr)
+	to = -1
+	if toStr != "" {
+		to, _ = strconv.Atoi(toStr)
+	}
+	return from, to
+}
+
+// hasTerminal reports whether any of matchers selects the value itself.
+func hasTerminal(matchers []passMatcher) bool {
+	for _, m := range matchers {
+		if len(m.segs) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readValue returns the raw value at the current position.
+func (s *jsonStream) readValue() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.scanValue(&buf); err != nil {
+		return nil, err
+	}
+	if !gjson.ValidBytes(buf.Bytes()) {
+		return nil, ErrInvalidJSON
+	}
+	return buf.Bytes(), nil
+}
+
+// scanValue copies the raw value at the current position to out.
+func (s *jsonStream) scanValue(out io.ByteWriter) error {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		return unexpected(err)
+	}
+	if err := out.WriteByte(c); err != nil {
+		return err
+	}
+
+	switch c {
+	case '"':
+		return s.scanString(out)
+	case '{', '[':
+		for depth := 1; depth > 0; {
+			if c, err = s.r.ReadByte(); err != nil {
+				return unexpected(err)
+			}
+			if err := out.WriteByte(c); err != nil {
+				return err
+			}
+			switch c {
+			case '"':
+				if err := s.scanString(out); err != nil {
+					return err
+				}
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		return nil
+	case '}', ']', ',', ':':
+		return fmt.Errorf("%w: unexpected %q", ErrInvalidJSON, c)
+	}
+
+	// numbers and literals end at a delimiter or the end of the input
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isSpace(c) || c == ',' || c == ']' || c == '}' {
+			return s.r.UnreadByte()
+		}
+		if err := out.WriteByte(c); err != nil {
+			return err
+		}
+	}
+}
+
+// scanString copies the rest of the string after the opening quote to out.
+func (s *jsonStream) scanString(out io.ByteWriter) error {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		if err := out.WriteByte(c); err != nil {
+			return err
+		}
+		switch c {
+		case '\\':
+			if c, err = s.r.ReadByte(); err != nil {
+				return unexpected(err)
+			}
+			if err := out.WriteByte(c); err != nil {
+				return err
+			}
+		case '"':
+			return nil
+		}
+	}
+}
+
+// space appends whitespace at the current position to dst.
+func (s *jsonStream) space(dst []byte) ([]byte, error) {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return dst, err
+		}
+		if !isSpace(c) {
+			return dst, s.r.UnreadByte()
+		}
+		dst = append(dst, c)
+	}
+}
+
+// peek returns the byte at the current position.
+func (s *jsonStream) peek() (byte, error) {
+	b, err := s.r.Peek(1)
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	return b[0], nil
+}
+
+// isSpace reports whether c is JSON whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// unexpected reports the end of the input in the middle of a value as invalid JSON.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("%w: unexpected end", ErrInvalidJSON)
+	}
+	return err
+}
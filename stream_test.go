@@ -0,0 +1,114 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskStream(t *testing.T) {
+	data := `{
+  "id": 7,
+  "password": "secret",
+  "customer": {"name": "John Smith", "email": "john@example.com", "email": "dup@example.com"},
+  "items": [
+    {"type": "card", "number": "4111111111111111", "tags": ["a", "b"]},
+    {"type": "cash", "number": "12345", "tags": []}
+  ],
+  "token": "abc"
+}`
+
+	tests := []struct {
+		name  string
+		rules []jsonmask.Rule
+	}{
+		{"values", []jsonmask.Rule{
+			{Path: "customer.name", Action: "initials"},
+			{Path: "customer.email", Action: "email"},
+			{Path: "items.#.number", Action: "truncate"},
+		}},
+		{"deletions", []jsonmask.Rule{
+			{Path: "id", Action: "-"},
+			{Path: "password", Action: "-"},
+			{Path: "token", Action: "-"},
+			{Path: "customer.email", Action: "-"},
+		}},
+		{"selectors", []jsonmask.Rule{
+			{Path: `items.#(type=="cash").number`, Action: "null"},
+			{Path: "items.-1.type", Action: "upper"},
+			{Path: "items.[1:].tags", Action: "count"},
+			{Path: "items.*.tags.0", Action: "upper"},
+		}},
+		{"streamed selectors", []jsonmask.Rule{
+			{Path: `items.#(type=="cash").number`, Action: "null"},
+			{Path: `items.#(number%"4*")#.type`, Action: "upper"},
+			{Path: "items.[1:2].tags", Action: "count"},
+			{Path: "items.0.tags.[:1]", Action: "upper"},
+			{Path: "items.*.tags.1", Action: "null"},
+			{Path: "customer.*", Action: "truncate"},
+		}},
+		{"overlapping", []jsonmask.Rule{
+			{Path: "customer.name", Action: "upper"},
+			{Path: "customer", Action: "count"},
+			{Path: "items.0.type", Action: "upper"},
+			{Path: "missing", Action: "null"},
+		}},
+		{"conditions", []jsonmask.Rule{
+			{Path: "password", Action: "-", When: "id==7"},
+		}},
+	}
+
+	jm := jsonmask.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smr := jsonmask.StructMaskRules{Rules: tt.rules}
+			expected, err := jm.Mask([]byte(data), smr)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, jm.MaskStream(&buf, iotest.OneByteReader(strings.NewReader(data)), smr))
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestMaskReaderWriter(t *testing.T) {
+	jm := jsonmask.New()
+	smr := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email"}}}
+	input := "{\"email\":\"john@example.com\"}\n{\"email\":\"jane@example.com\",\"id\":2}\n"
+	expected := "{\"email\":\"j**n@e******.com\"}\n{\"email\":\"j**e@e******.com\",\"id\":2}\n"
+
+	r := jm.MaskReader(strings.NewReader(input), smr)
+	res, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(res))
+	assert.NoError(t, r.Close())
+
+	var buf bytes.Buffer
+	w := jm.MaskWriter(&buf, smr)
+	for _, chunk := range []string{input[:10], input[10:40], input[40:]} {
+		_, err := io.WriteString(w, chunk)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, expected, buf.String())
+
+	// documents are masked one by one when rules need whole documents
+	conditional := jsonmask.StructMaskRules{Rules: []jsonmask.Rule{{Path: "email", Action: "email", When: "!x"}}}
+	buf.Reset()
+	require.NoError(t, jm.MaskStream(&buf, strings.NewReader(input), conditional))
+	assert.Equal(t, expected, buf.String())
+
+	w = jm.MaskWriter(io.Discard, smr)
+	_, _ = io.WriteString(w, `{"email":"john@example.com",]`)
+	assert.ErrorIs(t, w.Close(), jsonmask.ErrInvalidJSON)
+
+	_, err = io.ReadAll(jm.MaskReader(strings.NewReader(`{"email":"john@`), smr))
+	assert.ErrorIs(t, err, jsonmask.ErrInvalidJSON)
+}
@@ -0,0 +1,189 @@
+package jsonmask
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/tidwall/gjson"
+)
+
+// MaskStruct applies rules defined by mask tags directly to the struct pointed
+// to by v, overwriting field values, for callers who need a masked Go value
+// rather than masked JSON. Nested structs, pointers, slices and arrays are
+// processed recursively. A tagged field is masked via its JSON representation;
+// if the masked value doesn't fit the field type, e.g. "count" on a slice,
+// the field is set to its zero value. Deleted fields are set to zero values too.
+// Nested fields of a tagged field kept by its action are masked by their own tags.
+func (jm *JsonMaskerImpl) MaskStruct(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidInput
+	}
+
+	if jm.disabled {
+		return nil
+	}
+
+	return jm.maskStructValue(rv.Elem(), "", "", map[uintptr]bool{rv.Pointer(): true})
+}
+
+// maskStructValue masks fields of the addressable struct value s. Non-empty
+// override replaces actions of the fields, as set by the embedding site.
+// Visited pointers are tracked to stop on cyclic references.
+func (jm *JsonMaskerImpl) maskStructValue(s reflect.Value, path, override string, visited map[uintptr]bool) error {
+	for _, ft := range jm.structTags(s.Type()) {
+		fieldPath := path
+		if !ft.inline {
+			fieldPath = joinPath(path, ft.attr)
+		}
+
+		if ft.rule.Action != "" {
+			rule := ft.rule
+			rule.Path = fieldPath
+			if override != "" {
+				rule.Action = override
+			}
+			if err := jm.maskField(s.Field(ft.index), rule); err != nil {
+				return err
+			}
+			if rule.Action == "-" || rule.Keys {
+				continue
+			}
+			// the action may keep the container, e.g. limit, nested fields are masked too
+		}
+
+		nested := override
+		switch ft.override {
+		case "none":
+			continue
+		case "":
+		default:
+			nested = ft.override
+		}
+		if err := jm.maskNestedValue(s.Field(ft.index), fieldPath, nested, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type structTagsKey struct {
+	t reflect.Type
+}
+
+// fieldTag holds the parsed mask tag of an exported struct field.
+type fieldTag struct {
+	index    int
+	attr     string
+	inline   bool   // embedded struct without JSON name, its fields are at the parent path
+	rule     Rule   // without path, empty action if the field isn't tagged
+	override string // override option of nested fields
+}
+
+// structTags returns cached mask tags of exported fields of the struct type,
+// so MaskStruct doesn't parse tags on every call.
+func (jm *JsonMaskerImpl) structTags(t reflect.Type) []fieldTag {
+	if jm.parent != nil {
+		return jm.parent.structTags(t)
+	}
+
+	if tags, ok := jm.cache.Load(structTagsKey{t}); ok {
+		return tags.([]fieldTag)
+	}
+
+	var tags []fieldTag
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		attr, tag := jm.parseFieldTag(sf)
+		ft := fieldTag{index: i, attr: attr, inline: sf.Anonymous && !hasJSONName(sf)}
+		action, opts := parseMaskTag(tag)
+		if action != "" {
+			ft.rule = fieldRule("", tag, sf.Type)
+		}
+		ft.override = opts["override"]
+		tags = append(tags, ft)
+	}
+	jm.cache.Store(structTagsKey{t}, tags)
+	return tags
+}
+
+// precompileStructTags caches mask tags of the struct type and struct types
+// nested in its fields for MaskStruct.
+func (jm *JsonMaskerImpl) precompileStructTags(t reflect.Type, visited map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isLeafType(t) || visited[t] {
+		return
+	}
+	visited[t] = true
+
+	for _, ft := range jm.structTags(t) {
+		jm.precompileStructTags(t.Field(ft.index).Type, visited)
+	}
+}
+
+// maskNestedValue looks for structs nested in the untagged field value v.
+func (jm *JsonMaskerImpl) maskNestedValue(v reflect.Value, path, override string, visited map[uintptr]bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return nil
+		}
+		visited[v.Pointer()] = true
+		return jm.maskNestedValue(v.Elem(), path, override, visited)
+	case reflect.Struct:
+		if isLeafType(v.Type()) {
+			return nil
+		}
+		return jm.maskStructValue(v, path, override, visited)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := jm.maskNestedValue(v.Index(i), joinPath(path, "#"), override, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maskField overwrites the field value with its value masked by the rule.
+func (jm *JsonMaskerImpl) maskField(field reflect.Value, rule Rule) error {
+	if rule.Action == "-" && !rule.Keys {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	maskFunc, ok, err := jm.ruleFunc(rule)
+	if !ok {
+		return err
+	}
+
+	raw, err := json.Marshal(field.Interface())
+	if err != nil {
+		return err
+	}
+
+	var masked []byte
+	if rule.Keys {
+		value := gjson.ParseBytes(raw)
+		if !value.IsObject() {
+			return nil
+		}
+		masked = maskKeys(value, maskFunc)
+	} else {
+		masked = maskFunc(string(raw))
+	}
+
+	res := reflect.New(field.Type())
+	if err := json.Unmarshal(masked, res.Interface()); err != nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	field.Set(res.Elem())
+	return nil
+}
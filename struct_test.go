@@ -0,0 +1,61 @@
+package jsonmask_test
+
+import (
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonMaskerImpl_MaskStruct(t *testing.T) {
+	type Card struct {
+		Number string `json:"number" mask:"first4"`
+		CVV    string `json:"cvv" mask:"-"`
+	}
+	type Customer struct {
+		Name     string         `json:"name" mask:"upper"`
+		Email    *string        `json:"email" mask:"email"`
+		Age      int            `json:"age" mask:"zero"`
+		Cards    []Card         `json:"cards"`
+		Primary  *Card          `json:"primary"`
+		Orders   []int          `json:"orders" mask:"count"`
+		Balances map[string]int `json:"balances" mask:"email,keys"`
+		Next     *Customer      `json:"next"`
+		note     string
+	}
+
+	email := "john@example.com"
+	c := Customer{
+		Name:     "john",
+		Email:    &email,
+		Age:      42,
+		Cards:    []Card{{Number: "4111111111111111", CVV: "123"}},
+		Primary:  &Card{Number: "5500000000000004", CVV: "456"},
+		Orders:   []int{1, 2},
+		Balances: map[string]int{"jane@example.com": 5},
+		note:     "keep",
+	}
+	c.Next = &c
+
+	jm := jsonmask.New()
+	assert.NoError(t, jm.MaskStruct(&c))
+
+	assert.Equal(t, "JOHN", c.Name)
+	assert.Equal(t, "j**n@e******.com", *c.Email)
+	assert.Equal(t, "john@example.com", email, "pointed value is replaced, not modified")
+	assert.Equal(t, 0, c.Age)
+	assert.Equal(t, []Card{{Number: "4111"}}, c.Cards)
+	assert.Equal(t, &Card{Number: "5500"}, c.Primary)
+	assert.Nil(t, c.Orders, "masked value not fitting the field type")
+	assert.Equal(t, map[string]int{"j**e@e******.com": 5}, c.Balances)
+	assert.Equal(t, "keep", c.note)
+
+	assert.ErrorIs(t, jm.MaskStruct(c), jsonmask.ErrInvalidInput)
+	assert.ErrorIs(t, jm.MaskStruct((*Customer)(nil)), jsonmask.ErrInvalidInput)
+
+	type Unknown struct {
+		Name string `json:"name" mask:"unknown"`
+	}
+	assert.NoError(t, jm.MaskStruct(&Unknown{Name: "john"}))
+	assert.ErrorIs(t, jsonmask.New(jsonmask.WithConfig(jsonmask.Config{Strict: true})).MaskStruct(&Unknown{}), jsonmask.ErrUnknownAction)
+}
@@ -0,0 +1,160 @@
+package jsonmask
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// WithUnknownFields makes Mask apply the action to values of attributes not
+// present in the structure rules were parsed from, protecting against fields
+// proxied through without the Go model knowing about them. Use "-" to delete
+// such attributes. It applies to rules having Fields set, e.g. by ParseStruct,
+// in every masking call taking them, like MaskAt, MaskSplit, MaskDiff, MaskMap,
+// MaskBSON and MaskCodec. Maps, interfaces and types with custom JSON encoding
+// are not inspected.
+func WithUnknownFields(action string) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.unknownFields = action
+	}
+}
+
+// fieldsKey is a key of cached attribute paths of the type.
+type fieldsKey struct {
+	t reflect.Type
+}
+
+// structFields returns cached paths of all JSON attributes of the type of src.
+func (jm *JsonMaskerImpl) structFields(src any) []string {
+	if jm.parent != nil {
+		return jm.parent.structFields(src)
+	}
+
+	t := reflect.TypeOf(src)
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if fields, ok := jm.cache.Load(fieldsKey{t}); ok {
+		return fields.([]string)
+	}
+
+	fields := typeFields(t, "", map[reflect.Type]bool{})
+	jm.cache.Store(fieldsKey{t}, fields)
+	return fields
+}
+
+// typeFields returns paths of JSON attributes of the struct type and attributes
+// of nested structs. Array elements are denoted by "#". Recursive types are
+// inspected up to the first repetition.
+func typeFields(t reflect.Type, parentAttr string, visited map[reflect.Type]bool) []string {
+	var fields []string
+	walkTypeFields(t, parentAttr, visited, func(path string, _ reflect.StructField) {
+		fields = append(fields, path)
+	})
+	return fields
+}
+
+// walkTypeFields calls fn with the path and the field of every JSON attribute
+// of the struct type and attributes of nested structs, see typeFields.
+func walkTypeFields(t reflect.Type, parentAttr string, visited map[reflect.Type]bool, fn func(path string, sf reflect.StructField)) {
+	if t.Kind() != reflect.Struct || visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("json") == "-" {
+			continue
+		}
+
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if sf.Anonymous && !hasJSONName(sf) && ft.Kind() == reflect.Struct && !isLeafType(ft) {
+			// fields of embedded structs are promoted to the parent object
+			walkTypeFields(ft, parentAttr, visited, fn)
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" {
+			name = sf.Name
+		}
+		path := joinPath(parentAttr, pathEscaper.Replace(name))
+		fn(path, sf)
+
+		elemPath := path
+		for (ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array) && !isLeafType(ft) {
+			ft = ft.Elem()
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			elemPath += ".#"
+		}
+		if !isLeafType(ft) {
+			walkTypeFields(ft, elemPath, visited, fn)
+		}
+	}
+}
+
+// unknownFieldRules returns rules extended with rules applying the unknown
+// fields action to attributes of data not listed in fields.
+func (jm *JsonMaskerImpl) unknownFieldRules(data []byte, fields []string, rules []Rule) []Rule {
+	if jm.unknownFields == "" || len(fields) == 0 {
+		return rules
+	}
+
+	known := make(map[string]bool, len(fields))
+	containers := make(map[string]bool) // paths of inspected objects and arrays
+	for _, f := range fields {
+		known[f] = true
+		for i := 0; i < len(f); i++ {
+			switch f[i] {
+			case '\\':
+				i++ // skip escaped character
+			case '.':
+				containers[f[:i]] = true
+			}
+		}
+	}
+
+	res := append([]Rule(nil), rules...)
+
+	var visit func(v gjson.Result, path, general string)
+	visit = func(v gjson.Result, path, general string) {
+		switch {
+		case v.IsArray():
+			i := 0
+			v.ForEach(func(_, value gjson.Result) bool {
+				visit(value, joinPath(path, strconv.Itoa(i)), joinPath(general, "#"))
+				i++
+				return true
+			})
+		case v.IsObject():
+			v.ForEach(func(key, value gjson.Result) bool {
+				k := pathEscaper.Replace(key.Str)
+				p, g := joinPath(path, k), joinPath(general, k)
+				switch {
+				case !known[g]:
+					res = append(res, Rule{Path: p, Action: jm.unknownFields})
+				case containers[g]:
+					visit(value, p, g)
+				}
+				return true
+			})
+		}
+	}
+	visit(gjson.ParseBytes(data), "", "")
+
+	return res
+}
@@ -0,0 +1,103 @@
+package jsonmask_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestWithUnknownFields(t *testing.T) {
+	type Item struct {
+		SKU string `json:"sku"`
+	}
+	type Meta struct {
+		Source string `json:"source"`
+	}
+	type Order struct {
+		Meta
+		ID      int               `json:"id"`
+		Email   string            `json:"email" mask:"email"`
+		Items   []Item            `json:"items"`
+		Labels  map[string]string `json:"labels"`
+		Created time.Time         `json:"created"`
+		Secret  string            `json:"-"`
+	}
+
+	jm := jsonmask.New(jsonmask.WithUnknownFields("-"))
+	smr := jm.ParseStruct(Order{})
+	assert.Equal(t, []string{"source", "id", "email", "items", "items.#.sku", "labels", "created"}, smr.Fields)
+
+	data := []byte(`{
+		"source": "web",
+		"id": 1,
+		"email": "john@example.com",
+		"items": [{"sku": "A", "price": 10}, {"sku": "B"}],
+		"labels": {"any": "x"},
+		"created": "2024-01-01T00:00:00Z",
+		"ssn": "123-45-6789",
+		"Secret": "s"
+	}`)
+	result, err := jm.Mask(data, smr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"source": "web",
+		"id": 1,
+		"email": "j**n@e******.com",
+		"items": [{"sku": "A"}, {"sku": "B"}],
+		"labels": {"any": "x"},
+		"created": "2024-01-01T00:00:00Z"
+	}`, string(result))
+
+	masking := jsonmask.New(jsonmask.WithUnknownFields("null"))
+	result, err = masking.Mask([]byte(`{"id":1,"ssn":"123-45-6789"}`), masking.ParseStruct(Order{}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"ssn":null}`, string(result))
+
+	// rules without fields are not affected
+	result, err = jm.Mask([]byte(`{"id":1,"ssn":"123-45-6789"}`), jsonmask.StructMaskRules{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"ssn":"123-45-6789"}`, string(result))
+}
+
+func TestWithUnknownFields_EntryPoints(t *testing.T) {
+	type Customer struct {
+		ID    int    `json:"id"`
+		Email string `json:"email" mask:"email"`
+	}
+
+	jm := jsonmask.New(jsonmask.WithUnknownFields("-"))
+	smr := jm.ParseStruct(Customer{})
+	data := []byte(`{"id":1,"email":"john@example.com","ssn":"123-45-6789"}`)
+	expected := `{"id":1,"email":"j**n@e******.com"}`
+
+	result, err := jm.MaskAt([]byte(`{"payload":`+string(data)+`}`), "payload", smr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"payload":`+expected+`}`, string(result))
+
+	result, _, err = jm.MaskSplit(data, smr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(result))
+
+	result, err = jm.MaskDiff([]byte(`{"id":1}`), data, smr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(result))
+
+	result, err = jm.MaskGraphQL([]byte(`{"query":"mutation Login","variables":`+string(data)+`}`), jsonmask.GraphQLRules{Default: smr})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"query":"mutation Login","variables":`+expected+`}`, string(result))
+
+	m := map[string]any{"id": 1, "email": "john@example.com", "ssn": "123-45-6789"}
+	assert.NoError(t, jm.MaskMap(m, smr))
+	assert.Equal(t, map[string]any{"id": 1, "email": "j**n@e******.com"}, m)
+
+	doc, err := bson.Marshal(bson.D{{Key: "id", Value: 1}, {Key: "email", Value: "john@example.com"}, {Key: "ssn", Value: "123-45-6789"}})
+	assert.NoError(t, err)
+	result, err = jm.MaskBSON(doc, smr)
+	assert.NoError(t, err)
+	var res bson.D
+	assert.NoError(t, bson.Unmarshal(result, &res))
+	assert.Equal(t, bson.D{{Key: "id", Value: int32(1)}, {Key: "email", Value: "j**n@e******.com"}}, res)
+}
@@ -0,0 +1,235 @@
+package jsonmask
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// TokenPrefix is a prefix of values produced by TokenizeFn.
+const TokenPrefix = "tok:"
+
+// UnmaskReport describes fields that could not be restored by Unmask.
+type UnmaskReport struct {
+	// Unrestored holds paths of rules with irreversible actions and concrete
+	// paths of values the reverse function failed on.
+	Unrestored []string
+}
+
+// AddReversibleFunc adds a masking function associated with a name together
+// with its reverse function used by Unmask.
+func (jm *JsonMaskerImpl) AddReversibleFunc(name string, mask func(string) []byte, unmask func(string) ([]byte, error)) {
+	if jm.checkReplace(name) {
+		jm.addFunc(name, mask)
+		jm.unmaskFuncs[name] = unmask
+	}
+}
+
+// UnmaskAuthorizer decides whether the value at the concrete path may be unmasked
+// for the reason, recording an audit event of the decision. Denied values stay masked.
+// Returning an error aborts unmasking.
+type UnmaskAuthorizer func(ctx context.Context, path, reason string) (bool, error)
+
+// WithUnmaskAuthorizer guards unmasking with the authorizer. Once set, values are
+// unmasked only by UnmaskContext with an authorization, Unmask fails with ErrUnmaskDenied.
+func WithUnmaskAuthorizer(f UnmaskAuthorizer) Option {
+	return func(jm *JsonMaskerImpl) {
+		jm.unmaskAuthorizer = f
+	}
+}
+
+// Unmask reverses reversible actions (encryption, tokenization) applied by Mask
+// with the same rules. Values masked with irreversible actions are left untouched
+// and reported along with values that could not be restored.
+// It fails with ErrUnmaskDenied if unmasking is guarded by WithUnmaskAuthorizer.
+func (jm *JsonMaskerImpl) Unmask(data []byte, smr StructMaskRules) ([]byte, UnmaskReport, error) {
+	if jm.unmaskAuthorizer != nil {
+		return nil, UnmaskReport{}, ErrUnmaskDenied
+	}
+	return jm.unmask(context.Background(), data, smr, "", nil)
+}
+
+// UnmaskContext is like Unmask but every value is unmasked only if allowed by
+// the authorizer set by WithUnmaskAuthorizer for the reason. Values denied are
+// reported as unrestored. Without the authorizer it fails with ErrUnmaskDenied.
+func (jm *JsonMaskerImpl) UnmaskContext(ctx context.Context, data []byte, smr StructMaskRules, reason string) ([]byte, UnmaskReport, error) {
+	if jm.unmaskAuthorizer == nil {
+		return nil, UnmaskReport{}, ErrUnmaskDenied
+	}
+	return jm.unmask(ctx, data, smr, reason, jm.unmaskAuthorizer)
+}
+
+func (jm *JsonMaskerImpl) unmask(ctx context.Context, data []byte, smr StructMaskRules, reason string, authorize UnmaskAuthorizer) ([]byte, UnmaskReport, error) {
+	var (
+		report UnmaskReport
+		err    error
+	)
+
+	for _, rule := range smr.Rules {
+		if isExclusion(rule) {
+			continue
+		}
+
+		unmaskFunc := jm.lookupUnmaskFunc(rule.Action)
+		if unmaskFunc == nil {
+			report.Unrestored = append(report.Unrestored, rule.Path)
+			continue
+		}
+
+		for _, path := range expandPath(data, rule.Path) {
+			if authorize != nil {
+				allowed, err := authorize(ctx, path, reason)
+				if err != nil {
+					return nil, report, err
+				}
+				if !allowed {
+					report.Unrestored = append(report.Unrestored, path)
+					continue
+				}
+			}
+
+			restored, ferr := unmaskFunc(gjson.GetBytes(data, path).Raw)
+			if ferr != nil {
+				report.Unrestored = append(report.Unrestored, path)
+				continue
+			}
+
+			data, err = sjson.SetRawBytes(data, path, restored)
+			if err != nil {
+				return nil, report, err
+			}
+		}
+	}
+
+	return data, report, nil
+}
+
+// lookupUnmaskFunc returns the reverse function of the action or nil.
+// Reverse functions registered for a name apply to parametrized actions
+// with the name too, e.g. "encrypt" to "encrypt(key)".
+func (jm *JsonMaskerImpl) lookupUnmaskFunc(action string) func(string) ([]byte, error) {
+	names := []string{action}
+	if name, _, ok := parseAction(action); ok {
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		for p := jm; p != nil; p = p.parent {
+			if f, ok := p.unmaskFuncs[name]; ok {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// TokenStore keeps original values replaced with tokens.
+type TokenStore interface {
+	// Tokenize stores the value and returns a token referencing it.
+	Tokenize(value string) (string, error)
+
+	// Detokenize returns the value referenced by the token.
+	Detokenize(token string) (string, error)
+}
+
+// MemoryTokenStore is a TokenStore keeping values in memory.
+// The same value is always replaced with the same token.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]string // value -> token
+	values map[string]string // token -> value
+}
+
+// NewMemoryTokenStore creates a new instance of MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[string]string),
+		values: make(map[string]string),
+	}
+}
+
+// Tokenize implements TokenStore.
+func (ts *MemoryTokenStore) Tokenize(value string) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if token, ok := ts.tokens[value]; ok {
+		return token, nil
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	token := hex.EncodeToString(b)
+	ts.tokens[value] = token
+	ts.values[token] = value
+	return token, nil
+}
+
+// Detokenize implements TokenStore.
+func (ts *MemoryTokenStore) Detokenize(token string) (string, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	value, ok := ts.values[token]
+	if !ok {
+		return "", ErrTokenNotFound
+	}
+	return value, nil
+}
+
+// TokenizeFn returns a function that replaces the input value with a token
+// issued by the store, e.g. "tok:9f86d08...". NULL and tokens issued by the
+// store are returned as is, other values looking like tokens are tokenized.
+func TokenizeFn(store TokenStore) func(string) []byte {
+	detokenize := DetokenizeFn(store)
+	return func(s string) []byte {
+		if s == "null" {
+			return []byte(s)
+		}
+		if _, err := detokenize(s); err == nil {
+			return []byte(s) // already a token
+		}
+
+		token, err := store.Tokenize(s)
+		if err != nil {
+			return []byte(`null`)
+		}
+		return quote(TokenPrefix + token)
+	}
+}
+
+// DetokenizeFn returns a function that restores the original JSON value
+// replaced with a token by TokenizeFn.
+func DetokenizeFn(store TokenStore) func(string) ([]byte, error) {
+	return func(s string) ([]byte, error) {
+		if s == "null" {
+			return []byte(s), nil
+		}
+
+		str, ok := unquote(s)
+		if !ok || !strings.HasPrefix(str, TokenPrefix) {
+			return nil, ErrTokenNotFound
+		}
+
+		value, err := store.Detokenize(strings.TrimPrefix(str, TokenPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(value), nil
+	}
+}
+
+// Error definitions
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrUnmaskDenied  = errors.New("unmasking not authorized")
+)
@@ -0,0 +1,105 @@
+package jsonmask_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/axkit/jsonmask"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestJsonMaskerImpl_Unmask(t *testing.T) {
+	kp := jsonmask.NewStaticKeyProvider("k1", bytes.Repeat([]byte{1}, 32))
+	store := jsonmask.NewMemoryTokenStore()
+
+	jm := jsonmask.New()
+	jm.AddReversibleFunc("encrypt", jsonmask.EncryptFn(kp), jsonmask.DecryptFn(kp))
+	jm.AddReversibleFunc("token", jsonmask.TokenizeFn(store), jsonmask.DetokenizeFn(store))
+
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "encrypt"},
+			{Path: "cards.#.number", Action: "token"},
+			{Path: "name", Action: "initialChar"},
+			{Path: "password", Action: "-"},
+		},
+	}
+
+	original := `{"email":"john@example.com","cards":[{"number":"4111"},{"number":"5500"}],"name":"john","password":"x"}`
+
+	masked, err := jm.Mask([]byte(original), rules)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(masked), "john@example.com")
+	assert.NotContains(t, string(masked), "4111")
+
+	restored, report, err := jm.Unmask(masked, rules)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"email":"john@example.com","cards":[{"number":"4111"},{"number":"5500"}],"name":"J"}`, string(restored))
+	assert.Equal(t, []string{"name", "password"}, report.Unrestored)
+
+	// tokens and ciphertexts survive masking again
+	remasked, err := jm.Mask(masked, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, gjson.GetBytes(masked, "cards").Raw, gjson.GetBytes(remasked, "cards").Raw)
+	assert.Equal(t, gjson.GetBytes(masked, "email").Raw, gjson.GetBytes(remasked, "email").Raw)
+
+	// values only looking like tokens or ciphertexts are masked
+	forged, err := jm.Mask([]byte(`{"email":"enc:k1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA","cards":[{"number":"tok:4111111111111111"}]}`), rules)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(forged), "4111111111111111")
+	assert.NotContains(t, string(forged), "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA")
+
+	t.Run("Failed", func(t *testing.T) {
+		_, report, err := jm.Unmask([]byte(`{"email":"enc:k9:AAAA","cards":[{"number":"tok:unknown"}]}`), rules)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"email", "cards.0.number", "name", "password"}, report.Unrestored)
+	})
+}
+
+func TestJsonMaskerImpl_UnmaskContext(t *testing.T) {
+	kp := jsonmask.NewStaticKeyProvider("k1", bytes.Repeat([]byte{1}, 32))
+
+	type event struct{ path, reason, user string }
+	var audit []event
+
+	type userKey struct{}
+	jm := jsonmask.New(jsonmask.WithUnmaskAuthorizer(func(ctx context.Context, path, reason string) (bool, error) {
+		user, _ := ctx.Value(userKey{}).(string)
+		if user == "" {
+			return false, errors.New("anonymous")
+		}
+		audit = append(audit, event{path, reason, user})
+		return path != "ssn", nil
+	}))
+	jm.AddReversibleFunc("encrypt", jsonmask.EncryptFn(kp), jsonmask.DecryptFn(kp))
+
+	rules := jsonmask.StructMaskRules{
+		Rules: []jsonmask.Rule{
+			{Path: "email", Action: "encrypt"},
+			{Path: "ssn", Action: "encrypt"},
+		},
+	}
+	masked, err := jm.Mask([]byte(`{"email":"john@example.com","ssn":"123"}`), rules)
+	assert.NoError(t, err)
+
+	_, _, err = jm.Unmask(masked, rules)
+	assert.ErrorIs(t, err, jsonmask.ErrUnmaskDenied)
+
+	_, _, err = jm.UnmaskContext(context.Background(), masked, rules, "support ticket 42")
+	assert.EqualError(t, err, "anonymous")
+
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	restored, report, err := jm.UnmaskContext(ctx, masked, rules, "support ticket 42")
+	assert.NoError(t, err)
+	assert.Equal(t, `john@example.com`, gjson.GetBytes(restored, "email").Str)
+	assert.Equal(t, gjson.GetBytes(masked, "ssn").Str, gjson.GetBytes(restored, "ssn").Str)
+	assert.Equal(t, []string{"ssn"}, report.Unrestored)
+	assert.Equal(t, []event{{"email", "support ticket 42", "alice"}, {"ssn", "support ticket 42", "alice"}}, audit)
+
+	// without the authorizer
+	_, _, err = jsonmask.New().UnmaskContext(ctx, masked, rules, "support ticket 42")
+	assert.ErrorIs(t, err, jsonmask.ErrUnmaskDenied)
+}
@@ -0,0 +1,79 @@
+package jsonmask
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// edit describes replacement of data[start:end] with raw.
+type edit struct {
+	start, end int
+	raw        []byte
+}
+
+// applyEdits returns a copy of data with non-overlapping edits applied in a single pass.
+func applyEdits(data []byte, edits []edit) []byte {
+	if len(edits) == 0 {
+		return data
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	size := len(data)
+	for _, e := range edits {
+		size += len(e.raw) - (e.end - e.start)
+	}
+
+	res := make([]byte, 0, size)
+	pos := 0
+	for _, e := range edits {
+		res = append(res, data[pos:e.start]...)
+		res = append(res, e.raw...)
+		pos = e.end
+	}
+	return append(res, data[pos:]...)
+}
+
+// walk calls fn for every value nested in v, depth-first. The path holds
+// object keys and array indexes leading to the value. If fn returns false,
+// values nested in the current one are skipped.
+func walk(v gjson.Result, path []string, fn func(path []string, value gjson.Result) bool) {
+	if !v.IsObject() && !v.IsArray() {
+		return
+	}
+
+	i := 0
+	v.ForEach(func(key, value gjson.Result) bool {
+		var p []string
+		if v.IsArray() {
+			p = append(path, strconv.Itoa(i))
+			i++
+		} else {
+			p = append(path, key.Str)
+		}
+		p = p[:len(p):len(p)] // force copy on next append
+
+		if fn(p, value) {
+			walk(value, p, fn)
+		}
+		return true
+	})
+}
+
+// pathEscaper escapes characters with a special meaning in gjson/sjson paths.
+var pathEscaper = strings.NewReplacer(
+	`\`, `\\`, `.`, `\.`, `*`, `\*`, `?`, `\?`,
+	`|`, `\|`, `#`, `\#`, `@`, `\@`, `!`, `\!`, `=`, `\=`,
+)
+
+// joinKeys joins object keys and array indexes to a JSON path, escaping special characters.
+func joinKeys(keys []string) string {
+	escaped := make([]string, len(keys))
+	for i, k := range keys {
+		escaped[i] = pathEscaper.Replace(k)
+	}
+	return strings.Join(escaped, ".")
+}